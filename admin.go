@@ -0,0 +1,125 @@
+package spoor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+type AdminConfig struct {
+	// Loggers are reported under their name, they default to those built
+	// by NewFromConfig.
+	Loggers map[string]*Spoor
+	// Recent, if set, serves the entries it keeps, see RingBufferHook.
+	Recent *RingBufferHook
+}
+
+// AdminHandler returns a handler for an application's debug server serving:
+//
+//	/healthz   the health of the registered writers, see HealthHandler
+//	/stats     the levels and metrics of the loggers and the queue depths of
+//	           the registered writers, as JSON
+//	/metrics   the same in the Prometheus text format
+//	/recent    the entries kept by Recent
+//
+// It is mounted under a prefix with http.StripPrefix:
+//
+//	mux.Handle("/debug/spoor/", http.StripPrefix("/debug/spoor", spoor.AdminHandler(spoor.AdminConfig{})))
+func AdminHandler(cfg AdminConfig) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", HealthHandler())
+	mux.HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(cfg.stats())
+	})
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		cfg.stats().writePrometheus(w)
+	})
+	if cfg.Recent != nil {
+		mux.Handle("/recent", cfg.Recent)
+	}
+	return mux
+}
+
+type adminStats struct {
+	Loggers map[string]loggerStats `json:"loggers"`
+	Writers []writerStats          `json:"writers"`
+}
+
+type loggerStats struct {
+	Level   string  `json:"level"`
+	Metrics Metrics `json:"metrics"`
+	level   Level
+}
+
+// writerStats identifies the writer by its type and its index among the
+// reported writers, which tells apart those of the same type.
+type writerStats struct {
+	Writer  string `json:"writer"`
+	Index   int    `json:"index"`
+	Pending int    `json:"pending"`
+	Dropped uint64 `json:"dropped"`
+}
+
+func (cfg AdminConfig) stats() adminStats {
+	loggers := cfg.Loggers
+	if loggers == nil {
+		configLoggers.RLock()
+		loggers = make(map[string]*Spoor, len(configLoggers.m))
+		for name, l := range configLoggers.m {
+			loggers[name] = l
+		}
+		configLoggers.RUnlock()
+	}
+	stats := adminStats{Loggers: make(map[string]loggerStats, len(loggers))}
+	for name, l := range loggers {
+		stats.Loggers[name] = loggerStats{Level: l.cfgLevel.String(), Metrics: l.GetMetrics(), level: l.cfgLevel}
+	}
+	for _, c := range registered() {
+		p, ok := c.(interface{ Pending() int })
+		if !ok {
+			continue
+		}
+		ws := writerStats{Writer: fmt.Sprintf("%T", c), Index: len(stats.Writers), Pending: p.Pending()}
+		if d, ok := c.(interface{ Dropped() uint64 }); ok {
+			ws.Dropped = d.Dropped()
+		}
+		stats.Writers = append(stats.Writers, ws)
+	}
+	return stats
+}
+
+// writePrometheus writes s in the Prometheus text format.
+func (s adminStats) writePrometheus(w io.Writer) {
+	header := func(name, typ, help string) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+	}
+	names := sortedKeys(s.Loggers)
+	header("spoor_logger_level", "gauge", "Minimum level logged, from 1 (debug) to 5 (fatal).")
+	for _, name := range names {
+		fmt.Fprintf(w, "spoor_logger_level{logger=\"%s\"} %d\n", promLabel(name), s.Loggers[name].level)
+	}
+	header("spoor_sampled_kept_total", "counter", "Entries kept by the sampler.")
+	for _, name := range names {
+		fmt.Fprintf(w, "spoor_sampled_kept_total{logger=\"%s\"} %d\n", promLabel(name), s.Loggers[name].Metrics.Sampling.Kept)
+	}
+	header("spoor_sampled_out_total", "counter", "Entries dropped by the sampler.")
+	for _, name := range names {
+		fmt.Fprintf(w, "spoor_sampled_out_total{logger=\"%s\"} %d\n", promLabel(name), s.Loggers[name].Metrics.Sampling.SampledOut)
+	}
+	header("spoor_writer_pending", "gauge", "Lines waiting to be written.")
+	for _, ws := range s.Writers {
+		fmt.Fprintf(w, "spoor_writer_pending{writer=\"%s\",index=\"%d\"} %d\n", promLabel(ws.Writer), ws.Index, ws.Pending)
+	}
+	header("spoor_writer_dropped_total", "counter", "Lines dropped because the queue was full.")
+	for _, ws := range s.Writers {
+		fmt.Fprintf(w, "spoor_writer_dropped_total{writer=\"%s\",index=\"%d\"} %d\n", promLabel(ws.Writer), ws.Index, ws.Dropped)
+	}
+}
+
+// promLabel escapes a label value as the Prometheus text format expects,
+// which only knows backslash, double quote and line feed escapes.
+var promLabel = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace
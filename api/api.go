@@ -0,0 +1,46 @@
+// Package api is the logging interface for libraries: they depend on this
+// package alone, which imports nothing, and applications pass them a spoor
+// logger adapted with spoor.API.
+//
+//	type Client struct{ log api.Logger }
+//
+//	func NewClient(log api.Logger) *Client {
+//		if log == nil {
+//			log = api.Nop
+//		}
+//		return &Client{log: log}
+//	}
+//
+//	c.log.Log(api.Warn, "retrying", api.F("attempt", n))
+package api
+
+// Level has the values of the spoor levels.
+type Level int
+
+const (
+	Debug Level = iota + 1
+	Info
+	Warn
+	Error
+)
+
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// F returns a field.
+func F(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+type Logger interface {
+	Log(level Level, msg string, fields ...Field)
+}
+
+// Nop is a Logger discarding everything.
+var Nop Logger = nop{}
+
+type nop struct{}
+
+func (nop) Log(Level, string, ...Field) {}
@@ -0,0 +1,22 @@
+package spoor
+
+import "github.com/phuhao00/spoor/api"
+
+// API adapts l to the minimal interface of the api package, for libraries
+// which depend on it rather than on spoor. The fields are converted with Any.
+func API(l *Spoor) api.Logger {
+	return apiLogger{l}
+}
+
+type apiLogger struct{ l *Spoor }
+
+func (a apiLogger) Log(level api.Level, msg string, fields ...api.Field) {
+	if a.l.CheckLevel(Level(level)) && a.l.recorder == nil && a.l.debug == nil {
+		return
+	}
+	converted := make([]Field, len(fields))
+	for i, f := range fields {
+		converted[i] = Any(f.Key, f.Value)
+	}
+	a.l.log(2, Level(level), msg, converted)
+}
@@ -0,0 +1,180 @@
+package spoor
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+type AsyncConfig struct {
+	QueueSize int // lines waiting to be written, 10000 by default
+	// Workers is the number of goroutines writing to the wrapped writer, 1 by
+	// default. With more than one, lines may be written out of order unless
+	// Key is set.
+	Workers   int
+	BatchSize int  // lines handed over per write, 100 by default
+	DropFull  bool // drop lines with ErrQueueFull instead of blocking while the queue is full
+	// Key, when set, gives each worker its own share of the queue and sends
+	// the lines with the same key to the same worker, so that they are
+	// written in the order they came, e.g. the entries of one user or
+	// request. A constant key orders every line like a single worker.
+	Key func(line []byte) string
+}
+
+// AsyncWriter writes to another writer from its own goroutines through a
+// bounded queue, so that a slow remote sink does not hold up the others:
+//
+//	io.MultiWriter(os.Stderr, spoor.NewAsyncWriter(elastic, spoor.AsyncConfig{Workers: 4}))
+//
+// Batches are handed over with WriteBatch when the wrapped writer is a
+// BulkWriter.
+type AsyncWriter struct {
+	w       io.Writer
+	cfg     AsyncConfig
+	queues  []chan []byte // one shared queue, or one per worker with Key
+	wg      sync.WaitGroup
+	closeMu sync.RWMutex
+	closed  bool
+	mu      sync.Mutex
+	idle    *sync.Cond
+	pending int // queued or being written
+	dropped uint64
+}
+
+func NewAsyncWriter(w io.Writer, cfg AsyncConfig) *AsyncWriter {
+	if cfg.QueueSize == 0 {
+		cfg.QueueSize = 10000
+	}
+	if cfg.Workers == 0 {
+		cfg.Workers = 1
+	}
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = 100
+	}
+	aw := &AsyncWriter{w: w, cfg: cfg}
+	aw.idle = sync.NewCond(&aw.mu)
+	if cfg.Key == nil {
+		aw.queues = []chan []byte{make(chan []byte, cfg.QueueSize)}
+	} else {
+		size := cfg.QueueSize / cfg.Workers
+		if size == 0 {
+			size = 1
+		}
+		for i := 0; i < cfg.Workers; i++ {
+			aw.queues = append(aw.queues, make(chan []byte, size))
+		}
+	}
+	aw.wg.Add(cfg.Workers)
+	for i := 0; i < cfg.Workers; i++ {
+		go aw.work(aw.queues[i%len(aw.queues)])
+	}
+	return aw
+}
+
+func (aw *AsyncWriter) Write(p []byte) (n int, err error) {
+	line := make([]byte, len(p))
+	copy(line, p)
+	aw.closeMu.RLock()
+	defer aw.closeMu.RUnlock()
+	if aw.closed {
+		return 0, ErrWriterClosed
+	}
+	queue := aw.queues[0]
+	if aw.cfg.Key != nil {
+		h := fnv.New32a()
+		io.WriteString(h, aw.cfg.Key(line))
+		queue = aw.queues[h.Sum32()%uint32(len(aw.queues))]
+	}
+	aw.mu.Lock()
+	aw.pending++
+	aw.mu.Unlock()
+	if !aw.cfg.DropFull {
+		queue <- line
+		return len(p), nil
+	}
+	select {
+	case queue <- line:
+	default:
+		atomic.AddUint64(&aw.dropped, 1)
+		aw.done(1)
+		return 0, ErrQueueFull
+	}
+	return len(p), nil
+}
+
+func (aw *AsyncWriter) work(queue chan []byte) {
+	defer aw.wg.Done()
+	for line := range queue {
+		lines := [][]byte{line}
+	batch:
+		for len(lines) < aw.cfg.BatchSize {
+			select {
+			case line, ok := <-queue:
+				if !ok {
+					break batch
+				}
+				lines = append(lines, line)
+			default:
+				break batch
+			}
+		}
+		if err := WriteBatch(aw.w, lines); err != nil {
+			internalError(fmt.Errorf("spoor: async write: %w", err))
+		}
+		aw.done(len(lines))
+	}
+}
+
+func (aw *AsyncWriter) done(n int) {
+	aw.mu.Lock()
+	aw.pending -= n
+	if aw.pending == 0 {
+		aw.idle.Broadcast()
+	}
+	aw.mu.Unlock()
+}
+
+// Pending returns the number of lines queued or being written.
+func (aw *AsyncWriter) Pending() int {
+	aw.mu.Lock()
+	defer aw.mu.Unlock()
+	return aw.pending
+}
+
+// Dropped returns the number of lines dropped because the queue was full.
+func (aw *AsyncWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&aw.dropped)
+}
+
+// Sync waits until the lines written so far were handed over, then flushes
+// the wrapped writer if it has a Flush or Sync method.
+func (aw *AsyncWriter) Sync() error {
+	aw.mu.Lock()
+	for aw.pending > 0 {
+		aw.idle.Wait()
+	}
+	aw.mu.Unlock()
+	return flushWriter(aw.w)
+}
+
+// Close writes the queued lines, stops the workers and closes the wrapped
+// writer if it is an io.Closer.
+func (aw *AsyncWriter) Close() error {
+	aw.closeMu.Lock()
+	if aw.closed {
+		aw.closeMu.Unlock()
+		return nil
+	}
+	aw.closed = true
+	for _, queue := range aw.queues {
+		close(queue)
+	}
+	aw.closeMu.Unlock()
+	aw.wg.Wait()
+	if c, ok := aw.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package spoor
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"runtime"
+	"strings"
+)
+
+// The fields every audit entry must have, see Audit.
+const (
+	AuditActor    = "actor"
+	AuditAction   = "action"
+	AuditResource = "resource"
+	AuditOutcome  = "outcome"
+)
+
+// WithAuditWriter sets the writer of the entries logged with Audit, e.g. an
+// AuditWriter on its own file. They go to the logger output if it is not set.
+func WithAuditWriter(writer io.Writer) Option {
+	return func(spoor *Spoor) {
+		spoor.audit = &output{w: writer}
+	}
+}
+
+// Audit logs an audit event. It must have the actor, action, resource and
+// outcome fields, and is written whatever the level and sampling of the
+// logger. It returns an error, without writing anything, if a field is
+// missing, and the error of the audit writer otherwise.
+func (l *Spoor) Audit(event string, fields ...Field) error {
+	e := Entry{Time: l.clock.Now(), Level: INFO, Message: event, Fields: fields}
+	if len(l.fields) > 0 {
+		e.Fields = append(l.fields[:len(l.fields):len(l.fields)], fields...)
+		if l.encoded != nil {
+			e.Encoded, e.EncodedFields = l.encoded, len(l.fields)
+		}
+	}
+	var missing []string
+	for _, key := range []string{AuditActor, AuditAction, AuditResource, AuditOutcome} {
+		if !hasKey(e.Fields, key) {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("spoor: audit event %q without %s", event, strings.Join(missing, ", "))
+	}
+	if l.flag&(log.Lshortfile|log.Llongfile) != 0 {
+		_, e.File, e.Line, _ = runtime.Caller(1)
+	}
+	e.AddFields(Bool("audit", true))
+	l.enrich(&e)
+	out := l.audit
+	if out == nil {
+		out = l.out
+	}
+	buf := getBuffer()
+	l.formatter.Format(buf, &e)
+	err := out.write(e.Level, buf.Bytes())
+	putBuffer(buf)
+	return err
+}
+
+func hasKey(fields []Field, key string) bool {
+	for _, f := range fields {
+		if f.Key == key {
+			return true
+		}
+	}
+	return false
+}
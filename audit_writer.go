@@ -0,0 +1,106 @@
+package spoor
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+)
+
+// AuditRecord is one line written by an AuditWriter. MAC is the HMAC-SHA256
+// of Seq, Prev and Entry, Prev is the MAC of the previous record, so that a
+// removed, reordered or modified record breaks the chain.
+type AuditRecord struct {
+	Seq   uint64 `json:"seq"`
+	Entry string `json:"entry"`
+	Prev  string `json:"prev"`
+	MAC   string `json:"mac"`
+}
+
+func (r *AuditRecord) mac(key []byte) string {
+	h := hmac.New(sha256.New, key)
+	h.Write(strconv.AppendUint(nil, r.Seq, 10))
+	h.Write([]byte{'\n'})
+	h.Write([]byte(r.Prev))
+	h.Write([]byte{'\n'})
+	h.Write([]byte(r.Entry))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// AuditWriter writes every entry as a chained AuditRecord line, see
+// VerifyAuditLog.
+type AuditWriter struct {
+	mu   sync.Mutex
+	w    io.Writer
+	key  []byte
+	seq  uint64
+	prev string
+}
+
+// NewAuditWriter returns an AuditWriter continuing the chain after last, the
+// last record of an existing log as returned by VerifyAuditLog, or starting a
+// new one if last is nil.
+func NewAuditWriter(w io.Writer, key []byte, last *AuditRecord) *AuditWriter {
+	aw := &AuditWriter{w: w, key: key}
+	if last != nil {
+		aw.seq, aw.prev = last.Seq, last.MAC
+	}
+	return aw
+}
+
+func (aw *AuditWriter) Write(p []byte) (n int, err error) {
+	aw.mu.Lock()
+	defer aw.mu.Unlock()
+	rec := AuditRecord{Seq: aw.seq + 1, Entry: string(bytes.TrimRight(p, "\n")), Prev: aw.prev}
+	rec.MAC = rec.mac(aw.key)
+	line, err := json.Marshal(&rec)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := aw.w.Write(append(line, '\n')); err != nil {
+		return 0, err
+	}
+	aw.seq, aw.prev = rec.Seq, rec.MAC
+	return len(p), nil
+}
+
+// Close closes the wrapped writer if it is an io.Closer.
+func (aw *AuditWriter) Close() error {
+	if c, ok := aw.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// VerifyAuditLog checks the chain of the records read from r and returns the
+// last one. Lines which are not records, such as the header of a FileWriter
+// file, are skipped. The first record is trusted to follow its Prev, to check
+// rotated files as one chain read them with io.MultiReader in order.
+func VerifyAuditLog(r io.Reader, key []byte) (*AuditRecord, error) {
+	var last *AuditRecord
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<24)
+	for line := 1; sc.Scan(); line++ {
+		if !bytes.HasPrefix(sc.Bytes(), []byte(`{"seq":`)) {
+			continue
+		}
+		var rec AuditRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			return last, fmt.Errorf("spoor: audit log line %d: %v", line, err)
+		}
+		if !hmac.Equal([]byte(rec.MAC), []byte(rec.mac(key))) {
+			return last, fmt.Errorf("spoor: audit log line %d: record %d was modified", line, rec.Seq)
+		}
+		if last != nil && (rec.Seq != last.Seq+1 || rec.Prev != last.MAC) {
+			return last, fmt.Errorf("spoor: audit log line %d: record %d does not follow record %d", line, rec.Seq, last.Seq)
+		}
+		last = &rec
+	}
+	return last, sc.Err()
+}
@@ -0,0 +1,131 @@
+package spoor
+
+import (
+	"sync"
+	"time"
+)
+
+// batcher collects written lines and hands them over to send in bulk, either
+// when maxCount lines or maxBytes bytes, if not 0, are pending or every
+// interval.
+type batcher struct {
+	mu       sync.Mutex
+	lines    [][]byte
+	size     int
+	maxCount int
+	maxBytes int
+	interval time.Duration
+	send     func(lines [][]byte)
+	flushC   chan struct{}
+	closeC   chan struct{}
+	doneC    chan struct{}
+	sendMu   sync.Mutex
+	once     sync.Once
+}
+
+func newBatcher(maxCount, maxBytes int, interval time.Duration, send func(lines [][]byte)) *batcher {
+	b := &batcher{
+		maxCount: maxCount,
+		maxBytes: maxBytes,
+		interval: interval,
+		send:     send,
+		flushC:   make(chan struct{}, 1),
+		closeC:   make(chan struct{}),
+		doneC:    make(chan struct{}),
+	}
+	go b.loop()
+	return b
+}
+
+// Write queues p, it is sent with the next batch.
+func (b *batcher) Write(p []byte) (int, error) {
+	b.add(p)
+	return len(p), nil
+}
+
+// Flush sends the pending entries and waits until they are delivered.
+func (b *batcher) Flush() error {
+	b.flush()
+	return nil
+}
+
+// Close flushes the pending entries and stops the background sender.
+func (b *batcher) Close() error {
+	b.close()
+	return nil
+}
+
+// add copies p, since callers such as log.Logger reuse their buffers.
+func (b *batcher) add(p []byte) {
+	b.addLines([][]byte{p})
+}
+
+// WriteBatch queues lines, one entry each, under a single lock.
+func (b *batcher) WriteBatch(lines [][]byte) error {
+	b.addLines(lines)
+	return nil
+}
+
+func (b *batcher) addLines(lines [][]byte) {
+	b.mu.Lock()
+	for _, p := range lines {
+		line := make([]byte, len(p))
+		copy(line, p)
+		b.lines = append(b.lines, line)
+		b.size += len(line)
+	}
+	full := len(b.lines) >= b.maxCount || b.maxBytes > 0 && b.size >= b.maxBytes
+	b.mu.Unlock()
+	if full {
+		select {
+		case b.flushC <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Pending returns the number of lines waiting to be sent.
+func (b *batcher) Pending() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.lines)
+}
+
+func (b *batcher) take() [][]byte {
+	b.mu.Lock()
+	lines := b.lines
+	b.lines, b.size = nil, 0
+	b.mu.Unlock()
+	return lines
+}
+
+// flush sends all pending lines and waits for the send to complete.
+func (b *batcher) flush() {
+	b.sendMu.Lock()
+	defer b.sendMu.Unlock()
+	if lines := b.take(); len(lines) > 0 {
+		b.send(lines)
+	}
+}
+
+func (b *batcher) loop() {
+	defer close(b.doneC)
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			b.flush()
+		case <-b.flushC:
+			b.flush()
+		case <-b.closeC:
+			b.flush()
+			return
+		}
+	}
+}
+
+func (b *batcher) close() {
+	b.once.Do(func() { close(b.closeC) })
+	<-b.doneC
+}
@@ -0,0 +1,119 @@
+package spoor
+
+import (
+	"io"
+	"log"
+	"os"
+)
+
+// LoggerBuilder assembles a logger step by step, see Builder.
+type LoggerBuilder struct {
+	level     Level
+	flag      int
+	formatter Formatter
+	writers   []io.Writer
+	queueSize int
+	opts      []Option
+}
+
+// Builder starts a logger, INFO and text by default, which is configured by
+// chaining the methods of the returned LoggerBuilder:
+//
+//	l := spoor.Builder().Console().Level(spoor.INFO).JSON().Async(8192).Sample(0.1).AddWriter(fileWriter).Build()
+func Builder() *LoggerBuilder {
+	return &LoggerBuilder{level: INFO}
+}
+
+func (b *LoggerBuilder) Level(level Level) *LoggerBuilder {
+	b.level = level
+	return b
+}
+
+// Console adds stderr to the writers.
+func (b *LoggerBuilder) Console() *LoggerBuilder {
+	return b.AddWriter(os.Stderr)
+}
+
+// File adds a FileWriter writing to path, rotated at maxSize bytes if not 0.
+func (b *LoggerBuilder) File(path string, maxSize uint64, opts ...FileOption) *LoggerBuilder {
+	return b.AddWriter(NewFilePathWriter(path, 0, 0, maxSize, opts...))
+}
+
+// AddWriter adds w to the writers, every entry is written to all of them.
+func (b *LoggerBuilder) AddWriter(w io.Writer) *LoggerBuilder {
+	b.writers = append(b.writers, w)
+	return b
+}
+
+func (b *LoggerBuilder) Formatter(formatter Formatter) *LoggerBuilder {
+	b.formatter = formatter
+	return b
+}
+
+func (b *LoggerBuilder) JSON() *LoggerBuilder {
+	return b.Formatter(&JSONFormatter{})
+}
+
+func (b *LoggerBuilder) Text() *LoggerBuilder {
+	return b.Formatter(&TextFormatter{})
+}
+
+// Dev writes with DevFormatter, colored when stderr is a terminal.
+func (b *LoggerBuilder) Dev() *LoggerBuilder {
+	return b.Formatter(&DevFormatter{Color: isTerminal(os.Stderr)})
+}
+
+// Caller records the file and line of the call.
+func (b *LoggerBuilder) Caller() *LoggerBuilder {
+	b.flag = log.Lshortfile
+	return b
+}
+
+// Async puts every writer behind its own AsyncWriter queuing up to
+// queueSize lines, so that a slow writer does not hold up the others. The
+// AsyncWriters are registered for Shutdown.
+func (b *LoggerBuilder) Async(queueSize int) *LoggerBuilder {
+	b.queueSize = queueSize
+	return b
+}
+
+// Sample keeps about rate, between 0 and 1, of the entries, see
+// WithSampleRate.
+func (b *LoggerBuilder) Sample(rate float64) *LoggerBuilder {
+	return b.With(WithSampleRate(rate))
+}
+
+// With adds options applied after those of the builder.
+func (b *LoggerBuilder) With(opts ...Option) *LoggerBuilder {
+	b.opts = append(b.opts, opts...)
+	return b
+}
+
+// Build creates the logger, it writes to stderr if no writer was added.
+func (b *LoggerBuilder) Build() *Spoor {
+	writers := append([]io.Writer(nil), b.writers...)
+	if len(writers) == 0 {
+		writers = []io.Writer{os.Stderr}
+	}
+	if b.queueSize > 0 {
+		for i, w := range writers {
+			aw := NewAsyncWriter(w, AsyncConfig{QueueSize: b.queueSize})
+			Register(aw)
+			writers[i] = aw
+		}
+	}
+	var opts []Option
+	if len(writers) == 1 {
+		opts = append(opts, WithConsoleWriter(writers[0]))
+	} else {
+		dests := make([]Destination, len(writers))
+		for i, w := range writers {
+			dests[i] = Destination{Writer: w, Level: b.level}
+		}
+		opts = append(opts, WithDestinations(dests...))
+	}
+	if b.formatter != nil {
+		opts = append(opts, WithFormatter(b.formatter))
+	}
+	return NewSpoor(b.level, "", b.flag, append(opts, b.opts...)...)
+}
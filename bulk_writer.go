@@ -0,0 +1,32 @@
+package spoor
+
+import "io"
+
+// BulkWriter is implemented by writers accepting many entries in one call,
+// such as the batching Elastic, Logbus, CloudWatch and Stackdriver writers,
+// so that buffering writers in front of them can hand over a whole batch.
+type BulkWriter interface {
+	io.Writer
+	WriteBatch(lines [][]byte) error
+}
+
+// WriteBatch writes lines to w in one call if w is a BulkWriter, with one
+// Write per line otherwise. It stops at the first error.
+func WriteBatch(w io.Writer, lines [][]byte) error {
+	if bw, ok := w.(BulkWriter); ok {
+		return bw.WriteBatch(lines)
+	}
+	for _, line := range lines {
+		if _, err := w.Write(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	_ BulkWriter = (*ElasticWriter)(nil)
+	_ BulkWriter = (*LogbusWriter)(nil)
+	_ BulkWriter = (*CloudWatchWriter)(nil)
+	_ BulkWriter = (*StackdriverWriter)(nil)
+)
@@ -0,0 +1,104 @@
+package spoor
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("spoor: circuit breaker is open")
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// CircuitBreakerWriter stops calling a failing writer after threshold
+// consecutive errors. While open, writes go to fallback (or are dropped with
+// ErrCircuitOpen when fallback is nil); after coolDown one write is let
+// through to probe whether the writer has recovered.
+type CircuitBreakerWriter struct {
+	writer    io.Writer
+	fallback  io.Writer
+	threshold int
+	coolDown  time.Duration
+	mu        sync.Mutex
+	state     circuitState
+	failures  int
+	openedAt  time.Time
+}
+
+func NewCircuitBreakerWriter(writer, fallback io.Writer, threshold int, coolDown time.Duration) *CircuitBreakerWriter {
+	if threshold == 0 {
+		threshold = 5
+	}
+	if coolDown == 0 {
+		coolDown = time.Second * 30
+	}
+	return &CircuitBreakerWriter{
+		writer:    writer,
+		fallback:  fallback,
+		threshold: threshold,
+		coolDown:  coolDown,
+	}
+}
+
+func (cw *CircuitBreakerWriter) Write(p []byte) (n int, err error) {
+	if !cw.allow() {
+		if cw.fallback == nil {
+			return 0, ErrCircuitOpen
+		}
+		return cw.fallback.Write(p)
+	}
+	n, err = cw.writer.Write(p)
+	cw.record(err)
+	if err != nil && cw.fallback != nil {
+		return cw.fallback.Write(p)
+	}
+	return n, err
+}
+
+// allow reports whether the wrapped writer may be called, moving an open
+// breaker to half-open once the cool-down has passed.
+func (cw *CircuitBreakerWriter) allow() bool {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	switch cw.state {
+	case circuitOpen:
+		if time.Since(cw.openedAt) < cw.coolDown {
+			return false
+		}
+		cw.state = circuitHalfOpen
+		return true
+	case circuitHalfOpen:
+		// a probe is already in flight
+		return false
+	}
+	return true
+}
+
+func (cw *CircuitBreakerWriter) record(err error) {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	if err == nil {
+		cw.state = circuitClosed
+		cw.failures = 0
+		return
+	}
+	cw.failures++
+	if cw.state == circuitHalfOpen || cw.failures >= cw.threshold {
+		cw.state = circuitOpen
+		cw.openedAt = time.Now()
+	}
+}
+
+// Open reports whether writes are currently short-circuited.
+func (cw *CircuitBreakerWriter) Open() bool {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	return cw.state != circuitClosed
+}
@@ -0,0 +1,109 @@
+package spoor
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// CLIFormatter is the human first formatter of command line tools: the
+// message alone for INFO, prefixed by the level otherwise, e.g.
+// "warning: config not found path=/etc/app.yaml", without time or caller.
+type CLIFormatter struct {
+	Color  bool
+	Values ValueFormat
+}
+
+func (f *CLIFormatter) Format(buf *bytes.Buffer, e *Entry) {
+	if e.Level != INFO {
+		f.color(buf, levelColor(e.Level))
+		buf.WriteString(strings.ToLower(e.Level.String()))
+		buf.WriteString(": ")
+		f.color(buf, colorReset)
+	}
+	buf.WriteString(e.Message)
+	for _, field := range e.Fields {
+		buf.WriteByte(' ')
+		f.color(buf, colorGray)
+		buf.WriteString(field.Key)
+		buf.WriteByte('=')
+		f.color(buf, colorReset)
+		appendTextValue(buf, field, &f.Values)
+	}
+	buf.WriteByte('\n')
+}
+
+func (f *CLIFormatter) color(buf *bytes.Buffer, code string) {
+	if f.Color {
+		buf.WriteString(code)
+	}
+}
+
+// CLIFlags are the logging flags of a command line tool, see RegisterFlags
+// and Logger:
+//
+//	var logFlags spoor.CLIFlags
+//	logFlags.RegisterFlags(flag.CommandLine)
+//	flag.Parse()
+//	l, err := logFlags.Logger()
+//
+// With cobra, register them on a flag.FlagSet added to the command with
+// cmd.PersistentFlags().AddGoFlagSet.
+type CLIFlags struct {
+	Verbose bool
+	Quiet   bool
+	// Format is "cli", the default, "text", "json" or "dev".
+	Format string
+}
+
+// RegisterFlags adds the -verbose, -quiet and -log-format flags to fs.
+func (c *CLIFlags) RegisterFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&c.Verbose, "verbose", c.Verbose, "log debug messages")
+	fs.BoolVar(&c.Quiet, "quiet", c.Quiet, "log only errors")
+	fs.Func("log-format", "log format: cli, text, json or dev", func(s string) error {
+		if _, err := cliFormatter(s); err != nil {
+			return err
+		}
+		c.Format = s
+		return nil
+	})
+}
+
+// Level is ERROR with Quiet, DEBUG with Verbose and INFO otherwise.
+func (c *CLIFlags) Level() Level {
+	switch {
+	case c.Quiet:
+		return ERROR
+	case c.Verbose:
+		return DEBUG
+	}
+	return INFO
+}
+
+// Logger returns a logger writing to stderr at the level and in the format
+// of the flags, colored when stderr is a terminal and NO_COLOR is not set.
+func (c *CLIFlags) Logger(opts ...Option) (*Spoor, error) {
+	formatter, err := cliFormatter(c.Format)
+	if err != nil {
+		return nil, err
+	}
+	opts = append([]Option{WithConsoleWriter(os.Stderr), WithFormatter(formatter)}, opts...)
+	return NewSpoor(c.Level(), "", 0, opts...), nil
+}
+
+func cliFormatter(format string) (Formatter, error) {
+	color := isTerminal(os.Stderr) && os.Getenv("NO_COLOR") == ""
+	switch strings.ToLower(format) {
+	case "", "cli":
+		return &CLIFormatter{Color: color}, nil
+	case "text":
+		return &TextFormatter{}, nil
+	case "json":
+		return &JSONFormatter{}, nil
+	case "dev":
+		return &DevFormatter{Color: color}, nil
+	}
+	return nil, fmt.Errorf("spoor: unknown log format %q, want cli, text, json or dev", format)
+}
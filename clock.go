@@ -0,0 +1,30 @@
+package spoor
+
+import "time"
+
+// Clock supplies entry timestamps, tests can inject a fixed one.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time { return time.Time(c) }
+
+func WithClock(clock Clock) Option {
+	return func(spoor *Spoor) {
+		spoor.clock = clock
+	}
+}
+
+// At returns a logger whose entries are stamped with t instead of the current
+// time, e.g. when replaying or ingesting external events.
+func (l *Spoor) At(t time.Time) *Spoor {
+	c := *l
+	c.clock = fixedClock(t)
+	return &c
+}
@@ -0,0 +1,203 @@
+package spoor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	cloudWatchMaxEvents     = 10000
+	cloudWatchMaxBatchBytes = 1048576
+	cloudWatchEventOverhead = 26
+	cloudWatchMaxEventBytes = 256*1024 - cloudWatchEventOverhead
+)
+
+type CloudWatchConfig struct {
+	LogGroup      string
+	LogStream     string
+	Region        string
+	Credentials   AWSCredentials
+	BatchSize     int
+	FlushInterval time.Duration
+	MaxRetries    int // shorthand for Retry.MaxRetries
+	Retry         RetryPolicy
+	// Transport sets the proxy, TLS or extra headers, the requests are
+	// signed with Credentials so its SigV4 is ignored.
+	Transport HTTPTransport
+	Client    *http.Client
+}
+
+// CloudWatchWriter sends entries to CloudWatch Logs with PutLogEvents,
+// splitting batches along the service's count and payload limits.
+type CloudWatchWriter struct {
+	*batcher
+	cfg           CloudWatchConfig
+	sequenceToken string
+	streamCreated bool
+}
+
+func NewCloudWatchWriter(cfg CloudWatchConfig) *CloudWatchWriter {
+	cfg.Region = awsRegion(cfg.Region)
+	if cfg.LogStream == "" {
+		cfg.LogStream = fmt.Sprintf("%s/%s/%d", host, program, pid)
+	}
+	if cfg.Credentials.AccessKeyID == "" {
+		cfg.Credentials = AWSCredentialsFromEnv()
+	}
+	if cfg.BatchSize == 0 || cfg.BatchSize > cloudWatchMaxEvents {
+		cfg.BatchSize = cloudWatchMaxEvents
+	}
+	if cfg.FlushInterval == 0 {
+		cfg.FlushInterval = time.Second * 5
+	}
+	cfg.Retry = cfg.Retry.withDefaults(cfg.MaxRetries, time.Second)
+	if cfg.Client == nil {
+		cfg.Client = cfg.Transport.client(time.Second * 10)
+	}
+	cw := &CloudWatchWriter{cfg: cfg}
+	cw.batcher = newBatcher(cfg.BatchSize, cloudWatchMaxBatchBytes, cfg.FlushInterval, cw.send)
+	return cw
+}
+
+type cloudWatchEvent struct {
+	Timestamp int64  `json:"timestamp"`
+	Message   string `json:"message"`
+}
+
+func (cw *CloudWatchWriter) send(lines [][]byte) {
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	var events []cloudWatchEvent
+	size := 0
+	for _, line := range lines {
+		msg := strings.TrimRight(string(line), "\n")
+		if msg == "" {
+			continue
+		}
+		if len(msg) > cloudWatchMaxEventBytes {
+			msg = msg[:runeBoundary(msg, cloudWatchMaxEventBytes)]
+		}
+		if size+len(msg)+cloudWatchEventOverhead > cloudWatchMaxBatchBytes {
+			cw.put(events)
+			events, size = nil, 0
+		}
+		ts := now
+		if t, ok := lineTime(line); ok {
+			ts = t.UnixNano() / int64(time.Millisecond)
+		}
+		events = append(events, cloudWatchEvent{Timestamp: ts, Message: msg})
+		size += len(msg) + cloudWatchEventOverhead
+	}
+	if len(events) > 0 {
+		cw.put(events)
+	}
+}
+
+func (cw *CloudWatchWriter) put(events []cloudWatchEvent) {
+	// PutLogEvents wants the events of a batch in chronological order
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Timestamp < events[j].Timestamp })
+	err := cw.cfg.Retry.Do(func() error {
+		if !cw.streamCreated {
+			if err := cw.createStream(); err != nil {
+				return err
+			}
+			cw.streamCreated = true
+		}
+		return cw.putEvents(events)
+	})
+	if err != nil {
+		internalError(fmt.Errorf("spoor: cloudwatch dropped %d events: %w", len(events), err))
+	}
+}
+
+func (cw *CloudWatchWriter) putEvents(events []cloudWatchEvent) error {
+	req := map[string]interface{}{
+		"logGroupName":  cw.cfg.LogGroup,
+		"logStreamName": cw.cfg.LogStream,
+		"logEvents":     events,
+	}
+	if cw.sequenceToken != "" {
+		req["sequenceToken"] = cw.sequenceToken
+	}
+	var resp struct {
+		NextSequenceToken     string `json:"nextSequenceToken"`
+		ExpectedSequenceToken string `json:"expectedSequenceToken"`
+	}
+	errType, err := cw.call("PutLogEvents", req, &resp)
+	switch {
+	case err == nil:
+		cw.sequenceToken = resp.NextSequenceToken
+	case strings.HasSuffix(errType, "DataAlreadyAcceptedException"):
+		cw.sequenceToken = resp.ExpectedSequenceToken
+		return nil
+	case strings.HasSuffix(errType, "InvalidSequenceTokenException"):
+		// fixed by retrying with the expected token
+		cw.sequenceToken = resp.ExpectedSequenceToken
+		return unavailable(err)
+	case strings.HasSuffix(errType, "ResourceNotFoundException"):
+		// fixed by retrying after creating the stream
+		cw.streamCreated = false
+		return unavailable(err)
+	}
+	return err
+}
+
+func (cw *CloudWatchWriter) createStream() error {
+	req := map[string]string{
+		"logGroupName":  cw.cfg.LogGroup,
+		"logStreamName": cw.cfg.LogStream,
+	}
+	errType, err := cw.call("CreateLogStream", req, nil)
+	if strings.HasSuffix(errType, "ResourceAlreadyExistsException") {
+		return nil
+	}
+	return err
+}
+
+// call invokes a CloudWatch Logs action, on failure the AWS error type is
+// returned as well and resp is still decoded from the error body.
+func (cw *CloudWatchWriter) call(action string, reqBody, resp interface{}) (errType string, err error) {
+	body, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", err
+	}
+	endpoint := "https://logs." + cw.cfg.Region + ".amazonaws.com/"
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "Logs_20140328."+action)
+	cw.cfg.Transport.setHeaders(req)
+	signV4(req, body, cw.cfg.Credentials, cw.cfg.Region, "logs", time.Now())
+	r, err := cw.cfg.Client.Do(req)
+	if err != nil {
+		return "", unavailable(err)
+	}
+	defer r.Body.Close()
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp != nil {
+		json.Unmarshal(data, resp)
+	}
+	if r.StatusCode < 200 || r.StatusCode > 299 {
+		var awsErr struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		json.Unmarshal(data, &awsErr)
+		err = fmt.Errorf("cloudwatch %s: %s: %s %s", action, r.Status, awsErr.Type, awsErr.Message)
+		if retryableStatus(r.StatusCode) || strings.HasSuffix(awsErr.Type, "ThrottlingException") {
+			err = unavailable(err)
+		}
+		return awsErr.Type, err
+	}
+	return "", nil
+}
@@ -0,0 +1,155 @@
+package spoor
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// redirectTransport sends every request to a test server, for the writers
+// whose endpoint is fixed.
+type redirectTransport struct{ target *url.URL }
+
+func (rt redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme, req.URL.Host = rt.target.Scheme, rt.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func redirectClient(srv *httptest.Server) *http.Client {
+	target, _ := url.Parse(srv.URL)
+	return &http.Client{Transport: redirectTransport{target}}
+}
+
+type cloudWatchPut struct {
+	LogGroupName  string            `json:"logGroupName"`
+	LogStreamName string            `json:"logStreamName"`
+	SequenceToken string            `json:"sequenceToken"`
+	LogEvents     []cloudWatchEvent `json:"logEvents"`
+}
+
+// fakeCloudWatch accepts the puts carrying the expected sequence token, the
+// first one without, and answers InvalidSequenceTokenException otherwise.
+type fakeCloudWatch struct {
+	mu       sync.Mutex
+	expected string
+	actions  []string
+	puts     []cloudWatchPut
+	header   http.Header
+}
+
+func (f *fakeCloudWatch) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	action := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "Logs_20140328.")
+	f.actions = append(f.actions, action)
+	f.header = r.Header
+	if action != "PutLogEvents" {
+		w.Write([]byte("{}"))
+		return
+	}
+	var put cloudWatchPut
+	data, _ := io.ReadAll(r.Body)
+	json.Unmarshal(data, &put)
+	if put.SequenceToken != f.expected {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{
+			"__type":                "com.amazonaws.logs#InvalidSequenceTokenException",
+			"message":               "The given sequenceToken is invalid",
+			"expectedSequenceToken": f.expected,
+		})
+		return
+	}
+	f.puts = append(f.puts, put)
+	f.expected = "token-" + string(rune('a'+len(f.puts)))
+	json.NewEncoder(w).Encode(map[string]string{"nextSequenceToken": f.expected})
+}
+
+func TestCloudWatchWriter(t *testing.T) {
+	f := &fakeCloudWatch{}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+	cw := NewCloudWatchWriter(CloudWatchConfig{
+		LogGroup:      "app",
+		LogStream:     "web-1",
+		Region:        "eu-west-1",
+		Credentials:   AWSCredentials{AccessKeyID: "AKID", SecretAccessKey: "secret"},
+		BatchSize:     20000,
+		FlushInterval: time.Hour,
+		Retry:         RetryPolicy{InitialBackoff: time.Millisecond},
+		Transport:     HTTPTransport{Headers: map[string]string{"X-Tenant": "t1"}},
+		Client:        redirectClient(srv),
+	})
+	defer cw.Close()
+	if cw.cfg.BatchSize != cloudWatchMaxEvents {
+		t.Fatalf("BatchSize %d above the PutLogEvents limit", cw.cfg.BatchSize)
+	}
+
+	cw.send([][]byte{
+		[]byte(`{"time":"2021-02-03T04:05:07Z","msg":"second"}` + "\n"),
+		[]byte(`{"time":"2021-02-03T04:05:06Z","msg":"first"}` + "\n"),
+	})
+	cw.send([][]byte{[]byte("INFO next batch\n")})
+	// the token got lost, the writer retries with the expected one
+	cw.sequenceToken = "stale"
+	cw.send([][]byte{[]byte("INFO after a stale token\n")})
+
+	if got := strings.Join(f.actions, ","); got != "CreateLogStream,PutLogEvents,PutLogEvents,PutLogEvents,PutLogEvents" {
+		t.Fatalf("actions %s", got)
+	}
+	if len(f.puts) != 3 {
+		t.Fatalf("got %d puts, want 3", len(f.puts))
+	}
+	first := f.puts[0]
+	if first.LogGroupName != "app" || first.LogStreamName != "web-1" || first.SequenceToken != "" || len(first.LogEvents) != 2 ||
+		first.LogEvents[0].Timestamp != 1612325106000 || !strings.Contains(first.LogEvents[0].Message, "first") {
+		t.Fatalf("first put %+v", first)
+	}
+	if f.puts[1].SequenceToken != "token-b" || f.puts[2].SequenceToken != "token-c" {
+		t.Fatalf("sequence tokens %q, %q", f.puts[1].SequenceToken, f.puts[2].SequenceToken)
+	}
+	if f.header.Get("Content-Type") != "application/x-amz-json-1.1" || f.header.Get("X-Tenant") != "t1" ||
+		!strings.Contains(f.header.Get("Authorization"), "Credential=AKID/") || !strings.Contains(f.header.Get("Authorization"), "/eu-west-1/logs/aws4_request") {
+		t.Fatalf("headers %v", f.header)
+	}
+
+	proxy, _ := url.Parse("http://proxy.example:3128")
+	cw = NewCloudWatchWriter(CloudWatchConfig{LogGroup: "app", Transport: HTTPTransport{Proxy: proxy}})
+	defer cw.Close()
+	req, _ := http.NewRequest(http.MethodPost, "https://logs.eu-west-1.amazonaws.com/", nil)
+	if u, err := cw.cfg.Client.Transport.(*http.Transport).Proxy(req); err != nil || u.String() != proxy.String() {
+		t.Fatalf("proxy %v, %v", u, err)
+	}
+}
+
+func TestCloudWatchWriterLimits(t *testing.T) {
+	f := &fakeCloudWatch{}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+	cw := NewCloudWatchWriter(CloudWatchConfig{LogGroup: "app", Credentials: AWSCredentials{AccessKeyID: "AKID"}, FlushInterval: time.Hour, Client: redirectClient(srv)})
+	defer cw.Close()
+
+	// 5 events at the size limit: 4 fill the 1MB of a request
+	big := []byte("INFO " + strings.Repeat("x", cloudWatchMaxEventBytes) + "\n")
+	cw.send([][]byte{big, big, big, big, big})
+	if len(f.puts) != 2 || len(f.puts[0].LogEvents) != 4 || len(f.puts[1].LogEvents) != 1 {
+		t.Fatalf("got %d puts", len(f.puts))
+	}
+	if n := len(f.puts[0].LogEvents[0].Message); n != cloudWatchMaxEventBytes {
+		t.Fatalf("event of %d bytes, want it cut to %d", n, cloudWatchMaxEventBytes)
+	}
+
+	// an event cut in the middle of a rune keeps valid UTF-8
+	cw.send([][]byte{[]byte("INFO " + strings.Repeat("é", cloudWatchMaxEventBytes/2) + "\n")})
+	msg := f.puts[2].LogEvents[0].Message
+	if !utf8.ValidString(msg) || len(msg) != cloudWatchMaxEventBytes-1 {
+		t.Fatalf("event of %d bytes, valid UTF-8 %v", len(msg), utf8.ValidString(msg))
+	}
+}
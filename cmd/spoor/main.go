@@ -0,0 +1,457 @@
+// Command spoor reads the JSON log files written by spoor loggers:
+//
+//	spoor tail [-n 10] [-f] [flags] file
+//	spoor cat [flags] file...
+//	spoor query [-since time] [-until time] [flags] pattern
+//	spoor index file...
+//	spoor replay [replay flags] file...
+//	spoor validate [-strict] [-probe] config
+//
+// tail prints the last lines of a file and, with -f, follows it across
+// rotations when file is the symlink set with WithSymlink or a file which is
+// renamed or truncated. Both commands take the flags:
+//
+//	-level warn        skip the entries below a level
+//	-where key=value   keep the entries whose field, or msg, is value; != and
+//	                   ~ (contains) may be used instead of =, and -where may
+//	                   be repeated
+//	-format pretty     write the entries with the pretty (DevFormatter), text
+//	                   or json formatter
+//	-color             colorize the pretty format
+//
+// Lines which are not JSON are written as they are when no filter is set.
+//
+// query reads the entries of the files matching the glob pattern, e.g.
+// "/var/log/app/*.log", oldest first, between the RFC 3339 times of -since
+// and -until. The files whose index, written by FileWriters with WithIndex or
+// by index, shows they have no matching entry are skipped.
+//
+// replay sends the lines of log files, which may be gzip compressed, to
+// Elasticsearch, a logbus endpoint or stdout with their original timestamp,
+// see spoor.ReplayLog. It takes the flags:
+//
+//	-elastic url -index name   send to Elasticsearch
+//	-logbus url -token token   send to a logbus endpoint
+//	-since time -until time    skip the entries outside, in RFC 3339
+//	-level warn                skip the entries below a level
+//	-rate 1000                 send at most this many lines per second
+//
+// validate checks a logger config file, see spoor.Config, without building
+// the loggers and prints its problems; -strict reports the unknown keys and
+// -probe connects to the endpoints of the remote writers.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/phuhao00/spoor"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "spoor:", err)
+		os.Exit(1)
+	}
+}
+
+const usage = `usage: spoor tail [-n 10] [-f] [flags] file
+       spoor cat [flags] file...
+       spoor query [-since time] [-until time] [flags] pattern
+       spoor index file...
+       spoor replay [flags] file...
+       spoor validate [-strict] [-probe] config`
+
+func run(args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	switch args[0] {
+	case "replay":
+		return replay(args[1:], stdout)
+	case "validate":
+		return validate(args[1:], stdout)
+	case "index":
+		for _, name := range args[1:] {
+			if _, err := spoor.BuildIndex(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	fs := flag.NewFlagSet("spoor "+args[0], flag.ContinueOnError)
+	var p printer
+	var wheres whereFlag
+	fs.Var(&p.level, "level", "skip the entries below `level`")
+	fs.Var(&wheres, "where", "keep the entries matching `key=value`, key!=value or key~value")
+	format := fs.String("format", "pretty", "output `format`: pretty, text or json")
+	color := fs.Bool("color", false, "colorize the pretty format")
+	switch args[0] {
+	case "tail":
+		n := fs.Int("n", 10, "print the last `n` entries")
+		follow := fs.Bool("f", false, "follow the file")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() != 1 {
+			return errors.New(usage)
+		}
+		if err := p.init(stdout, *format, *color, wheres); err != nil {
+			return err
+		}
+		return tail(fs.Arg(0), *n, *follow, &p)
+	case "query":
+		var q spoor.LogQuery
+		fs.Var(timeFlag{&q.Since}, "since", "skip the entries before `time`")
+		fs.Var(timeFlag{&q.Until}, "until", "skip the entries from `time`")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() != 1 {
+			return errors.New(usage)
+		}
+		if err := p.init(stdout, *format, *color, wheres); err != nil {
+			return err
+		}
+		q.Level, q.Fields = p.level, make(map[string]string)
+		for _, c := range wheres {
+			if c.op == "=" && c.key != "msg" {
+				q.Fields[c.key] = c.value
+			}
+		}
+		var err error
+		qerr := spoor.QueryLogs(fs.Arg(0), q, func(e *spoor.Entry) bool {
+			err = p.write(e)
+			return err == nil
+		})
+		if qerr != nil {
+			return qerr
+		}
+		return err
+	case "cat":
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if err := p.init(stdout, *format, *color, wheres); err != nil {
+			return err
+		}
+		for _, name := range fs.Args() {
+			if err := cat(name, &p); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return errors.New(usage)
+}
+
+// condition is one -where expression.
+type condition struct {
+	key, op, value string
+}
+
+type whereFlag []condition
+
+func (w *whereFlag) String() string { return "" }
+
+func (w *whereFlag) Set(s string) error {
+	for _, op := range []string{"!=", "~", "="} {
+		if i := strings.Index(s, op); i > 0 {
+			*w = append(*w, condition{key: s[:i], op: op, value: s[i+len(op):]})
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid expression %q", s)
+}
+
+// printer filters and formats the lines read.
+type printer struct {
+	out       io.Writer
+	formatter spoor.Formatter
+	level     spoor.Level
+	where     []condition
+	buf       bytes.Buffer
+}
+
+func (p *printer) init(out io.Writer, format string, color bool, where []condition) error {
+	p.out, p.where = out, where
+	switch format {
+	case "pretty":
+		p.formatter = &spoor.DevFormatter{Color: color}
+	case "text":
+		p.formatter = &spoor.TextFormatter{Flag: log.LstdFlags | log.Lmicroseconds}
+	case "json":
+		p.formatter = &spoor.JSONFormatter{}
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+	return nil
+}
+
+// render returns the output for line, nil if it is filtered out.
+func (p *printer) render(line []byte) []byte {
+	e, err := spoor.ParseJSONEntry(line)
+	if err != nil {
+		if p.level != 0 || len(p.where) > 0 || len(bytes.TrimSpace(line)) == 0 {
+			return nil
+		}
+		return append(bytes.TrimRight(line, "\n"), '\n')
+	}
+	if e.Level < p.level || !p.match(&e) {
+		return nil
+	}
+	p.buf.Reset()
+	p.formatter.Format(&p.buf, &e)
+	return append([]byte(nil), p.buf.Bytes()...)
+}
+
+// write formats e if it matches the filters.
+func (p *printer) write(e *spoor.Entry) error {
+	if e.Level < p.level || !p.match(e) {
+		return nil
+	}
+	p.buf.Reset()
+	p.formatter.Format(&p.buf, e)
+	_, err := p.out.Write(p.buf.Bytes())
+	return err
+}
+
+func (p *printer) match(e *spoor.Entry) bool {
+	for _, c := range p.where {
+		value, found := e.Message, c.key == "msg"
+		for _, f := range e.Fields {
+			if !found && f.Key == c.key {
+				value, found = fmt.Sprint(f.Value()), true
+			}
+		}
+		var ok bool
+		switch c.op {
+		case "=":
+			ok = found && value == c.value
+		case "!=":
+			ok = !found || value != c.value
+		case "~":
+			ok = found && strings.Contains(value, c.value)
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func cat(name string, p *printer) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if out := p.render(line); out != nil {
+			if _, err := p.out.Write(out); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func tail(name string, n int, follow bool, p *printer) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer func() { f.Close() }()
+	r := bufio.NewReader(f)
+	// keep the last n entries, the partial last line is left for follow
+	last := make([][]byte, 0, n)
+	var partial []byte
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			partial = line
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if out := p.render(line); out != nil && n > 0 {
+			if len(last) == n {
+				last = append(last[:0], last[1:]...)
+			}
+			last = append(last, out)
+		}
+	}
+	for _, out := range last {
+		if _, err := p.out.Write(out); err != nil {
+			return err
+		}
+	}
+	for follow {
+		line, err := r.ReadBytes('\n')
+		partial = append(partial, line...)
+		if err == nil {
+			if out := p.render(partial); out != nil {
+				if _, err := p.out.Write(out); err != nil {
+					return err
+				}
+			}
+			partial = partial[:0]
+			continue
+		}
+		if err != io.EOF {
+			return err
+		}
+		time.Sleep(time.Millisecond * 250)
+		if reopened, err := reopen(name, f); err != nil {
+			return err
+		} else if reopened != nil {
+			f = reopened
+			r.Reset(f)
+			partial = partial[:0]
+		}
+	}
+	return nil
+}
+
+// reopen returns the file now at name if it is not f, or f rewound if it was
+// truncated, and nil if f is still the file to read. It is called at the end
+// of f so that a rotated file is read to its end first.
+func reopen(name string, f *os.File) (*os.File, error) {
+	fi, err := os.Stat(name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil // between a rename and the new file
+		}
+		return nil, err
+	}
+	cur, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if !os.SameFile(fi, cur) {
+		nf, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		f.Close()
+		return nf, nil
+	}
+	if offset, err := f.Seek(0, io.SeekCurrent); err == nil && fi.Size() < offset {
+		_, err := f.Seek(0, io.SeekStart)
+		return f, err
+	}
+	return nil, nil
+}
+
+func replay(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("spoor replay", flag.ContinueOnError)
+	var opts spoor.ReplayOptions
+	fs.Var(&opts.Level, "level", "skip the entries below `level`")
+	fs.Var(timeFlag{&opts.Since}, "since", "skip the entries before `time`")
+	fs.Var(timeFlag{&opts.Until}, "until", "skip the entries from `time`")
+	fs.IntVar(&opts.Rate, "rate", 0, "send at most `n` lines per second")
+	elastic := fs.String("elastic", "", "Elasticsearch `url`")
+	index := fs.String("index", "", "Elasticsearch `index`")
+	logbus := fs.String("logbus", "", "logbus endpoint `url`")
+	token := fs.String("token", "", "logbus `token`")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	var w io.Writer = stdout
+	switch {
+	case *elastic != "":
+		w = spoor.NewElasticWriter(spoor.ElasticConfig{URL: *elastic, Index: *index})
+	case *logbus != "":
+		w = spoor.NewLogbusWriter(spoor.LogbusConfig{Endpoint: *logbus, Token: *token})
+	}
+	var mu sync.Mutex
+	var errs []error
+	spoor.SetInternalErrorHandler(func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	})
+	total := 0
+	for _, name := range fs.Args() {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		n, err := spoor.ReplayLog(f, w, opts)
+		f.Close()
+		total += n
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	if c, ok := w.(io.Closer); ok {
+		c.Close()
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%d lines handed over, failures: %v", total, errs)
+	}
+	if w != stdout {
+		fmt.Fprintf(os.Stderr, "%d lines sent\n", total)
+	}
+	return nil
+}
+
+type timeFlag struct {
+	t *time.Time
+}
+
+func (f timeFlag) String() string {
+	if f.t == nil || f.t.IsZero() {
+		return ""
+	}
+	return f.t.Format(time.RFC3339)
+}
+
+func (f timeFlag) Set(s string) error {
+	t, err := time.Parse(time.RFC3339, s)
+	*f.t = t
+	return err
+}
+
+func validate(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("spoor validate", flag.ContinueOnError)
+	var opts spoor.ValidateOptions
+	fs.BoolVar(&opts.Strict, "strict", false, "report the unknown keys")
+	fs.BoolVar(&opts.Probe, "probe", false, "connect to the endpoints of the remote writers")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New(usage)
+	}
+	cfg, err := spoor.LoadConfig(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	err = spoor.ValidateConfig(cfg, opts)
+	var errs spoor.ConfigErrors
+	if !errors.As(err, &errs) {
+		return err
+	}
+	for _, e := range errs {
+		fmt.Fprintln(stdout, e)
+	}
+	return fmt.Errorf("%s: %d problems", fs.Arg(0), len(errs))
+}
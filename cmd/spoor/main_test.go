@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/phuhao00/spoor"
+)
+
+func TestCat(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := time.Date(2021, 2, 3, 4, 5, 6, 0, time.UTC)
+	l := spoor.NewSpoor(spoor.DEBUG, "", 0, spoor.WithConsoleWriter(f), spoor.WithFormatter(&spoor.JSONFormatter{}), spoor.WithClock(fixedClock(ts)))
+	l.Debug("cache miss", spoor.String("key", "a"))
+	l.Warn("slow request", spoor.String("path", "/users"), spoor.Int("ms", 900))
+	l.Error("failed", spoor.String("path", "/orders"))
+	f.WriteString("plain line\n")
+	f.Close()
+
+	var out bytes.Buffer
+	if err := run([]string{"cat", "-level", "warn", "-where", "path~/u", "-format", "text", name}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if want := "2021/02/03 04:05:06.000000 WARNING slow request path=/users ms=900\n"; out.String() != want {
+		t.Fatalf("got %q, want %q", out.String(), want)
+	}
+	out.Reset()
+	if err := run([]string{"tail", "-n", "2", "-format", "json", name}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"time":"2021-02-03T04:05:06Z","level":"ERROR","msg":"failed","fields":{"path":"/orders"}}` + "\nplain line\n"; out.String() != want {
+		t.Fatalf("got %q, want %q", out.String(), want)
+	}
+}
+
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time { return time.Time(c) }
@@ -0,0 +1,665 @@
+package spoor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config declares loggers built from writers, enrichers and filters which
+// are defined once under a name and shared by the loggers referencing them.
+// It is usually read from a JSON file with LoadConfig:
+//
+//	{
+//	  "level": "info",
+//	  "default": "api",
+//	  "writers": {
+//	    "console": {"type": "stderr", "format": "dev"},
+//	    "file": {"type": "file", "path": "/var/log/app.log", "level": "debug"}
+//	  },
+//	  "enrichers": {"service": {"type": "service", "name": "api", "version": "1.2"}},
+//	  "filters": {"pii": {"drop": ["password", "token"]}},
+//	  "redactors": {"auth": {"keys": ["authorization"], "secrets": true}},
+//	  "samplers": {"tenth": {"rate": 0.1}},
+//	  "loggers": {
+//	    "api": {"level": "debug", "format": "json", "writers": ["console", "file"],
+//	            "enrichers": ["service"], "filters": ["pii"]},
+//	    "access": {"writers": ["file"], "pipeline": ["pii", "auth", "tenth", "service"]}
+//	  }
+//	}
+type Config struct {
+	// Level overrides the level of every logger, e.g. to turn on debug
+	// everywhere without editing each of them.
+	Level string `json:"level"`
+	// Default names the logger returned by NewFromConfig, it defaults to
+	// "default" or to the only logger.
+	Default   string                    `json:"default"`
+	Writers   map[string]WriterConfig   `json:"writers"`
+	Enrichers map[string]EnricherConfig `json:"enrichers"`
+	Filters   map[string]FilterConfig   `json:"filters"`
+	Redactors map[string]RedactorConfig `json:"redactors"`
+	Samplers  map[string]SamplerConfig  `json:"samplers"`
+	Loggers   map[string]LoggerConfig   `json:"loggers"`
+
+	unknown []string // paths of the keys LoadConfig did not know
+}
+
+type LoggerConfig struct {
+	Level  string `json:"level"`  // defaults to "info"
+	Format string `json:"format"` // text, json, ecs or dev, defaults to text
+	Caller bool   `json:"caller"` // record the file and line
+	// Writers are the names of the writers receiving the entries, each of
+	// them at its own level and format.
+	Writers   []string `json:"writers"`
+	Enrichers []string `json:"enrichers"`
+	// Filters are merged in order into the field transform of the logger.
+	Filters []string `json:"filters"`
+	// Pipeline names filters, redactors, samplers and enrichers run in this
+	// order after those above, see WithPipeline. A name defined for several
+	// kinds of components is prefixed with the kind, e.g. "filter:pii".
+	Pipeline []string `json:"pipeline"`
+}
+
+type WriterConfig struct {
+	// Type is stdout, stderr, file, elastic, logbus, datadog or splunk.
+	Type   string `json:"type"`
+	Level  string `json:"level"`  // skip the entries below, if set
+	Format string `json:"format"` // overrides the format of the logger
+	Path   string `json:"path"`   // file
+	// MaxSize is the size in bytes at which a file is rotated.
+	MaxSize uint64 `json:"max_size"`
+	URL     string `json:"url"`   // elastic, logbus, splunk
+	Index   string `json:"index"` // elastic, splunk
+	// Token is the logbus or Splunk token, or the Datadog API key.
+	Token string `json:"token"`
+	Site  string `json:"site"` // datadog
+	// FlushInterval is a duration such as "3s", it is rounded up to seconds
+	// for files.
+	FlushInterval string `json:"flush_interval"`
+}
+
+type EnricherConfig struct {
+	// Type is static, process, service, kubernetes, runtime or secrets.
+	Type    string            `json:"type"`
+	Fields  map[string]string `json:"fields"`  // static
+	Name    string            `json:"name"`    // service
+	Version string            `json:"version"` // service
+	Mask    bool              `json:"mask"`    // secrets, see SecretsEnricher
+}
+
+// FilterConfig is the config form of FieldTransform.
+type FilterConfig struct {
+	Rename         map[string]string `json:"rename"`
+	SnakeCase      bool              `json:"snake_case"`
+	Lowercase      bool              `json:"lowercase"`
+	Drop           []string          `json:"drop"`
+	MaxValueLength int               `json:"max_value_length"`
+}
+
+type RedactorConfig struct {
+	Keys    []string `json:"keys"`    // fields whose value is replaced by [REDACTED]
+	Secrets bool     `json:"secrets"` // mask the secrets, see SecretsEnricher
+}
+
+// SamplerConfig keeps Rate of the entries if set, otherwise it caps repeated
+// entries like WithSampling.
+type SamplerConfig struct {
+	Rate       float64 `json:"rate"`
+	Tick       string  `json:"tick"` // a duration, defaults to "1s"
+	First      int     `json:"first"`
+	Thereafter int     `json:"thereafter"`
+}
+
+// ConfigError is a problem of a Config, Path locates it, e.g.
+// "loggers.api.writers[1]".
+type ConfigError struct {
+	Path string
+	Err  error
+}
+
+func (e *ConfigError) Error() string { return e.Path + ": " + e.Err.Error() }
+
+func (e *ConfigError) Unwrap() error { return e.Err }
+
+// ConfigErrors lists every problem found in a Config.
+type ConfigErrors []*ConfigError
+
+func (errs ConfigErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return "spoor: invalid config: " + strings.Join(msgs, "; ")
+}
+
+func (errs *ConfigErrors) add(path string, format string, args ...interface{}) {
+	*errs = append(*errs, &ConfigError{Path: path, Err: fmt.Errorf(format, args...)})
+}
+
+// LoadConfig reads a Config from a JSON file.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("cannot parse config %s: %v", path, err)
+	}
+	var raw interface{}
+	json.Unmarshal(data, &raw)
+	cfg.unknown = unknownKeys("", raw, reflect.TypeOf(cfg))
+	return &cfg, nil
+}
+
+// unknownKeys returns the paths of the keys of the JSON value v which have
+// no field in t.
+func unknownKeys(path string, v interface{}, t reflect.Type) []string {
+	var unknown []string
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, _ := v.(map[string]interface{})
+		for _, key := range sortedKeys(obj) {
+			field, ok := configField(t, key)
+			if !ok {
+				unknown = append(unknown, joinPath(path, key))
+				continue
+			}
+			unknown = append(unknown, unknownKeys(joinPath(path, key), obj[key], field.Type)...)
+		}
+	case reflect.Map:
+		obj, _ := v.(map[string]interface{})
+		for _, key := range sortedKeys(obj) {
+			unknown = append(unknown, unknownKeys(joinPath(path, key), obj[key], t.Elem())...)
+		}
+	}
+	return unknown
+}
+
+var configFormats = map[string]func() Formatter{
+	"text": func() Formatter { return &TextFormatter{Flag: log.LstdFlags | log.Lmicroseconds} },
+	"json": func() Formatter { return &JSONFormatter{} },
+	"ecs":  func() Formatter { return &ECSFormatter{} },
+	"dev":  func() Formatter { return &DevFormatter{} },
+}
+
+// check returns the problems of c, the entries are visited in name order so
+// that the errors are reported in a stable order.
+func (c *Config) check() ConfigErrors {
+	var errs ConfigErrors
+	checkLevel := func(path, level string) {
+		if level != "" {
+			if _, err := ParseLogLevel(level); err != nil {
+				errs.add(path, "%v", err)
+			}
+		}
+	}
+	checkFormat := func(path, format string) {
+		if _, ok := configFormats[format]; format != "" && !ok {
+			errs.add(path, "unknown format %q (text, json, ecs, dev)", format)
+		}
+	}
+	checkLevel("level", c.Level)
+	if _, ok := c.Loggers[c.Default]; c.Default != "" && !ok {
+		errs.add("default", "unknown logger %q", c.Default)
+	}
+	for _, name := range sortedKeys(c.Writers) {
+		w, path := c.Writers[name], "writers."+name
+		checkLevel(path+".level", w.Level)
+		checkFormat(path+".format", w.Format)
+		if w.FlushInterval != "" {
+			if d, err := time.ParseDuration(w.FlushInterval); err != nil || d <= 0 {
+				errs.add(path+".flush_interval", "invalid duration %q", w.FlushInterval)
+			}
+		}
+		switch w.Type {
+		case "stdout", "stderr":
+		case "file":
+			if w.Path == "" {
+				errs.add(path+".path", "missing for a file writer")
+			}
+		case "elastic", "logbus", "splunk":
+			if w.URL == "" {
+				errs.add(path+".url", "missing for a %s writer", w.Type)
+			}
+		case "datadog":
+			if w.Token == "" {
+				errs.add(path+".token", "missing for a datadog writer")
+			}
+		default:
+			errs.add(path+".type", "unknown writer type %q", w.Type)
+		}
+	}
+	for _, name := range sortedKeys(c.Enrichers) {
+		switch t := c.Enrichers[name].Type; t {
+		case "static", "process", "service", "kubernetes", "runtime", "secrets":
+		default:
+			errs.add("enrichers."+name+".type", "unknown enricher type %q", t)
+		}
+	}
+	for _, name := range sortedKeys(c.Samplers) {
+		sc, path := c.Samplers[name], "samplers."+name
+		if sc.Rate < 0 || sc.Rate > 1 {
+			errs.add(path+".rate", "%v is not between 0 and 1", sc.Rate)
+		}
+		if sc.Tick != "" {
+			if d, err := time.ParseDuration(sc.Tick); err != nil || d <= 0 {
+				errs.add(path+".tick", "invalid duration %q", sc.Tick)
+			}
+		}
+	}
+	for _, name := range sortedKeys(c.Loggers) {
+		lc, path := c.Loggers[name], "loggers."+name
+		checkLevel(path+".level", lc.Level)
+		checkFormat(path+".format", lc.Format)
+		if len(lc.Writers) == 0 {
+			errs.add(path+".writers", "no writer")
+		}
+		for i, ref := range lc.Writers {
+			if _, ok := c.Writers[ref]; !ok {
+				errs.add(fmt.Sprintf("%s.writers[%d]", path, i), "unknown writer %q", ref)
+			}
+		}
+		for i, ref := range lc.Enrichers {
+			if _, ok := c.Enrichers[ref]; !ok {
+				errs.add(fmt.Sprintf("%s.enrichers[%d]", path, i), "unknown enricher %q", ref)
+			}
+		}
+		for i, ref := range lc.Filters {
+			if _, ok := c.Filters[ref]; !ok {
+				errs.add(fmt.Sprintf("%s.filters[%d]", path, i), "unknown filter %q", ref)
+			}
+		}
+		for i, ref := range lc.Pipeline {
+			if _, _, err := c.component(ref); err != nil {
+				errs.add(fmt.Sprintf("%s.pipeline[%d]", path, i), "%v", err)
+			}
+		}
+	}
+	return errs
+}
+
+// ValidateOptions tunes ValidateConfig.
+type ValidateOptions struct {
+	// Strict reports the keys of the file read by LoadConfig which are not
+	// part of the schema, such as misspelled ones, which are ignored
+	// otherwise.
+	Strict bool
+	// Probe connects to the endpoints of the remote writers and checks that
+	// the directories of the files exist.
+	Probe        bool
+	ProbeTimeout time.Duration // per endpoint, 3s by default
+}
+
+// ValidateConfig checks cfg without building any logger: the levels,
+// formats, durations, the settings each writer type requires and the
+// references between entries, and, depending on opts, the unknown keys and
+// the reachability of the endpoints. It returns a ConfigErrors listing every
+// problem found, or nil.
+func ValidateConfig(cfg *Config, opts ValidateOptions) error {
+	errs := cfg.check()
+	if opts.Strict {
+		for _, path := range cfg.unknown {
+			errs.add(path, "unknown key")
+		}
+	}
+	if opts.Probe {
+		errs = append(errs, cfg.probe(opts.ProbeTimeout)...)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// probe checks the writers' endpoints and directories concurrently.
+func (c *Config) probe(timeout time.Duration) ConfigErrors {
+	if timeout == 0 {
+		timeout = time.Second * 3
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var errs ConfigErrors
+	for _, name := range sortedKeys(c.Writers) {
+		w, path := c.Writers[name], "writers."+name
+		check := func(field string, probe func() error) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := probe(); err != nil {
+					mu.Lock()
+					errs.add(path+"."+field, "%v", err)
+					mu.Unlock()
+				}
+			}()
+		}
+		switch w.Type {
+		case "file":
+			check("path", func() error {
+				fi, err := os.Stat(filepath.Dir(w.Path))
+				if err == nil && !fi.IsDir() {
+					err = fmt.Errorf("%s is not a directory", filepath.Dir(w.Path))
+				}
+				return err
+			})
+		case "elastic", "logbus", "splunk":
+			check("url", func() error { return probeURL(w.URL, timeout) })
+		case "datadog":
+			site := defaultString(w.Site, "datadoghq.com")
+			check("site", func() error { return probeURL("https://http-intake.logs."+site, timeout) })
+		}
+	}
+	wg.Wait()
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Path < errs[j].Path })
+	return errs
+}
+
+// probeURL opens a TCP connection to the host of rawURL.
+func probeURL(rawURL string, timeout time.Duration) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		port := "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+		addr = net.JoinHostPort(u.Hostname(), port)
+	}
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return fmt.Errorf("unreachable: %v", err)
+	}
+	return conn.Close()
+}
+
+// Build creates the loggers of c by name. A writer referenced by several
+// loggers is created once and shared. The writers are registered for
+// Shutdown. A ConfigErrors lists every problem of c.
+func (c *Config) Build() (map[string]*Spoor, error) {
+	if errs := c.check(); len(errs) > 0 {
+		return nil, errs
+	}
+	writers := make(map[string]io.Writer)
+	loggers := make(map[string]*Spoor, len(c.Loggers))
+	for _, name := range sortedKeys(c.Loggers) {
+		lc := c.Loggers[name]
+		level := configLevel(c.Level, configLevel(lc.Level, INFO))
+		var dests []Destination
+		for _, ref := range lc.Writers {
+			w, ok := writers[ref]
+			if !ok {
+				w = c.Writers[ref].build()
+				writers[ref] = w
+				if closer, ok := w.(io.Closer); ok {
+					Register(closer)
+				}
+			}
+			dest := Destination{Writer: w, Level: configLevel(c.Writers[ref].Level, level)}
+			if format := c.Writers[ref].Format; format != "" {
+				dest.Formatter = configFormats[format]()
+			}
+			dests = append(dests, dest)
+		}
+		opts := []Option{WithFormatter(configFormats[defaultString(lc.Format, "text")]()), WithDestinations(dests...)}
+		var enrichers []Enricher
+		for _, ref := range lc.Enrichers {
+			enrichers = append(enrichers, c.Enrichers[ref].build())
+		}
+		if len(enrichers) > 0 {
+			opts = append(opts, WithEnricher(enrichers...))
+		}
+		if len(lc.Filters) > 0 {
+			t := &FieldTransform{Rename: make(map[string]string)}
+			for _, ref := range lc.Filters {
+				c.Filters[ref].mergeInto(t)
+			}
+			opts = append(opts, WithFieldTransform(t))
+		}
+		if len(lc.Pipeline) > 0 {
+			stages := make([]Stage, len(lc.Pipeline))
+			for i, ref := range lc.Pipeline {
+				stages[i] = c.stage(ref)
+			}
+			opts = append(opts, WithPipeline(stages...))
+		}
+		flag := 0
+		if lc.Caller {
+			flag = log.Lshortfile
+		}
+		loggers[name] = NewSpoor(level, "", flag, opts...)
+	}
+	return loggers, nil
+}
+
+var configLoggers struct {
+	sync.RWMutex
+	m        map[string]*Spoor
+	override *Spoor // see WithTestGlobal
+}
+
+// NewFromConfig builds the loggers of cfg and returns the default one, see
+// Config.Default, the others are returned by GetLogger.
+func NewFromConfig(cfg *Config) (*Spoor, error) {
+	name := cfg.Default
+	if name == "" {
+		name = "default"
+		if len(cfg.Loggers) == 1 {
+			name = sortedKeys(cfg.Loggers)[0]
+		}
+		if _, ok := cfg.Loggers[name]; !ok {
+			return nil, ConfigErrors{{Path: "default", Err: fmt.Errorf("missing, and no logger is named \"default\"")}}
+		}
+	}
+	loggers, err := cfg.Build()
+	if err != nil {
+		return nil, err
+	}
+	configLoggers.Lock()
+	configLoggers.m = loggers
+	configLoggers.Unlock()
+	return loggers[name], nil
+}
+
+// GetLogger returns the logger named name by the Config last passed to
+// NewFromConfig, or nil.
+func GetLogger(name string) *Spoor {
+	configLoggers.RLock()
+	defer configLoggers.RUnlock()
+	if configLoggers.override != nil {
+		return configLoggers.override
+	}
+	return configLoggers.m[name]
+}
+
+// TB is the part of testing.TB used by WithTestGlobal.
+type TB interface {
+	Helper()
+	Cleanup(func())
+}
+
+// WithTestGlobal makes GetLogger return l, whatever the name, until the end
+// of the test t, e.g. a logger of spoortest.New to make assertions on the
+// entries of code using the global loggers. Tests using it must not run in
+// parallel with each other.
+func WithTestGlobal(t TB, l *Spoor) {
+	t.Helper()
+	configLoggers.Lock()
+	prev := configLoggers.override
+	configLoggers.override = l
+	configLoggers.Unlock()
+	t.Cleanup(func() {
+		configLoggers.Lock()
+		configLoggers.override = prev
+		configLoggers.Unlock()
+	})
+}
+
+// component returns the kind and name of the pipeline component ref, which
+// may be prefixed with its kind.
+func (c *Config) component(ref string) (kind, name string, err error) {
+	prefix, name := "", ref
+	if i := strings.IndexByte(ref, ':'); i >= 0 {
+		prefix, name = ref[:i], ref[i+1:]
+	}
+	_, filter := c.Filters[name]
+	_, redactor := c.Redactors[name]
+	_, sampler := c.Samplers[name]
+	_, enricher := c.Enrichers[name]
+	var kinds []string
+	for kind, defined := range map[string]bool{"filter": filter, "redactor": redactor, "sampler": sampler, "enricher": enricher} {
+		if defined && (prefix == "" || prefix == kind) {
+			kinds = append(kinds, kind)
+		}
+	}
+	sort.Strings(kinds)
+	switch len(kinds) {
+	case 0:
+		return "", "", fmt.Errorf("unknown component %q", ref)
+	case 1:
+		return kinds[0], name, nil
+	}
+	return "", "", fmt.Errorf("ambiguous component %q, prefix it with %s:", ref, strings.Join(kinds, ": or "))
+}
+
+// stage builds the pipeline stage of the component ref.
+func (c *Config) stage(ref string) Stage {
+	kind, name, _ := c.component(ref)
+	switch kind {
+	case "filter":
+		t := &FieldTransform{Rename: make(map[string]string)}
+		c.Filters[name].mergeInto(t)
+		return TransformStage(t)
+	case "redactor":
+		return c.Redactors[name].stage()
+	case "sampler":
+		return c.Samplers[name].stage()
+	}
+	return EnricherStage(c.Enrichers[name].build())
+}
+
+func (rc RedactorConfig) stage() Stage {
+	redact := RedactStage(rc.Keys...)
+	if !rc.Secrets {
+		return redact
+	}
+	secrets := SecretsEnricher(true)
+	return func(e *Entry) bool {
+		secrets(e)
+		return redact(e)
+	}
+}
+
+func (sc SamplerConfig) stage() Stage {
+	if sc.Rate > 0 {
+		return SampleStage(sc.Rate)
+	}
+	tick, _ := time.ParseDuration(defaultString(sc.Tick, "1s"))
+	return RepeatSampleStage(tick, sc.First, sc.Thereafter)
+}
+
+func configLevel(s string, def Level) Level {
+	if s == "" {
+		return def
+	}
+	level, _ := ParseLogLevel(s)
+	return level
+}
+
+func (w WriterConfig) build() io.Writer {
+	flush, _ := time.ParseDuration(w.FlushInterval)
+	switch w.Type {
+	case "stdout":
+		return os.Stdout
+	case "stderr":
+		return os.Stderr
+	case "file":
+		return NewFilePathWriter(w.Path, 0, int((flush+time.Second-1)/time.Second), w.MaxSize)
+	case "elastic":
+		return NewElasticWriter(ElasticConfig{URL: w.URL, Index: w.Index, FlushInterval: flush})
+	case "logbus":
+		return NewLogbusWriter(LogbusConfig{Endpoint: w.URL, Token: w.Token, FlushInterval: flush})
+	case "datadog":
+		return NewDatadogWriter(DatadogConfig{APIKey: w.Token, Site: w.Site, FlushInterval: flush})
+	case "splunk":
+		return NewSplunkWriter(SplunkConfig{URL: w.URL, Token: w.Token, Index: w.Index, FlushInterval: flush})
+	}
+	return io.Discard
+}
+
+func (ec EnricherConfig) build() Enricher {
+	switch ec.Type {
+	case "static":
+		fields := make([]Field, 0, len(ec.Fields))
+		for _, key := range sortedKeys(ec.Fields) {
+			fields = append(fields, String(key, ec.Fields[key]))
+		}
+		return StaticEnricher(fields...)
+	case "process":
+		return ProcessEnricher()
+	case "service":
+		return ServiceEnricher(ec.Name, ec.Version)
+	case "kubernetes":
+		return KubernetesEnricher()
+	case "runtime":
+		return RuntimeEnricher(false)
+	case "secrets":
+		return SecretsEnricher(ec.Mask)
+	}
+	return func(e *Entry) {}
+}
+
+// mergeInto adds the steps of fc to t: renames and drops accumulate, a later
+// filter wins for the same key, and the shortest MaxValueLength applies.
+func (fc FilterConfig) mergeInto(t *FieldTransform) {
+	for from, to := range fc.Rename {
+		t.Rename[from] = to
+	}
+	t.SnakeCase = t.SnakeCase || fc.SnakeCase
+	t.Lowercase = t.Lowercase || fc.Lowercase
+	t.Drop = append(t.Drop, fc.Drop...)
+	if fc.MaxValueLength > 0 && (t.MaxValueLength == 0 || fc.MaxValueLength < t.MaxValueLength) {
+		t.MaxValueLength = fc.MaxValueLength
+	}
+}
+
+// configField returns the field of the struct type t decoded from key.
+func configField(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if name := strings.Split(f.Tag.Get("json"), ",")[0]; name != "" && name != "-" && strings.EqualFold(name, key) {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// sortedKeys returns the keys of m, a map with string keys, in order.
+func sortedKeys(m interface{}) []string {
+	values := reflect.ValueOf(m).MapKeys()
+	keys := make([]string, len(values))
+	for i, v := range values {
+		keys[i] = v.String()
+	}
+	sort.Strings(keys)
+	return keys
+}
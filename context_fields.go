@@ -0,0 +1,60 @@
+package spoor
+
+import "context"
+
+// WithContextFields sets the function returning the fields taken from the
+// context given to WithContext, e.g. the ids of the active OpenTelemetry
+// span:
+//
+//	spoor.WithContextFields(func(ctx context.Context) []spoor.Field {
+//		sc := trace.SpanContextFromContext(ctx)
+//		if !sc.IsValid() {
+//			return nil
+//		}
+//		return []spoor.Field{
+//			spoor.String("trace_id", sc.TraceID().String()),
+//			spoor.String("span_id", sc.SpanID().String()),
+//			spoor.String("trace_flags", sc.TraceFlags().String()),
+//		}
+//	})
+func WithContextFields(extract func(ctx context.Context) []Field) Option {
+	return func(spoor *Spoor) {
+		spoor.contextFields = extract
+	}
+}
+
+// SpanRecorder records entries on the span carried by a context, e.g. as
+// OpenTelemetry span events, see WithSpanEvents. The entry must not be
+// retained after RecordEntry returns.
+type SpanRecorder interface {
+	RecordEntry(ctx context.Context, e *Entry)
+}
+
+// WithSpanEvents passes the ERROR and FATAL entries of the loggers returned
+// by WithContext to recorder, with their context.
+func WithSpanEvents(recorder SpanRecorder) Option {
+	return func(spoor *Spoor) {
+		spoor.spanEvents = recorder
+	}
+}
+
+// WithContext returns a child logger carrying the fields of ctx, see
+// WithContextFields, whose errors are recorded on the span of ctx, see
+// WithSpanEvents.
+func (l *Spoor) WithContext(ctx context.Context) *Spoor {
+	c := l
+	if l.contextFields != nil {
+		if fields := l.contextFields(ctx); len(fields) > 0 {
+			c = l.With(fields...)
+		}
+	}
+	if l.spanEvents == nil {
+		return c
+	}
+	if c == l {
+		copied := *l
+		c = &copied
+	}
+	c.ctx = ctx
+	return c
+}
@@ -0,0 +1,160 @@
+package spoor
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	datadogMaxEntries    = 1000
+	datadogMaxBatchBytes = 4 * 1024 * 1024 // the intake takes 5MB uncompressed
+	datadogMaxEntryBytes = 1024 * 1024
+)
+
+type DatadogConfig struct {
+	APIKey string
+	// Site is the Datadog site, e.g. "datadoghq.eu", it defaults to
+	// "datadoghq.com".
+	Site string
+	// Endpoint overrides the intake URL derived from Site, e.g. for a proxy.
+	Endpoint      string
+	Service       string
+	Source        string   // ddsource, defaults to "go"
+	Hostname      string   // defaults to the host name
+	Tags          []string // ddtags, e.g. "env:prod"
+	Gzip          bool
+	BatchSize     int
+	FlushInterval time.Duration
+	// MaxRetries and RetryBackoff are shorthands for Retry.MaxRetries and
+	// Retry.InitialBackoff.
+	MaxRetries   int
+	RetryBackoff time.Duration
+	Retry        RetryPolicy
+	DeadLetter   io.Writer
+	Transport    HTTPTransport // proxy, TLS or extra headers
+	Client       *http.Client
+}
+
+// DatadogWriter posts batches of entries to the Datadog Logs intake API,
+// mapping spoor levels to Datadog statuses. JSON lines are sent as the
+// message, which Datadog parses into attributes.
+type DatadogWriter struct {
+	*httpSender
+	cfg  DatadogConfig
+	tags string
+}
+
+func NewDatadogWriter(cfg DatadogConfig) *DatadogWriter {
+	if cfg.Site == "" {
+		cfg.Site = "datadoghq.com"
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://http-intake.logs." + cfg.Site + "/api/v2/logs"
+	}
+	if cfg.Source == "" {
+		cfg.Source = "go"
+	}
+	if cfg.Hostname == "" {
+		cfg.Hostname = host
+	}
+	if cfg.Service == "" {
+		cfg.Service = program
+	}
+	if cfg.BatchSize == 0 || cfg.BatchSize > datadogMaxEntries {
+		cfg.BatchSize = datadogMaxEntries
+	}
+	if cfg.FlushInterval == 0 {
+		cfg.FlushInterval = time.Second * 3
+	}
+	cfg.Retry = cfg.Retry.withDefaults(cfg.MaxRetries, cfg.RetryBackoff)
+	if cfg.Client == nil {
+		cfg.Client = cfg.Transport.client(time.Second * 10)
+	}
+	dw := &DatadogWriter{cfg: cfg, tags: strings.Join(cfg.Tags, ",")}
+	dw.httpSender = newHTTPSender(&httpSender{
+		name:        "datadog",
+		url:         cfg.Endpoint,
+		contentType: "application/json",
+		gzip:        cfg.Gzip,
+		retry:       cfg.Retry,
+		deadLetter:  cfg.DeadLetter,
+		transport:   cfg.Transport,
+		client:      cfg.Client,
+		encode:      dw.encode,
+		header:      func(h http.Header) { h.Set("DD-API-KEY", cfg.APIKey) },
+	}, cfg.BatchSize, datadogMaxBatchBytes, cfg.FlushInterval)
+	return dw
+}
+
+func datadogStatus(p []byte) string {
+	lvl, ok := lineLevel(p)
+	if !ok {
+		return "info"
+	}
+	switch lvl {
+	case DEBUG:
+		return "debug"
+	case WARN:
+		return "warning"
+	case ERROR:
+		return "error"
+	case FATAL:
+		return "critical"
+	}
+	return "info"
+}
+
+type datadogLog struct {
+	Message  string `json:"message"`
+	Status   string `json:"status"`
+	Service  string `json:"service,omitempty"`
+	Source   string `json:"ddsource,omitempty"`
+	Tags     string `json:"ddtags,omitempty"`
+	Hostname string `json:"hostname,omitempty"`
+}
+
+// encode drops the entries above the size limit of the intake, cutting them
+// would send invalid JSON, to the dead letter writer.
+func (dw *DatadogWriter) encode(lines [][]byte) ([]byte, error) {
+	logs := make([]datadogLog, 0, len(lines))
+	for _, line := range lines {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		if len(line) > datadogMaxEntryBytes {
+			dw.drop([][]byte{line}, fmt.Errorf("datadog: entry of %d bytes above the %d bytes limit", len(line), datadogMaxEntryBytes))
+			continue
+		}
+		logs = append(logs, datadogLog{
+			Message:  string(line),
+			Status:   datadogStatus(line),
+			Service:  dw.cfg.Service,
+			Source:   dw.cfg.Source,
+			Tags:     dw.tags,
+			Hostname: dw.cfg.Hostname,
+		})
+	}
+	if len(logs) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(logs)
+}
+
+func gzipBytes(p []byte) ([]byte, error) {
+	var zipped bytes.Buffer
+	zw := gzip.NewWriter(&zipped)
+	if _, err := zw.Write(p); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return zipped.Bytes(), nil
+}
@@ -0,0 +1,94 @@
+package spoor
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// DeadLetterRecord is one line of a dead-letter file: an entry that could not
+// be delivered together with the reason.
+type DeadLetterRecord struct {
+	Time  time.Time `json:"time"`
+	Error string    `json:"error"`
+	Entry string    `json:"entry"`
+}
+
+// DeadLetterWriter writes to the wrapped writer and, once its retries are
+// exhausted, saves the entry to dead so it can be replayed later.
+type DeadLetterWriter struct {
+	writer  io.Writer
+	dead    io.Writer
+	retries int
+	backoff time.Duration
+	mu      sync.Mutex
+}
+
+func NewDeadLetterWriter(writer, dead io.Writer, retries int, backoff time.Duration) *DeadLetterWriter {
+	return &DeadLetterWriter{
+		writer:  writer,
+		dead:    dead,
+		retries: retries,
+		backoff: backoff,
+	}
+}
+
+func (dw *DeadLetterWriter) Write(p []byte) (n int, err error) {
+	for attempt := 0; ; attempt++ {
+		if _, err = dw.writer.Write(p); err == nil {
+			return len(p), nil
+		}
+		if attempt >= dw.retries {
+			break
+		}
+		time.Sleep(dw.backoff)
+	}
+	if err := dw.writeDead([][]byte{p}, err); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (dw *DeadLetterWriter) writeDead(entries [][]byte, cause error) error {
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
+	return writeDeadLetters(dw.dead, entries, cause)
+}
+
+func writeDeadLetters(dead io.Writer, entries [][]byte, cause error) error {
+	now := time.Now()
+	for _, entry := range entries {
+		line, err := json.Marshal(DeadLetterRecord{Time: now, Error: cause.Error(), Entry: string(entry)})
+		if err != nil {
+			return err
+		}
+		if _, err := dead.Write(append(line, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Replay re-ingests the entries of a dead-letter file into w and returns the
+// number of entries written.
+func Replay(r io.Reader, w io.Writer) (int, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	n := 0
+	for sc.Scan() {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var rec DeadLetterRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			return n, err
+		}
+		if _, err := io.WriteString(w, rec.Entry); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, sc.Err()
+}
@@ -0,0 +1,18 @@
+package spoor
+
+// WithDebugBuffer returns a logger, usually scoped to one request, keeping in
+// memory the last size entries below the logger level, 100 if 0. They are
+// written, oldest first, before the next entry logged at ERROR or above, and
+// are discarded with the logger otherwise:
+//
+//	l := spoor.FromContext(r.Context()).WithDebugBuffer(0)
+//
+// Loggers derived from it with With share its buffer.
+func (l *Spoor) WithDebugBuffer(size int) *Spoor {
+	if size <= 0 {
+		size = 100
+	}
+	c := *l
+	c.debug = newRing(size)
+	return &c
+}
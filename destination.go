@@ -0,0 +1,70 @@
+package spoor
+
+import "io"
+
+// Destination is an output with its own minimum level, see WithDestinations.
+type Destination struct {
+	Writer io.Writer
+	Level  Level
+	// Formatter formats the entries of this output instead of the logger's
+	// formatter, if set.
+	Formatter Formatter
+}
+
+// WithDestinations sends the entries to several outputs, each of them
+// receiving the entries at or above its level, e.g. the console at INFO and a
+// file at DEBUG. The level of the logger still applies first, so it should be
+// the lowest of them.
+func WithDestinations(dests ...Destination) Option {
+	return func(spoor *Spoor) {
+		d := destinations(dests)
+		spoor.SetOutput(d)
+		spoor.out.mu.Lock()
+		spoor.out.dests = d
+		spoor.out.mu.Unlock()
+	}
+}
+
+type destinations []Destination
+
+// Write finds the level of lines written through Logger.Output with
+// lineLevel, lines without one go to every destination.
+func (d destinations) Write(p []byte) (n int, err error) {
+	level, ok := lineLevel(p)
+	if !ok {
+		level = FATAL
+	}
+	return len(p), d.writeLevel(level, p)
+}
+
+// writeEntry writes p, e formatted by the logger, to the destinations,
+// formatting e again with format for those which have their own formatter.
+func (d destinations) writeEntry(e *Entry, p []byte, format func(f Formatter, e *Entry) []byte) error {
+	var first error
+	for _, dest := range d {
+		if e.Level < dest.Level {
+			continue
+		}
+		line := p
+		if dest.Formatter != nil {
+			line = format(dest.Formatter, e)
+		}
+		if err := writeLevel(dest.Writer, e.Level, line); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func (d destinations) writeLevel(level Level, p []byte) error {
+	var first error
+	for _, dest := range d {
+		if level < dest.Level {
+			continue
+		}
+		if err := writeLevel(dest.Writer, level, p); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
@@ -0,0 +1,127 @@
+package spoor
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+const (
+	colorReset  = "\x1b[0m"
+	colorRed    = "\x1b[31m"
+	colorYellow = "\x1b[33m"
+	colorBlue   = "\x1b[34m"
+	colorGray   = "\x1b[90m"
+	colorBold   = "\x1b[1m"
+)
+
+// DevFormatter is a human friendly formatter for local development: aligned
+// time, level and caller columns, one field per line and errors with their
+// causes and stack traces spelled out.
+type DevFormatter struct {
+	Color       bool
+	CallerWidth int // caller column width, defaults to 24
+}
+
+func (f *DevFormatter) Format(buf *bytes.Buffer, e *Entry) {
+	var arr [32]byte
+	f.color(buf, colorGray)
+	buf.Write(e.Time.AppendFormat(arr[:0], "15:04:05.000"))
+	f.color(buf, colorReset)
+	buf.WriteByte(' ')
+
+	f.color(buf, levelColor(e.Level))
+	level := e.Level.String()
+	buf.WriteString(level)
+	f.color(buf, colorReset)
+	buf.WriteString(strings.Repeat(" ", 8-len(level)))
+
+	if e.File != "" {
+		caller := shortCaller(e.File) + ":" + strconv.Itoa(e.Line)
+		width := f.CallerWidth
+		if width == 0 {
+			width = 24
+		}
+		f.color(buf, colorGray)
+		buf.WriteString(caller)
+		f.color(buf, colorReset)
+		if pad := width - len(caller); pad > 0 {
+			buf.WriteString(strings.Repeat(" ", pad))
+		}
+		buf.WriteByte(' ')
+	}
+
+	if e.Level >= ERROR {
+		f.color(buf, colorBold)
+	}
+	buf.WriteString(e.Message)
+	f.color(buf, colorReset)
+	buf.WriteByte('\n')
+
+	for _, field := range e.Fields {
+		buf.WriteString("    ")
+		f.color(buf, colorBlue)
+		buf.WriteString(field.Key)
+		f.color(buf, colorReset)
+		buf.WriteString(": ")
+		if field.Type == ErrorType {
+			f.formatError(buf, field)
+			continue
+		}
+		buf.WriteString(strings.Replace(textValue(field), "\n", "\n      ", -1))
+		buf.WriteByte('\n')
+	}
+}
+
+func (f *DevFormatter) formatError(buf *bytes.Buffer, field Field) {
+	err, _ := field.Interface.(error)
+	f.color(buf, colorRed)
+	buf.WriteString(textValue(field))
+	f.color(buf, colorReset)
+	buf.WriteByte('\n')
+	if err == nil {
+		return
+	}
+	for _, cause := range errorCauses(err) {
+		buf.WriteString("      caused by: ")
+		buf.WriteString(cause.Error())
+		buf.WriteString(" (")
+		buf.WriteString(errorType(cause))
+		buf.WriteString(")\n")
+	}
+	for _, frame := range errorStack(err) {
+		f.color(buf, colorGray)
+		buf.WriteString("        ")
+		buf.WriteString(frame)
+		f.color(buf, colorReset)
+		buf.WriteByte('\n')
+	}
+}
+
+func (f *DevFormatter) color(buf *bytes.Buffer, code string) {
+	if f.Color {
+		buf.WriteString(code)
+	}
+}
+
+func levelColor(level Level) string {
+	switch level {
+	case DEBUG:
+		return colorGray
+	case INFO:
+		return colorBlue
+	case WARN:
+		return colorYellow
+	}
+	return colorRed
+}
+
+// shortCaller keeps the last directory and the file name of path.
+func shortCaller(path string) string {
+	if i := strings.LastIndexByte(path, '/'); i >= 0 {
+		if j := strings.LastIndexByte(path[:i], '/'); j >= 0 {
+			return path[j+1:]
+		}
+	}
+	return path
+}
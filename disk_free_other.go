@@ -0,0 +1,8 @@
+//go:build !linux && !darwin && !freebsd
+
+package spoor
+
+// diskFree is not implemented on this platform, only MaxDirBytes applies.
+func diskFree(path string) (uint64, bool) {
+	return 0, false
+}
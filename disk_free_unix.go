@@ -0,0 +1,15 @@
+//go:build linux || darwin || freebsd
+
+package spoor
+
+import "syscall"
+
+// diskFree returns the space available to unprivileged users on the file
+// system of path.
+func diskFree(path string) (uint64, bool) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, false
+	}
+	return uint64(st.Bavail) * uint64(st.Bsize), true
+}
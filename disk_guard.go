@@ -0,0 +1,144 @@
+package spoor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DiskGuard limits the disk usage of a FileWriter, see WithDiskGuard.
+type DiskGuard struct {
+	// MinFreeBytes is the free space of the file system under which rotated
+	// files are deleted.
+	MinFreeBytes uint64
+	// MaxDirBytes is the total size of the writer's files above which rotated
+	// files are deleted.
+	MaxDirBytes uint64
+	Interval    time.Duration // between checks, a minute by default
+}
+
+// WithDiskGuard checks the disk usage periodically. When a limit is exceeded
+// the oldest rotated files are deleted and, if that is not enough, only ERROR
+// and FATAL lines are written until usage is back under the limits. Each
+// action is reported to the internal error handler, see
+// SetInternalErrorHandler.
+func WithDiskGuard(guard DiskGuard) FileOption {
+	return func(fw *FileWriter) {
+		if guard.Interval == 0 {
+			guard.Interval = time.Minute
+		}
+		fw.guard = &guard
+	}
+}
+
+func (fw *FileWriter) guardLoop() {
+	for range time.NewTicker(fw.guard.Interval).C {
+		fw.checkDisk()
+	}
+}
+
+func (fw *FileWriter) checkDisk() {
+	fw.mu.Lock()
+	current := fw.path
+	fw.mu.Unlock()
+	files := fw.ownFiles()
+	var total uint64
+	for _, f := range files {
+		total += uint64(f.Size())
+	}
+	exceeded := func() bool {
+		if fw.guard.MaxDirBytes > 0 && total > fw.guard.MaxDirBytes {
+			return true
+		}
+		free, ok := diskFree(fw.logDir)
+		return ok && free < fw.guard.MinFreeBytes
+	}
+	var deleted []string
+	for _, f := range files {
+		if !exceeded() {
+			break
+		}
+		path := filepath.Join(fw.logDir, f.Name())
+		if path == current {
+			continue
+		}
+		if err := os.Remove(path); err == nil {
+			total -= uint64(f.Size())
+			deleted = append(deleted, f.Name())
+		}
+	}
+	degraded := exceeded()
+
+	if len(deleted) > 0 {
+		fw.guardError(fmt.Sprintf("deleted %s", strings.Join(deleted, ", ")))
+	}
+	fw.mu.Lock()
+	changed := degraded != fw.degraded
+	fw.degraded = degraded
+	fw.mu.Unlock()
+	switch {
+	case changed && degraded:
+		fw.guardError("disk limits still exceeded, writing ERROR and FATAL lines only")
+	case changed:
+		fw.guardError("disk usage back under limits, writing all lines")
+	}
+}
+
+// guardError reports an action of the disk guard, outside of the log file
+// whose format is unknown here.
+func (fw *FileWriter) guardError(msg string) {
+	internalError(fmt.Errorf("spoor: disk guard %s: %s", fw.logDir, msg))
+}
+
+// ownFiles returns the files of the writer in its directory, oldest first.
+func (fw *FileWriter) ownFiles() []os.FileInfo {
+	pattern := fw.filePattern()
+	entries, err := os.ReadDir(fw.logDir)
+	if err != nil {
+		return nil
+	}
+	var files []os.FileInfo
+	for _, e := range entries {
+		if !e.Type().IsRegular() || !pattern.MatchString(e.Name()) {
+			continue
+		}
+		if fi, err := e.Info(); err == nil {
+			files = append(files, fi)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].ModTime().Before(files[j].ModTime()) })
+	return files
+}
+
+// filePattern matches the names of the files created by the writer, and not
+// those of other programs sharing the directory.
+func (fw *FileWriter) filePattern() *regexp.Regexp {
+	switch {
+	case fw.template != "":
+		placeholders := strings.NewReplacer(
+			`\{app\}`, regexp.QuoteMeta(program),
+			`\{host\}`, regexp.QuoteMeta(host),
+			`\{pid\}`, strconv.Itoa(pid),
+			`\{level\}`, regexp.QuoteMeta(fw.level.String()),
+			`\{date\}`, `\d{4}-\d{2}-\d{2}`,
+			`\{time\}`, `\d{6}`,
+			`\{seq\}`, `\d+`,
+		)
+		if strings.Contains(fw.template, "{seq}") {
+			return regexp.MustCompile("^" + placeholders.Replace(regexp.QuoteMeta(fw.template)) + "$")
+		}
+		ext := filepath.Ext(fw.template)
+		base := placeholders.Replace(regexp.QuoteMeta(strings.TrimSuffix(fw.template, ext)))
+		return regexp.MustCompile("^" + base + `(\.\d+)?` + placeholders.Replace(regexp.QuoteMeta(ext)) + "$")
+	case fw.fileName != "":
+		ext := filepath.Ext(fw.fileName)
+		base := regexp.QuoteMeta(strings.TrimSuffix(fw.fileName, ext))
+		return regexp.MustCompile("^" + base + `(\.\d{8}-\d{6}(\.\d+)?)?` + regexp.QuoteMeta(ext) + "$")
+	}
+	return regexp.MustCompile("^" + regexp.QuoteMeta(program) + `\.log\.`)
+}
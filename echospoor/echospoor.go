@@ -0,0 +1,53 @@
+// Package echospoor logs the requests served by Echo with spoor.
+package echospoor
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/phuhao00/spoor"
+)
+
+// Middleware logs one entry per request with its route, see
+// spoor.Spoor.LogRequest, and recovers the panics of the handlers, which are
+// logged with their stack and answered with a 500. The request scoped logger
+// is stored in the request context, see spoor.FromContext:
+//
+//	e := echo.New()
+//	e.Use(echospoor.Middleware(l))
+func Middleware(l *spoor.Spoor) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) (err error) {
+			start := time.Now()
+			req := c.Request()
+			id, logger := l.RequestLogger(req.Header.Get)
+			c.Response().Header().Set(spoor.RequestIDHeader, id)
+			c.SetRequest(req.WithContext(spoor.NewContext(req.Context(), id, logger)))
+			defer func() {
+				if p := recover(); p != nil {
+					if p == http.ErrAbortHandler {
+						panic(p)
+					}
+					logger.LogPanic(p)
+					err = echo.ErrInternalServerError
+				}
+				// write the error response now to log its status
+				if err != nil {
+					c.Error(err)
+				}
+				logger.LogRequest(spoor.RequestLog{
+					Method:  req.Method,
+					Path:    req.URL.Path,
+					Route:   c.Path(),
+					Status:  c.Response().Status,
+					Bytes:   c.Response().Size,
+					Latency: time.Since(start),
+					Remote:  c.RealIP(),
+					Err:     err,
+				})
+			}()
+			return next(c)
+		}
+	}
+}
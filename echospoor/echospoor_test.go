@@ -0,0 +1,49 @@
+package echospoor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/phuhao00/spoor"
+)
+
+func TestMiddleware(t *testing.T) {
+	mw := spoor.NewMemoryWriter(10)
+	l := spoor.NewSpoor(spoor.DEBUG, "", 0, spoor.WithConsoleWriter(mw))
+	e := echo.New()
+	e.Use(Middleware(l))
+	e.GET("/users/:id", func(c echo.Context) error {
+		spoor.FromContext(c.Request().Context()).Info("handling")
+		return echo.NewHTTPError(http.StatusNotFound, "no such user")
+	})
+	e.GET("/panic", func(c echo.Context) error { panic("boom") })
+
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	req.Header.Set(spoor.RequestIDHeader, "abc")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound || rec.Header().Get(spoor.RequestIDHeader) != "abc" {
+		t.Fatalf("status %d, headers %v", rec.Code, rec.Header())
+	}
+	lines := mw.Lines()
+	if len(lines) != 2 || !strings.Contains(string(lines[0]), "INFO handling request_id=abc") {
+		t.Fatalf("got %q", lines)
+	}
+	for _, want := range []string{"WARNING request", "path=/users/42 route=/users/:id status=404", "no such user", "request_id=abc"} {
+		if !strings.Contains(string(lines[1]), want) {
+			t.Errorf("%q not in %q", want, lines[1])
+		}
+	}
+
+	mw.Reset()
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	lines = mw.Lines()
+	if rec.Code != http.StatusInternalServerError || len(lines) != 2 ||
+		!strings.Contains(string(lines[0]), "ERROR panic serving request") || !strings.Contains(string(lines[1]), "ERROR request") {
+		t.Fatalf("status %d, got %q", rec.Code, lines)
+	}
+}
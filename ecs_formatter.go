@@ -0,0 +1,88 @@
+package spoor
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// ecsVersion is the version of the Elastic Common Schema written.
+const ecsVersion = "8.11.0"
+
+// ECSFormatter writes one JSON object per line using the Elastic Common
+// Schema field names, so that the Kibana log views, dashboards and detection
+// rules work on the entries as they are:
+// {"@timestamp":"...","log.level":"info","message":"...","ecs.version":"...",...}
+// The first error field is written as error.message, error.type and
+// error.stack_trace, the TraceKey and SpanKey fields as trace.id and span.id
+// and the other fields at the top level under their key, or KeyMap's.
+type ECSFormatter struct {
+	ServiceName        string // service.name, defaults to the program name
+	ServiceVersion     string // service.version, if set
+	ServiceEnvironment string // service.environment, if set
+	TraceKey           string // defaults to "trace_id"
+	// KeyMap maps field keys to ECS field names, e.g. "user" to "user.name".
+	KeyMap map[string]string
+}
+
+func (f *ECSFormatter) Format(buf *bytes.Buffer, e *Entry) {
+	var arr [64]byte
+	buf.WriteString(`{"@timestamp":"`)
+	buf.Write(e.Time.UTC().AppendFormat(arr[:0], "2006-01-02T15:04:05.000000000Z07:00"))
+	buf.WriteString(`","log.level":"`)
+	buf.WriteString(strings.ToLower(e.Level.String()))
+	buf.WriteString(`","message":`)
+	appendJSONString(buf, e.Message)
+	buf.WriteString(`,"ecs.version":"` + ecsVersion + `","service.name":`)
+	appendJSONString(buf, defaultString(f.ServiceName, program))
+	if f.ServiceVersion != "" {
+		buf.WriteString(`,"service.version":`)
+		appendJSONString(buf, f.ServiceVersion)
+	}
+	if f.ServiceEnvironment != "" {
+		buf.WriteString(`,"service.environment":`)
+		appendJSONString(buf, f.ServiceEnvironment)
+	}
+	if e.File != "" {
+		buf.WriteString(`,"log.origin.file.name":`)
+		appendJSONString(buf, e.File)
+		buf.WriteString(`,"log.origin.file.line":`)
+		buf.WriteString(strconv.Itoa(e.Line))
+	}
+	traceKey := defaultString(f.TraceKey, "trace_id")
+	errorDone := false
+	for _, field := range e.Fields {
+		key := field.Key
+		switch {
+		case field.Type == ErrorType && !errorDone:
+			if err, ok := field.Interface.(error); ok && err != nil {
+				errorDone = true
+				appendECSError(buf, err)
+				continue
+			}
+		case key == traceKey && field.Type == StringType:
+			key = "trace.id"
+		case key == SpanKey && field.Type == StringType:
+			key = "span.id"
+		}
+		if mapped, ok := f.KeyMap[key]; ok {
+			key = mapped
+		}
+		buf.WriteByte(',')
+		appendJSONString(buf, key)
+		buf.WriteByte(':')
+		appendJSONValue(buf, field, &ValueFormat{})
+	}
+	buf.WriteString("}\n")
+}
+
+func appendECSError(buf *bytes.Buffer, err error) {
+	buf.WriteString(`,"error.message":`)
+	appendJSONString(buf, err.Error())
+	buf.WriteString(`,"error.type":`)
+	appendJSONString(buf, errorType(err))
+	if stack := errorStack(err); len(stack) > 0 {
+		buf.WriteString(`,"error.stack_trace":`)
+		appendJSONString(buf, strings.Join(stack, "\n"))
+	}
+}
@@ -0,0 +1,242 @@
+package spoor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type ElasticConfig struct {
+	URL           string // e.g. http://127.0.0.1:9200
+	Index         string
+	Username      string
+	Password      string
+	BatchSize     int
+	BatchBytes    int // also flush once this many bytes are pending, 5MB by default
+	FlushInterval time.Duration
+	// MaxRetries and RetryBackoff are shorthands for Retry.MaxRetries and
+	// Retry.InitialBackoff.
+	MaxRetries   int
+	RetryBackoff time.Duration
+	Retry        RetryPolicy
+	// DeadLetter receives a DeadLetterRecord for every document Elasticsearch
+	// rejected permanently or that was still failing after the retries.
+	DeadLetter io.Writer
+	// Transport sets the proxy, TLS, extra headers or SigV4 signing, e.g.
+	// SigV4Config{Service: "es"} for Amazon OpenSearch Service.
+	Transport HTTPTransport
+	Client    *http.Client
+}
+
+type ElasticWriter struct {
+	*batcher
+	cfg ElasticConfig
+}
+
+func NewElasticWriter(cfg ElasticConfig) *ElasticWriter {
+	cfg.URL = strings.TrimRight(cfg.URL, "/")
+	if cfg.Index == "" {
+		cfg.Index = program
+	}
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = 500
+	}
+	if cfg.BatchBytes == 0 {
+		cfg.BatchBytes = 5 * 1024 * 1024
+	}
+	if cfg.FlushInterval == 0 {
+		cfg.FlushInterval = time.Second * 3
+	}
+	cfg.Retry = cfg.Retry.withDefaults(cfg.MaxRetries, cfg.RetryBackoff)
+	if cfg.Client == nil {
+		cfg.Client = cfg.Transport.client(time.Second * 10)
+	}
+	ew := &ElasticWriter{cfg: cfg}
+	ew.batcher = newBatcher(cfg.BatchSize, cfg.BatchBytes, cfg.FlushInterval, ew.send)
+	return ew
+}
+
+// Flush sends the pending documents and waits until they are acknowledged.
+func (ew *ElasticWriter) Flush() error {
+	ew.flush()
+	return nil
+}
+
+// Close flushes the pending documents and stops the background sender.
+func (ew *ElasticWriter) Close() error {
+	ew.close()
+	return nil
+}
+
+// HealthCheck asks the cluster health, a red cluster is unhealthy.
+func (ew *ElasticWriter) HealthCheck(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ew.cfg.URL+"/_cluster/health", nil)
+	if err != nil {
+		return err
+	}
+	if ew.cfg.Username != "" {
+		req.SetBasicAuth(ew.cfg.Username, ew.cfg.Password)
+	}
+	ew.cfg.Transport.prepare(req, nil)
+	resp, err := ew.cfg.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	var health struct {
+		Status string `json:"status"`
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("elastic health: %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		return fmt.Errorf("elastic health: cannot parse response: %v", err)
+	}
+	if health.Status == "red" {
+		return errors.New("elastic health: cluster is red")
+	}
+	return nil
+}
+
+// send indexes lines with the bulk API. Items failing with a retryable
+// status are resent following the Retry policy, the others go to
+// DeadLetter.
+func (ew *ElasticWriter) send(lines [][]byte) {
+	now := time.Now()
+	pending := make([][]byte, 0, len(lines))
+	for _, line := range lines {
+		if doc := lineDocument(line, now); doc != nil {
+			pending = append(pending, doc)
+		}
+	}
+	for attempt := 0; len(pending) > 0; attempt++ {
+		retry, rejected, err := ew.bulk(pending)
+		if len(rejected) > 0 {
+			ew.deadLetter(rejected, fmt.Errorf("%d documents rejected", len(rejected)))
+		}
+		if err == nil {
+			if pending = retry; len(pending) == 0 {
+				return
+			}
+			err = unavailable(fmt.Errorf("%d documents still failing after %d retries", len(pending), attempt))
+		}
+		d, ok := ew.cfg.Retry.next(attempt, now, err)
+		if !ok {
+			ew.deadLetter(pending, err)
+			return
+		}
+		time.Sleep(d)
+	}
+}
+
+type bulkResponse struct {
+	Errors bool                  `json:"errors"`
+	Items  []map[string]bulkItem `json:"items"`
+}
+
+type bulkItem struct {
+	Status int `json:"status"`
+}
+
+// bulk posts docs in one request. A returned error means the request as a
+// whole failed; otherwise retry and rejected hold the failed items.
+func (ew *ElasticWriter) bulk(docs [][]byte) (retry, rejected [][]byte, err error) {
+	var body bytes.Buffer
+	action := fmt.Sprintf(`{"index":{"_index":%q}}`+"\n", ew.cfg.Index)
+	for _, doc := range docs {
+		body.WriteString(action)
+		body.Write(doc)
+		body.WriteByte('\n')
+	}
+	req, err := http.NewRequest(http.MethodPost, ew.cfg.URL+"/_bulk", bytes.NewReader(body.Bytes()))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if ew.cfg.Username != "" {
+		req.SetBasicAuth(ew.cfg.Username, ew.cfg.Password)
+	}
+	ew.cfg.Transport.prepare(req, body.Bytes())
+	resp, err := ew.cfg.Client.Do(req)
+	if err != nil {
+		return nil, nil, unavailable(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		err = fmt.Errorf("elastic bulk: %s: %s", resp.Status, bytes.TrimSpace(data))
+		if retryableStatus(resp.StatusCode) {
+			return nil, nil, unavailable(err)
+		}
+		return nil, nil, err
+	}
+	var br bulkResponse
+	if err := json.Unmarshal(data, &br); err != nil {
+		return nil, nil, fmt.Errorf("elastic bulk: cannot parse response: %v", err)
+	}
+	if !br.Errors {
+		return nil, nil, nil
+	}
+	for i, item := range br.Items {
+		if i >= len(docs) {
+			break
+		}
+		for _, result := range item {
+			switch {
+			case result.Status >= 200 && result.Status <= 299:
+			case retryableStatus(result.Status):
+				retry = append(retry, docs[i])
+			default:
+				rejected = append(rejected, docs[i])
+			}
+		}
+	}
+	return retry, rejected, nil
+}
+
+func (ew *ElasticWriter) deadLetter(docs [][]byte, err error) {
+	if ew.cfg.DeadLetter == nil {
+		internalError(fmt.Errorf("spoor: elastic dropped %d documents: %w", len(docs), err))
+		return
+	}
+	entries := make([][]byte, len(docs))
+	for i, doc := range docs {
+		entries[i] = append(doc, '\n')
+	}
+	if err := writeDeadLetters(ew.cfg.DeadLetter, entries, err); err != nil {
+		internalError(fmt.Errorf("spoor: elastic dead letter: %w", err))
+	}
+}
+
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500
+}
+
+// lineDocument turns a written line into a JSON document. Lines which are
+// JSON objects already are kept as is, others are wrapped as the message.
+func lineDocument(line []byte, t time.Time) []byte {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return nil
+	}
+	if line[0] == '{' && json.Valid(line) {
+		return line
+	}
+	doc, _ := json.Marshal(map[string]interface{}{
+		"@timestamp": t.Format(time.RFC3339Nano),
+		"host":       host,
+		"program":    program,
+		"pid":        pid,
+		"message":    string(line),
+	})
+	return doc
+}
@@ -0,0 +1,57 @@
+package spoor
+
+import (
+	"bufio"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestElasticWriterPartialFailure(t *testing.T) {
+	var mu sync.Mutex
+	var requests [][]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var docs []string
+		sc := bufio.NewScanner(r.Body)
+		for sc.Scan() {
+			if !strings.HasPrefix(sc.Text(), `{"index"`) {
+				docs = append(docs, sc.Text())
+			}
+		}
+		mu.Lock()
+		requests = append(requests, docs)
+		first := len(requests) == 1
+		mu.Unlock()
+		if first {
+			w.Write([]byte(`{"errors":true,"items":[{"index":{"status":201}},{"index":{"status":429}},{"index":{"status":400}}]}`))
+			return
+		}
+		w.Write([]byte(`{"errors":false,"items":[{"index":{"status":201}}]}`))
+	}))
+	defer srv.Close()
+
+	var dead bytes.Buffer
+	ew := NewElasticWriter(ElasticConfig{URL: srv.URL, RetryBackoff: time.Millisecond, DeadLetter: &dead})
+	ew.Write([]byte("INFO ok\n"))
+	ew.Write([]byte("INFO busy\n"))
+	ew.Write([]byte(`{"message":"bad"}` + "\n"))
+	ew.Close()
+
+	if len(requests) != 2 {
+		t.Fatalf("got %d bulk requests, want 2", len(requests))
+	}
+	if len(requests[1]) != 1 || !strings.Contains(requests[1][0], "INFO busy") {
+		t.Fatalf("retried %v, want only the 429 item", requests[1])
+	}
+	var replayed bytes.Buffer
+	if n, err := Replay(&dead, &replayed); err != nil || n != 1 {
+		t.Fatalf("Replay = %d, %v", n, err)
+	}
+	if got := replayed.String(); got != `{"message":"bad"}`+"\n" {
+		t.Fatalf("dead letter = %q", got)
+	}
+}
@@ -0,0 +1,194 @@
+package spoor
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// encryptedMagic starts every block written by an EncryptingWriter, it is
+// followed by the big endian length of the nonce and sealed data.
+var encryptedMagic = []byte("SPENC1")
+
+// KeyFunc returns an AES key of 16, 24 or 32 bytes, e.g. from a KMS.
+type KeyFunc func() ([]byte, error)
+
+// KeyFromEnv returns a KeyFunc reading a base64 encoded key from the
+// environment variable name.
+func KeyFromEnv(name string) KeyFunc {
+	return func() ([]byte, error) {
+		s := os.Getenv(name)
+		if s == "" {
+			return nil, fmt.Errorf("spoor: %s is not set", name)
+		}
+		return base64.StdEncoding.DecodeString(s)
+	}
+}
+
+// EncryptingWriter buffers lines and writes them to another writer, such as
+// a FileWriter, as blocks encrypted with AES-GCM. Use DecryptLog to read them
+// back.
+type EncryptingWriter struct {
+	mu        sync.Mutex
+	w         io.Writer
+	aead      cipher.AEAD
+	buf       []byte
+	blockSize int
+	closeC    chan struct{}
+	once      sync.Once
+}
+
+// NewEncryptingWriter returns a writer encrypting blocks of blockSize bytes,
+// 64KB if 0, and the pending bytes every flushInterval, 3s if 0.
+func NewEncryptingWriter(w io.Writer, key KeyFunc, blockSize int, flushInterval time.Duration) (*EncryptingWriter, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if blockSize == 0 {
+		blockSize = 64 * 1024
+	}
+	if flushInterval == 0 {
+		flushInterval = time.Second * 3
+	}
+	ew := &EncryptingWriter{w: w, aead: aead, blockSize: blockSize, closeC: make(chan struct{})}
+	go ew.flushTicker(flushInterval)
+	return ew, nil
+}
+
+func newGCM(key KeyFunc) (cipher.AEAD, error) {
+	k, err := key()
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (ew *EncryptingWriter) Write(p []byte) (n int, err error) {
+	ew.mu.Lock()
+	defer ew.mu.Unlock()
+	ew.buf = append(ew.buf, p...)
+	if len(ew.buf) >= ew.blockSize {
+		if err := ew.flush(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush encrypts and writes the pending bytes.
+func (ew *EncryptingWriter) Flush() error {
+	ew.mu.Lock()
+	defer ew.mu.Unlock()
+	return ew.flush()
+}
+
+func (ew *EncryptingWriter) flush() error {
+	if len(ew.buf) == 0 {
+		return nil
+	}
+	nonce := make([]byte, ew.aead.NonceSize(), ew.aead.NonceSize()+len(ew.buf)+ew.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	sealed := ew.aead.Seal(nonce, nonce, ew.buf, encryptedMagic)
+	block := make([]byte, len(encryptedMagic)+4, len(encryptedMagic)+4+len(sealed))
+	copy(block, encryptedMagic)
+	binary.BigEndian.PutUint32(block[len(encryptedMagic):], uint32(len(sealed)))
+	block = append(block, sealed...)
+	ew.buf = ew.buf[:0]
+	if _, err := ew.w.Write(block); err != nil {
+		return err
+	}
+	if f, ok := ew.w.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+func (ew *EncryptingWriter) flushTicker(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := ew.Flush(); err != nil {
+				internalError(fmt.Errorf("spoor: encrypting writer: %v", err))
+			}
+		case <-ew.closeC:
+			return
+		}
+	}
+}
+
+// Close flushes the pending bytes and closes the wrapped writer if it is an
+// io.Closer.
+func (ew *EncryptingWriter) Close() error {
+	ew.once.Do(func() { close(ew.closeC) })
+	if err := ew.Flush(); err != nil {
+		return err
+	}
+	if c, ok := ew.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// DecryptLog writes to w the plain text of the blocks written to r by an
+// EncryptingWriter. Lines written outside of the blocks, such as the header
+// of a FileWriter file, are skipped.
+func DecryptLog(r io.Reader, w io.Writer, key KeyFunc) error {
+	aead, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	br := bufio.NewReader(r)
+	for {
+		head, err := br.Peek(len(encryptedMagic))
+		if err == io.EOF && len(head) == 0 {
+			return nil
+		}
+		if !bytes.Equal(head, encryptedMagic) {
+			if _, err := br.ReadBytes('\n'); err != nil {
+				if err == io.EOF {
+					return nil
+				}
+				return err
+			}
+			continue
+		}
+		br.Discard(len(encryptedMagic))
+		var size uint32
+		if err := binary.Read(br, binary.BigEndian, &size); err != nil {
+			return fmt.Errorf("spoor: truncated encrypted block: %v", err)
+		}
+		sealed := make([]byte, size)
+		if _, err := io.ReadFull(br, sealed); err != nil {
+			return fmt.Errorf("spoor: truncated encrypted block: %v", err)
+		}
+		if len(sealed) < aead.NonceSize() {
+			return errors.New("spoor: encrypted block too short")
+		}
+		plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], encryptedMagic)
+		if err != nil {
+			return fmt.Errorf("spoor: cannot decrypt block: %v", err)
+		}
+		if _, err := w.Write(plain); err != nil {
+			return err
+		}
+	}
+}
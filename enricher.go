@@ -0,0 +1,144 @@
+package spoor
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Enricher adds fields to an entry before it is written, see Entry.AddFields.
+// Fields which never change are cheaper to add once with With.
+type Enricher func(e *Entry)
+
+// WithEnricher adds enrichers run for every entry of the logger, after the
+// global ones.
+func WithEnricher(enrichers ...Enricher) Option {
+	return func(spoor *Spoor) {
+		spoor.enrichers = append(spoor.enrichers[:len(spoor.enrichers):len(spoor.enrichers)], enrichers...)
+	}
+}
+
+var globalEnrichers struct {
+	mu   sync.Mutex
+	list atomic.Value // []Enricher
+}
+
+// AddGlobalEnricher adds an enricher run for every entry of every logger.
+func AddGlobalEnricher(enricher Enricher) {
+	globalEnrichers.mu.Lock()
+	list, _ := globalEnrichers.list.Load().([]Enricher)
+	globalEnrichers.list.Store(append(list[:len(list):len(list)], enricher))
+	globalEnrichers.mu.Unlock()
+}
+
+func (l *Spoor) enrich(e *Entry) {
+	list, _ := globalEnrichers.list.Load().([]Enricher)
+	for _, enricher := range list {
+		enricher(e)
+	}
+	for _, enricher := range l.enrichers {
+		enricher(e)
+	}
+}
+
+// AddFields appends fields to the entry without modifying the slice passed by
+// the caller of the logging method.
+func (e *Entry) AddFields(fields ...Field) {
+	if e.owned {
+		e.Fields = append(e.Fields, fields...)
+		return
+	}
+	e.Fields = append(e.Fields[:len(e.Fields):len(e.Fields)], fields...)
+}
+
+// SetField replaces the fields with the key of f by f, or appends f if there
+// is none, without modifying the slice passed by the caller.
+func (e *Entry) SetField(f Field) {
+	found := false
+	fields := make([]Field, 0, len(e.Fields)+1)
+	for i, old := range e.Fields {
+		if old.Key != f.Key {
+			fields = append(fields, old)
+			continue
+		}
+		if !found {
+			fields = append(fields, f)
+			found = true
+		}
+		if i < e.EncodedFields {
+			e.Encoded, e.EncodedFields = nil, 0
+		}
+	}
+	if !found {
+		fields = append(fields, f)
+	}
+	e.Fields = fields
+}
+
+// RemoveField removes the fields with key, without modifying the slice passed
+// by the caller.
+func (e *Entry) RemoveField(key string) {
+	fields := make([]Field, 0, len(e.Fields))
+	for i, f := range e.Fields {
+		if f.Key != key {
+			fields = append(fields, f)
+		} else if i < e.EncodedFields {
+			e.Encoded, e.EncodedFields = nil, 0
+		}
+	}
+	e.Fields = fields
+}
+
+// StaticEnricher adds the same fields to every entry.
+func StaticEnricher(fields ...Field) Enricher {
+	return func(e *Entry) {
+		e.AddFields(fields...)
+	}
+}
+
+// ProcessEnricher adds the host name, program name and pid.
+func ProcessEnricher() Enricher {
+	return StaticEnricher(String("host", host), String("program", program), Int("pid", pid))
+}
+
+// ServiceEnricher adds the service name and version.
+func ServiceEnricher(name, version string) Enricher {
+	return StaticEnricher(String("service", name), String("version", version))
+}
+
+// KubernetesEnricher adds the pod, namespace and node names, read from the
+// POD_NAME, POD_NAMESPACE and NODE_NAME environment variables usually set
+// with the downward API, and the container id, for those which are known.
+func KubernetesEnricher() Enricher {
+	var fields []Field
+	for _, kv := range [][2]string{{"k8s_pod", "POD_NAME"}, {"k8s_namespace", "POD_NAMESPACE"}, {"k8s_node", "NODE_NAME"}} {
+		if v := os.Getenv(kv[1]); v != "" {
+			fields = append(fields, String(kv[0], v))
+		}
+	}
+	if id := containerID(); id != "" {
+		fields = append(fields, String("container_id", id))
+	}
+	return StaticEnricher(fields...)
+}
+
+// containerID returns the id of the container the process runs in, found in
+// /proc/self/cgroup, or "".
+func containerID() string {
+	f, err := os.Open("/proc/self/cgroup")
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		for _, part := range strings.FieldsFunc(sc.Text(), func(r rune) bool { return r == '/' || r == ':' || r == '-' || r == '.' }) {
+			if len(part) == 64 && strings.Trim(part, "0123456789abcdef") == "" {
+				return part
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,77 @@
+package spoor
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+)
+
+// StackTracer is implemented by errors which recorded where they were
+// created, see WithStack.
+type StackTracer interface {
+	StackTrace() []runtime.Frame
+}
+
+type stackError struct {
+	error
+	pcs []uintptr
+}
+
+// WithStack annotates err with the stack of its caller.
+func WithStack(err error) error {
+	if err == nil {
+		return nil
+	}
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	return &stackError{error: err, pcs: pcs[:n]}
+}
+
+func (e *stackError) Unwrap() error { return e.error }
+
+func (e *stackError) StackTrace() []runtime.Frame {
+	frames := runtime.CallersFrames(e.pcs)
+	var out []runtime.Frame
+	for {
+		frame, more := frames.Next()
+		out = append(out, frame)
+		if !more {
+			return out
+		}
+	}
+}
+
+// errorCauses returns the chain of errors wrapped by err, err excluded.
+func errorCauses(err error) []error {
+	var causes []error
+	for cause := errors.Unwrap(err); cause != nil && len(causes) < 32; cause = errors.Unwrap(cause) {
+		if _, ok := cause.(*stackError); ok {
+			continue
+		}
+		causes = append(causes, cause)
+	}
+	return causes
+}
+
+// errorStack returns the stack recorded by the first error of the chain
+// implementing StackTracer.
+func errorStack(err error) []string {
+	var st StackTracer
+	if !errors.As(err, &st) {
+		return nil
+	}
+	frames := st.StackTrace()
+	stack := make([]string, 0, len(frames))
+	for _, f := range frames {
+		stack = append(stack, f.Function+" "+f.File+":"+strconv.Itoa(f.Line))
+	}
+	return stack
+}
+
+func errorType(err error) string {
+	if se, ok := err.(*stackError); ok {
+		err = se.error
+	}
+	return fmt.Sprintf("%T", err)
+}
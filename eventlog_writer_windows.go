@@ -0,0 +1,87 @@
+package spoor
+
+import (
+	"io"
+	"os"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	advapi32                  = syscall.NewLazyDLL("advapi32.dll")
+	procRegisterEventSourceW  = advapi32.NewProc("RegisterEventSourceW")
+	procDeregisterEventSource = advapi32.NewProc("DeregisterEventSource")
+	procReportEventW          = advapi32.NewProc("ReportEventW")
+)
+
+const (
+	eventlogError       = 0x0001
+	eventlogWarning     = 0x0002
+	eventlogInformation = 0x0004
+)
+
+// EventLogWriter reports every line to the Windows Event Log, as an error,
+// warning or information event depending on its level.
+type EventLogWriter struct {
+	handle uintptr
+}
+
+// NewEventLogWriter registers source, the program name if empty, as an event
+// source.
+func NewEventLogWriter(source string) (*EventLogWriter, error) {
+	if source == "" {
+		source = strings.TrimSuffix(program, ".exe")
+	}
+	name, err := syscall.UTF16PtrFromString(source)
+	if err != nil {
+		return nil, err
+	}
+	h, _, err := procRegisterEventSourceW.Call(0, uintptr(unsafe.Pointer(name)))
+	if h == 0 {
+		return nil, err
+	}
+	return &EventLogWriter{handle: h}, nil
+}
+
+func (ew *EventLogWriter) Write(p []byte) (n int, err error) {
+	line := strings.TrimRight(string(p), "\r\n")
+	eventType := eventlogInformation
+	if level, ok := lineLevel(p); ok {
+		switch {
+		case level >= ERROR:
+			eventType = eventlogError
+		case level == WARN:
+			eventType = eventlogWarning
+		}
+	}
+	msg, err := syscall.UTF16PtrFromString(strings.Replace(line, "\x00", "", -1))
+	if err != nil {
+		return 0, err
+	}
+	strs := []*uint16{msg}
+	r, _, err := procReportEventW.Call(ew.handle, uintptr(eventType), 0, 1, 0, 1, 0,
+		uintptr(unsafe.Pointer(&strs[0])), 0)
+	if r == 0 {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (ew *EventLogWriter) Close() error {
+	r, _, err := procDeregisterEventSource.Call(ew.handle)
+	if r == 0 {
+		return err
+	}
+	return nil
+}
+
+// NewPlatformWriter returns an EventLogWriter for the program, or os.Stderr
+// if the event source cannot be registered.
+func NewPlatformWriter() io.Writer {
+	ew, err := NewEventLogWriter("")
+	if err != nil {
+		return os.Stderr
+	}
+	return ew
+}
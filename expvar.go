@@ -0,0 +1,22 @@
+package spoor
+
+import (
+	"expvar"
+	"fmt"
+)
+
+// PublishExpvar publishes the stats served by AdminHandler under /stats as
+// the expvar variable namespace, "spoor" if empty, so that they appear in
+// /debug/vars. loggers default to those built by NewFromConfig. It fails if
+// the variable exists.
+func PublishExpvar(namespace string, loggers map[string]*Spoor) error {
+	if namespace == "" {
+		namespace = "spoor"
+	}
+	if expvar.Get(namespace) != nil {
+		return fmt.Errorf("spoor: expvar %q already published", namespace)
+	}
+	cfg := AdminConfig{Loggers: loggers}
+	expvar.Publish(namespace, expvar.Func(func() interface{} { return cfg.stats() }))
+	return nil
+}
@@ -0,0 +1,87 @@
+package spoor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// exit is replaced in tests.
+var exit = os.Exit
+
+// WithFatalDeadline sets how long Fatal waits for the queued entries, such
+// as those of an AsyncWriter, to be written and for the writers to be
+// flushed and closed before exiting, 5s by default.
+func WithFatalDeadline(d time.Duration) Option {
+	return func(spoor *Spoor) {
+		spoor.fatalDeadline = d
+	}
+}
+
+// Sync writes the entries queued by the writers of the logger and flushes
+// them: an AsyncWriter is drained, the writers with a Flush or Sync method,
+// including those of WithDestinations, are flushed.
+func (l *Spoor) Sync() error {
+	var first error
+	for _, out := range []*output{l.out, l.audit} {
+		if out == nil {
+			continue
+		}
+		out.mu.Lock()
+		w := out.w
+		out.mu.Unlock()
+		if err := flushWriter(w); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func flushWriter(w io.Writer) error {
+	switch w := w.(type) {
+	case *os.File:
+		return nil // not buffered, and Sync fails on terminals
+	case destinations:
+		var first error
+		for _, dest := range w {
+			if err := flushWriter(dest.Writer); err != nil && first == nil {
+				first = err
+			}
+		}
+		return first
+	case interface{ Flush() error }:
+		return w.Flush()
+	case interface{ Sync() error }:
+		return w.Sync()
+	}
+	return nil
+}
+
+// exitFatal syncs the logger and closes the registered writers, see
+// Register, within the fatal deadline, then exits with status 1.
+func (l *Spoor) exitFatal() {
+	d := l.fatalDeadline
+	if d == 0 {
+		d = time.Second * 5
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := l.Sync(); err != nil {
+			internalError(fmt.Errorf("spoor: fatal: sync: %w", err))
+		}
+		if err := Shutdown(ctx); err != nil && ctx.Err() == nil {
+			internalError(err)
+		}
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		internalError(fmt.Errorf("spoor: fatal: writers not flushed within %v", d))
+	}
+	exit(1)
+}
@@ -0,0 +1,52 @@
+// Package fiberspoor logs the requests served by Fiber with spoor.
+package fiberspoor
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/phuhao00/spoor"
+)
+
+// Middleware logs one entry per request with its route, see
+// spoor.Spoor.LogRequest, and recovers the panics of the handlers, which are
+// logged with their stack and answered with a 500. Errors returned by the
+// handlers are passed to the error handler of the app. The request scoped
+// logger is stored in the user context, see spoor.FromContext:
+//
+//	app := fiber.New()
+//	app.Use(fiberspoor.Middleware(l))
+func Middleware(l *spoor.Spoor) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		// fasthttp reuses the header values once the request is served
+		id, logger := l.RequestLogger(func(key string) string { return utils.CopyString(c.Get(key)) })
+		c.Set(spoor.RequestIDHeader, id)
+		c.SetUserContext(spoor.NewContext(c.UserContext(), id, logger))
+		var err error
+		defer func() {
+			if p := recover(); p != nil {
+				logger.LogPanic(p)
+				c.SendStatus(fiber.StatusInternalServerError)
+			}
+			logger.LogRequest(spoor.RequestLog{
+				Method:  c.Method(),
+				Path:    c.Path(),
+				Route:   c.Route().Path,
+				Status:  c.Response().StatusCode(),
+				Bytes:   int64(len(c.Response().Body())),
+				Latency: time.Since(start),
+				Remote:  c.IP(),
+				Err:     err,
+			})
+		}()
+		if err = c.Next(); err != nil {
+			// write the error response now to log its status
+			if herr := c.App().ErrorHandler(c, err); herr != nil {
+				c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+		return nil
+	}
+}
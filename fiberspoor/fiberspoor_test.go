@@ -0,0 +1,53 @@
+package fiberspoor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/phuhao00/spoor"
+)
+
+func TestMiddleware(t *testing.T) {
+	mw := spoor.NewMemoryWriter(10)
+	l := spoor.NewSpoor(spoor.DEBUG, "", 0, spoor.WithConsoleWriter(mw))
+	app := fiber.New()
+	app.Use(Middleware(l))
+	app.Get("/users/:id", func(c *fiber.Ctx) error {
+		spoor.FromContext(c.UserContext()).Info("handling")
+		return fiber.NewError(http.StatusNotFound, "no such user")
+	})
+	app.Get("/panic", func(c *fiber.Ctx) error { panic("boom") })
+
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	req.Header.Set(spoor.RequestIDHeader, "abc")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusNotFound || resp.Header.Get(spoor.RequestIDHeader) != "abc" {
+		t.Fatalf("status %d, headers %v", resp.StatusCode, resp.Header)
+	}
+	lines := mw.Lines()
+	if len(lines) != 2 || !strings.Contains(string(lines[0]), "INFO handling request_id=abc") {
+		t.Fatalf("got %q", lines)
+	}
+	for _, want := range []string{"WARNING request", "path=/users/42 route=/users/:id status=404 bytes=12", "no such user", "request_id=abc"} {
+		if !strings.Contains(string(lines[1]), want) {
+			t.Errorf("%q not in %q", want, lines[1])
+		}
+	}
+
+	mw.Reset()
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines = mw.Lines()
+	if resp.StatusCode != http.StatusInternalServerError || len(lines) != 2 ||
+		!strings.Contains(string(lines[0]), "ERROR panic serving request") || !strings.Contains(string(lines[1]), "ERROR request") {
+		t.Fatalf("status %d, got %q", resp.StatusCode, lines)
+	}
+}
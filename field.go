@@ -0,0 +1,141 @@
+package spoor
+
+import (
+	"math"
+	"time"
+)
+
+type FieldType uint8
+
+const (
+	StringType FieldType = iota + 1
+	IntType
+	FloatType
+	BoolType
+	DurationType
+	TimeType
+	ErrorType
+	AnyType
+)
+
+func (t FieldType) String() string {
+	switch t {
+	case StringType:
+		return "string"
+	case IntType:
+		return "int"
+	case FloatType:
+		return "float"
+	case BoolType:
+		return "bool"
+	case DurationType:
+		return "duration"
+	case TimeType:
+		return "time"
+	case ErrorType:
+		return "error"
+	case AnyType:
+		return "any"
+	}
+	return "invalid"
+}
+
+// Field is a typed key/value pair. Scalar values are stored inline so that
+// building fields does not allocate.
+type Field struct {
+	Key       string
+	Type      FieldType
+	Integer   int64
+	Str       string
+	Interface interface{}
+}
+
+func String(key, value string) Field {
+	return Field{Key: key, Type: StringType, Str: value}
+}
+
+func Int(key string, value int) Field {
+	return Field{Key: key, Type: IntType, Integer: int64(value)}
+}
+
+func Int64(key string, value int64) Field {
+	return Field{Key: key, Type: IntType, Integer: value}
+}
+
+func Float64(key string, value float64) Field {
+	return Field{Key: key, Type: FloatType, Integer: int64(math.Float64bits(value))}
+}
+
+func Bool(key string, value bool) Field {
+	var i int64
+	if value {
+		i = 1
+	}
+	return Field{Key: key, Type: BoolType, Integer: i}
+}
+
+func Dur(key string, value time.Duration) Field {
+	return Field{Key: key, Type: DurationType, Integer: int64(value)}
+}
+
+func Time(key string, value time.Time) Field {
+	return Field{Key: key, Type: TimeType, Integer: value.UnixNano(), Interface: value.Location()}
+}
+
+// Err stores err under the "error" key.
+func Err(err error) Field {
+	return NamedErr("error", err)
+}
+
+func NamedErr(key string, err error) Field {
+	return Field{Key: key, Type: ErrorType, Interface: err}
+}
+
+func Any(key string, value interface{}) Field {
+	switch v := value.(type) {
+	case string:
+		return String(key, v)
+	case int:
+		return Int(key, v)
+	case int64:
+		return Int64(key, v)
+	case float64:
+		return Float64(key, v)
+	case bool:
+		return Bool(key, v)
+	case time.Duration:
+		return Dur(key, v)
+	case time.Time:
+		return Time(key, v)
+	case error:
+		return NamedErr(key, v)
+	}
+	return Field{Key: key, Type: AnyType, Interface: value}
+}
+
+// Value returns the field value as an interface{}.
+func (f Field) Value() interface{} {
+	switch f.Type {
+	case StringType:
+		return f.Str
+	case IntType:
+		return f.Integer
+	case FloatType:
+		return math.Float64frombits(uint64(f.Integer))
+	case BoolType:
+		return f.Integer == 1
+	case DurationType:
+		return time.Duration(f.Integer)
+	case TimeType:
+		return f.time()
+	}
+	return f.Interface
+}
+
+func (f Field) time() time.Time {
+	t := time.Unix(0, f.Integer)
+	if loc, ok := f.Interface.(*time.Location); ok {
+		t = t.In(loc)
+	}
+	return t
+}
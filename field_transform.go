@@ -0,0 +1,77 @@
+package spoor
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// FieldTransform rewrites field keys and values so that entries follow the
+// conventions of a company, see WithFieldTransform. The steps are applied in
+// the order of the struct fields.
+type FieldTransform struct {
+	Rename         map[string]string // exact key renames
+	SnakeCase      bool              // userID becomes user_id
+	Lowercase      bool
+	Drop           []string // keys removed after renaming
+	MaxValueLength int      // string values are cut to this many runes if not 0
+}
+
+// WithFieldTransform applies t to the fields of every entry, including those
+// added by With and by enrichers.
+func WithFieldTransform(t *FieldTransform) Option {
+	return func(spoor *Spoor) {
+		spoor.transform = t
+	}
+}
+
+// Key returns the transformed key and false if it is dropped.
+func (t *FieldTransform) Key(key string) (string, bool) {
+	if renamed, ok := t.Rename[key]; ok {
+		key = renamed
+	}
+	if t.SnakeCase {
+		key = snakeCase(key)
+	}
+	if t.Lowercase {
+		key = strings.ToLower(key)
+	}
+	for _, drop := range t.Drop {
+		if key == drop {
+			return "", false
+		}
+	}
+	return key, true
+}
+
+// fields returns the transformed copy of fields.
+func (t *FieldTransform) fields(fields []Field) []Field {
+	out := make([]Field, 0, len(fields))
+	for _, f := range fields {
+		key, ok := t.Key(f.Key)
+		if !ok {
+			continue
+		}
+		f.Key = key
+		if t.MaxValueLength > 0 && f.Type == StringType && utf8.RuneCountInString(f.Str) > t.MaxValueLength {
+			f.Str = truncateRunes(f.Str, t.MaxValueLength)
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+func snakeCase(s string) string {
+	var b strings.Builder
+	runes := []rune(s)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && runes[i-1] != '_' && (!unicode.IsUpper(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
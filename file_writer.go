@@ -3,12 +3,17 @@ package spoor
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
 )
 
@@ -21,10 +26,93 @@ type FileWriter struct {
 	logDir        string
 	bufferSize    int
 	flushInterval int //second
+	fileName      string
+	template      string
+	seq           int
+	symlink       string
+	path          string // of the current file
+	guard         *DiskGuard
+	degraded      bool // set by the disk guard
+	syncPolicy    SyncPolicy
+	writes        int
+	shared        bool
+	exitOnError   bool
+	index         bool
 	mu            sync.Mutex
 }
 
-func NewFileWriter(logDir string, bufferSize, flushInterval int, maxSize uint64) *FileWriter {
+// WithSharedAppend lets several processes write to the same file, set with
+// WithFileName or WithFileTemplate without {pid}: every line is written
+// unbuffered with a single write to the file opened with O_APPEND, so lines
+// of different processes never interleave. The default names hold the pid,
+// so only the writers of one process created in the same second share them.
+// The writer does not rotate the file, leave that to an external tool and
+// Reopen.
+func WithSharedAppend() FileOption {
+	return func(fw *FileWriter) {
+		fw.shared = true
+	}
+}
+
+// WithExitOnError makes the process exit with status 2 when the file cannot
+// be rotated or written, instead of returning the error to the logger, which
+// passes it to its error handler, see WithErrorHandler.
+func WithExitOnError() FileOption {
+	return func(fw *FileWriter) {
+		fw.exitOnError = true
+	}
+}
+
+// SyncPolicy tells when a FileWriter flushes its buffer and syncs the file to
+// disk besides the periodic flush, trading throughput for durability.
+type SyncPolicy struct {
+	Never       bool  // do not sync the file, not even on the periodic flush
+	EveryWrites int   // flush and sync after this many writes if not 0
+	MinLevel    Level // flush and sync after a line at or above this level if not 0
+}
+
+// WithSyncPolicy sets the sync policy, the file is only synced on the
+// periodic flush by default.
+func WithSyncPolicy(policy SyncPolicy) FileOption {
+	return func(fw *FileWriter) {
+		fw.syncPolicy = policy
+	}
+}
+
+// FileOption configures a FileWriter, see NewFileWriter.
+type FileOption func(fw *FileWriter)
+
+// WithFileName writes to the file name in the log directory instead of a new
+// file named after the program, the time and the pid at every rotation. An
+// existing file is appended to, a rotated file is renamed with its rotation
+// time before the extension, app.log becoming app.20060102-150405.log.
+func WithFileName(name string) FileOption {
+	return func(fw *FileWriter) {
+		fw.fileName = name
+	}
+}
+
+// WithFileTemplate names the files after template, a new one at every
+// rotation. The placeholders are {app}, {host}, {pid}, {level}, {date}
+// (2006-01-02), {time} (150405) and {seq}, the number of the file for this
+// writer, skipping the existing files. Without {seq}, an existing file is
+// appended to until it is full, then a number is added before the
+// extension, app.log being followed by app.1.log.
+func WithFileTemplate(template string) FileOption {
+	return func(fw *FileWriter) {
+		fw.template = template
+	}
+}
+
+// WithSymlink maintains a symbolic link named name, e.g. "current.log", in the
+// log directory to the file being written.
+func WithSymlink(name string) FileOption {
+	return func(fw *FileWriter) {
+		fw.symlink = name
+	}
+}
+
+func NewFileWriter(logDir string, bufferSize, flushInterval int, maxSize uint64, opts ...FileOption) *FileWriter {
 	fw := &FileWriter{
 		maxSize:       maxSize,
 		logDir:        logDir,
@@ -32,6 +120,9 @@ func NewFileWriter(logDir string, bufferSize, flushInterval int, maxSize uint64)
 		flushInterval: flushInterval,
 		mu:            sync.Mutex{},
 	}
+	for _, opt := range opts {
+		opt(fw)
+	}
 	if maxSize == 0 {
 		fw.maxSize = 1024 * 1024 * 1800
 	}
@@ -42,41 +133,211 @@ func NewFileWriter(logDir string, bufferSize, flushInterval int, maxSize uint64)
 		fw.bufferSize = 256 * 1024
 	}
 	fw.loop()
+	if fw.guard != nil {
+		go fw.guardLoop()
+	}
 	return fw
 }
 
+// NewFilePathWriter is NewFileWriter writing to the file path, see
+// WithFileName.
+func NewFilePathWriter(path string, bufferSize, flushInterval int, maxSize uint64, opts ...FileOption) *FileWriter {
+	opts = append([]FileOption{WithFileName(filepath.Base(path))}, opts...)
+	return NewFileWriter(filepath.Dir(path), bufferSize, flushInterval, maxSize, opts...)
+}
+
+// Flush writes the buffered lines to the file.
+func (fw *FileWriter) Flush() error {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	if fw.Writer == nil {
+		return nil
+	}
+	return fw.Writer.Flush()
+}
+
+// Sync flushes the buffered lines and syncs the file to disk.
 func (fw *FileWriter) Sync() error {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	if fw.file == nil || fw.Writer == nil {
+		return nil
+	}
+	if err := fw.Writer.Flush(); err != nil {
+		return err
+	}
 	return fw.file.Sync()
 }
 
+// Write writes p, whose level, needed by the sync policy and the disk guard,
+// is found with the level names of the line. The loggers writing to fw give
+// it with WriteLevel instead.
 func (fw *FileWriter) Write(p []byte) (n int, err error) {
-	if fw.bytesCounter+uint64(len(p)) >= fw.maxSize || fw.Writer == nil {
+	return fw.write(p, 0, false)
+}
+
+// WriteLevel writes p, an entry of level.
+func (fw *FileWriter) WriteLevel(level Level, p []byte) (n int, err error) {
+	return fw.write(p, level, true)
+}
+
+func (fw *FileWriter) write(p []byte, level Level, known bool) (n int, err error) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	if !known && (fw.degraded || fw.syncPolicy.MinLevel > 0) {
+		level, known = lineLevel(p)
+	}
+	if fw.degraded && known && level < ERROR {
+		return len(p), nil
+	}
+	if fw.bytesCounter+uint64(len(p)) >= fw.maxSize && !fw.shared || fw.Writer == nil {
 		if err := fw.rotateFile(time.Now()); err != nil {
-			fw.exit(err)
+			return 0, fw.fail(fmt.Errorf("spoor: rotate log file in %s: %w", fw.logDir, err))
 		}
 	}
-	n, err = fw.Writer.Write(p)
+	if fw.shared {
+		n, err = fw.file.Write(p)
+	} else {
+		n, err = fw.Writer.Write(p)
+	}
 	fw.bytesCounter += uint64(n)
 	if err != nil {
-		fw.exit(err)
+		return n, fw.fail(fmt.Errorf("spoor: write %s: %w", fw.path, err))
+	}
+	fw.writes++
+	if fw.syncPolicy.EveryWrites > 0 && fw.writes%fw.syncPolicy.EveryWrites == 0 {
+		fw.flush()
+	} else if fw.syncPolicy.MinLevel > 0 && known && level >= fw.syncPolicy.MinLevel {
+		fw.flush()
 	}
 	return
 }
 
+// HealthCheck reports whether a file can be created in the log directory.
+func (fw *FileWriter) HealthCheck(ctx context.Context) error {
+	if err := os.MkdirAll(fw.logDir, 0777); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(fw.logDir, ".health")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// Close flushes and closes the current file, the next write starts a new one.
+func (fw *FileWriter) Close() error {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	if fw.file == nil {
+		return nil
+	}
+	fw.Writer.Flush()
+	err := fw.file.Close()
+	fw.file, fw.Writer = nil, nil
+	return err
+}
+
+// Reopen flushes and closes the current file and opens it again by name, for
+// use after an external tool such as logrotate moved it away.
+func (fw *FileWriter) Reopen() error {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	if fw.file == nil {
+		return nil
+	}
+	fw.Writer.Flush()
+	fw.file.Close()
+	f, err := os.OpenFile(fw.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		fw.file, fw.Writer = nil, nil
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		fw.file, fw.Writer = nil, nil
+		return err
+	}
+	fw.file, fw.bytesCounter = f, uint64(fi.Size())
+	fw.Writer.Reset(f)
+	return nil
+}
+
+// ReopenOnSignal calls Reopen whenever the process receives one of signals,
+// SIGHUP if none is given. It returns a function stopping the handling.
+func (fw *FileWriter) ReopenOnSignal(signals ...os.Signal) (stop func()) {
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGHUP}
+	}
+	c := make(chan os.Signal, 1)
+	quit := make(chan struct{})
+	signal.Notify(c, signals...)
+	go func() {
+		for {
+			select {
+			case <-c:
+				if err := fw.Reopen(); err != nil {
+					internalError(fmt.Errorf("spoor: reopen %s: %v", fw.path, err))
+				}
+			case <-quit:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(c)
+			close(quit)
+		})
+	}
+}
+
 // rotateFile closes the FileWriter's file and starts a new one.
 func (fw *FileWriter) rotateFile(now time.Time) error {
-	if fw.file != nil {
-		fw.Flush()
+	rotating := fw.file != nil
+	closed := fw.path
+	if rotating {
+		fw.Writer.Flush()
 		fw.file.Close()
 	}
 	var err error
-	fw.file, _, err = createLogFile(fw.level.String(), fw.logDir, now)
 	fw.bytesCounter = 0
+	var fname string
+	switch {
+	case fw.template != "":
+		fw.file, fname, fw.bytesCounter, err = fw.openTemplateLogFile(now)
+	case fw.fileName != "":
+		fname = filepath.Join(fw.logDir, fw.fileName)
+		fw.file, closed, fw.bytesCounter, err = openNamedLogFile(fw.logDir, fw.fileName, rotating, now)
+	default:
+		flag := os.O_CREATE | os.O_RDWR | os.O_TRUNC
+		if fw.shared {
+			// another writer may have created it in the same second
+			flag = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+		}
+		fw.file, fname, err = createLogFile(fw.level.String(), fw.logDir, now, flag)
+	}
+	if fw.index && rotating && closed != "" && closed != fname {
+		go indexRotated(closed)
+	}
 	if err != nil {
 		return err
 	}
+	fw.path = fname
+	if fw.symlink != "" {
+		symlink := filepath.Join(fw.logDir, fw.symlink)
+		os.Remove(symlink)
+		os.Symlink(filepath.Base(fname), symlink)
+	}
 
 	fw.Writer = bufio.NewWriterSize(fw.file, fw.bufferSize)
+	if fw.shared {
+		// the header of every process would be mixed with the lines
+		return nil
+	}
 	var buf bytes.Buffer
 	fmt.Fprintf(&buf, "Log file created at: %s\n", now.Format("2006/01/02 15:04:05"))
 	fmt.Fprintf(&buf, "Running on machine: %s\n", host)
@@ -87,7 +348,7 @@ func (fw *FileWriter) rotateFile(now time.Time) error {
 	return err
 }
 
-func createLogFile(levelName, logDir string, t time.Time) (f *os.File, filename string, err error) {
+func createLogFile(levelName, logDir string, t time.Time, flag int) (f *os.File, filename string, err error) {
 	if len(logDir) == 0 {
 		return nil, "", errors.New("no log dirs")
 	}
@@ -95,7 +356,7 @@ func createLogFile(levelName, logDir string, t time.Time) (f *os.File, filename
 	name, link := getLogName(levelName, t)
 	var lastErr error
 	fname := filepath.Join(logDir, name)
-	f, err = os.Create(fname)
+	f, err = os.OpenFile(fname, flag, 0666)
 	if err == nil {
 		symlink := filepath.Join(logDir, link)
 		os.Remove(symlink)
@@ -106,6 +367,93 @@ func createLogFile(levelName, logDir string, t time.Time) (f *os.File, filename
 	return nil, "", fmt.Errorf("cannot create log file: %v", lastErr)
 }
 
+// openTemplateLogFile opens the next file named after fw.template for
+// appending and returns it with its path and size.
+func (fw *FileWriter) openTemplateLogFile(t time.Time) (*os.File, string, uint64, error) {
+	if err := os.MkdirAll(fw.logDir, 0777); err != nil {
+		return nil, "", 0, err
+	}
+	var path string
+	for {
+		fw.seq++
+		path = filepath.Join(fw.logDir, strings.NewReplacer(
+			"{app}", program,
+			"{host}", host,
+			"{pid}", strconv.Itoa(pid),
+			"{level}", fw.level.String(),
+			"{date}", t.Format("2006-01-02"),
+			"{time}", t.Format("150405"),
+			"{seq}", strconv.Itoa(fw.seq),
+		).Replace(fw.template))
+		if !strings.Contains(fw.template, "{seq}") || !fileExists(path) {
+			break
+		}
+	}
+	if !strings.Contains(fw.template, "{seq}") {
+		// do not reopen the file being rotated, or another full one
+		base, ext := path, filepath.Ext(path)
+		for i := 1; path == fw.path || fileSize(path) >= fw.maxSize; i++ {
+			path = strings.TrimSuffix(base, ext) + "." + strconv.Itoa(i) + ext
+		}
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return nil, "", 0, fmt.Errorf("cannot create log file: %v", err)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, "", 0, err
+	}
+	return f, path, uint64(fi.Size()), nil
+}
+
+// openNamedLogFile opens name in logDir for appending, after renaming the
+// current file with the time if rotating. It returns the path of the rotated
+// file and the size of the file.
+func openNamedLogFile(logDir, name string, rotating bool, t time.Time) (*os.File, string, uint64, error) {
+	if err := os.MkdirAll(logDir, 0777); err != nil {
+		return nil, "", 0, err
+	}
+	path := filepath.Join(logDir, name)
+	var rotated string
+	if rotating {
+		ext := filepath.Ext(name)
+		base := strings.TrimSuffix(name, ext) + "." + t.Format("20060102-150405")
+		rotated = filepath.Join(logDir, base+ext)
+		for i := 1; fileExists(rotated); i++ {
+			rotated = filepath.Join(logDir, base+"."+strconv.Itoa(i)+ext)
+		}
+		if err := os.Rename(path, rotated); err != nil && !os.IsNotExist(err) {
+			return nil, "", 0, err
+		}
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return nil, "", 0, fmt.Errorf("cannot create log file: %v", err)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, "", 0, err
+	}
+	return f, rotated, uint64(fi.Size()), nil
+}
+
+// fileSize returns the size of path, 0 if it does not exist.
+func fileSize(path string) uint64 {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return uint64(fi.Size())
+}
+
+func fileExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
 func getLogName(levelName string, t time.Time) (name, link string) {
 	name = fmt.Sprintf("%s.log.%04d-%02d-%02d-%02d-%02d-%02d.%4d",
 		program,
@@ -137,8 +485,23 @@ func (fw *FileWriter) flush() {
 	file := fw.file
 	if file != nil {
 		fw.Writer.Flush()
-		fw.Sync()
+		if !fw.syncPolicy.Never {
+			file.Sync()
+		}
+	}
+}
+
+// fail drops the current file after err, the next write starts a new one,
+// and returns err unless fw exits on errors.
+func (fw *FileWriter) fail(err error) error {
+	if fw.exitOnError {
+		fw.exit(err)
 	}
+	if fw.file != nil {
+		fw.file.Close()
+	}
+	fw.file, fw.Writer = nil, nil
+	return err
 }
 
 func (fw *FileWriter) exit(err error) {
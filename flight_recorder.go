@@ -0,0 +1,51 @@
+package spoor
+
+import (
+	"io"
+	"os"
+)
+
+type flightRecorder struct {
+	entries *ring
+	w       io.Writer
+}
+
+// WithFlightRecorder keeps the last size entries of every level in memory,
+// including those below the configured level, so that they can be written to
+// w, os.Stderr if nil, when the process crashes. See DumpOnPanic.
+func WithFlightRecorder(size int, w io.Writer) Option {
+	return func(spoor *Spoor) {
+		if size <= 0 {
+			size = 1000
+		}
+		if w == nil {
+			w = os.Stderr
+		}
+		spoor.recorder = &flightRecorder{entries: newRing(size), w: w}
+	}
+}
+
+// DumpFlightRecorder writes the entries kept by the flight recorder, oldest
+// first, with the logger's formatter.
+func (l *Spoor) DumpFlightRecorder() {
+	if l.recorder == nil {
+		return
+	}
+	buf := getBuffer()
+	for _, e := range l.recorder.entries.list() {
+		l.formatter.Format(buf, &e)
+	}
+	l.recorder.w.Write(buf.Bytes())
+	putBuffer(buf)
+}
+
+// DumpOnPanic dumps the flight recorder if the goroutine is panicking and then
+// resumes panicking. It must be deferred directly:
+//
+//	defer l.DumpOnPanic()
+func (l *Spoor) DumpOnPanic() {
+	if r := recover(); r != nil {
+		l.DumpFlightRecorder()
+		panic(r)
+	}
+}
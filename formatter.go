@@ -0,0 +1,404 @@
+package spoor
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type Entry struct {
+	Time    time.Time
+	Level   Level
+	Message string
+	Fields  []Field
+	File    string
+	Line    int
+	// Encoded holds the first EncodedFields of Fields as already rendered by
+	// the formatter, see FieldEncoder. Whoever changes one of those fields
+	// must reset both.
+	Encoded       []byte
+	EncodedFields int
+	owned         bool // Fields was built by the logger, see WithFieldCapacity
+}
+
+// Formatter renders an entry, including the trailing newline, into buf.
+type Formatter interface {
+	Format(buf *bytes.Buffer, e *Entry)
+}
+
+// FieldEncoder is implemented by formatters which can render fields ahead of
+// time, so that the fields of a logger returned by With are encoded once
+// instead of on every entry.
+type FieldEncoder interface {
+	EncodeFields(buf *bytes.Buffer, fields []Field)
+}
+
+// TextFormatter writes the same header as the standard library logger for
+// Flag and Prefix, followed by "LEVEL msg key=value ...".
+type TextFormatter struct {
+	Flag   int
+	Prefix string
+	// TimeFormat, when set, replaces the date and time of Flag by the
+	// timestamp formatted with this layout, or by a number if Epoch is set.
+	TimeFormat string
+	Epoch      EpochUnit
+	Location   *time.Location // zone of the timestamp, overrides log.LUTC
+	Precision  time.Duration  // truncate the timestamp, e.g. to time.Millisecond
+	// Multiline sets how the line breaks of messages, such as stack traces,
+	// are written. They are escaped by default so that line based collectors
+	// keep one entry per line, MultilineIndent lets them join the
+	// continuation lines instead. Field values are always quoted.
+	Multiline MultilineMode
+	// Continuation starts the continuation lines of MultilineIndent, a tab
+	// by default.
+	Continuation string
+	Values       ValueFormat // rendering of durations, times and bytes
+}
+
+type MultilineMode int
+
+const (
+	MultilineEscape MultilineMode = iota // write the line breaks as \n and \r
+	MultilineRaw                         // write them as they are
+	MultilineIndent                      // start the following lines with Continuation
+)
+
+func (f *TextFormatter) Format(buf *bytes.Buffer, e *Entry) {
+	if f.Flag&log.Lmsgprefix == 0 {
+		buf.WriteString(f.Prefix)
+	}
+	f.formatHeader(buf, e)
+	if f.Flag&log.Lmsgprefix != 0 {
+		buf.WriteString(f.Prefix)
+	}
+	buf.WriteString(e.Level.String())
+	buf.WriteByte(' ')
+	f.appendMessage(buf, e.Message)
+	fields := e.Fields
+	if e.Encoded != nil {
+		buf.Write(e.Encoded)
+		fields = fields[e.EncodedFields:]
+	}
+	f.EncodeFields(buf, fields)
+	buf.WriteByte('\n')
+}
+
+func (f *TextFormatter) EncodeFields(buf *bytes.Buffer, fields []Field) {
+	var failed []string
+	for _, field := range fields {
+		buf.WriteByte(' ')
+		appendTextString(buf, field.Key)
+		buf.WriteByte('=')
+		if err := appendTextValue(buf, field, &f.Values); err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+	if len(failed) > 0 {
+		buf.WriteString(" encoding_error=")
+		appendTextString(buf, strings.Join(failed, "; "))
+	}
+}
+
+func (f *TextFormatter) appendMessage(buf *bytes.Buffer, msg string) {
+	if f.Multiline == MultilineRaw || !strings.ContainsAny(msg, "\r\n") {
+		buf.WriteString(msg)
+		return
+	}
+	if f.Multiline == MultilineEscape {
+		for i := 0; i < len(msg); i++ {
+			switch msg[i] {
+			case '\n':
+				buf.WriteString(`\n`)
+			case '\r':
+				buf.WriteString(`\r`)
+			default:
+				buf.WriteByte(msg[i])
+			}
+		}
+		return
+	}
+	continuation := f.Continuation
+	if continuation == "" {
+		continuation = "\t"
+	}
+	lines := strings.Split(strings.TrimRight(strings.ReplaceAll(msg, "\r\n", "\n"), "\n"), "\n")
+	for i, line := range lines {
+		if i > 0 {
+			buf.WriteByte('\n')
+			buf.WriteString(continuation)
+		}
+		buf.WriteString(line)
+	}
+}
+
+func (f *TextFormatter) formatHeader(buf *bytes.Buffer, e *Entry) {
+	opts := timeOptions{location: f.Location, layout: f.TimeFormat, epoch: f.Epoch, precision: f.Precision}
+	if f.Flag&log.LUTC != 0 && f.Location == nil {
+		opts.location = time.UTC
+	}
+	if f.TimeFormat != "" || f.Epoch != EpochNone {
+		var arr [64]byte
+		buf.Write(opts.appendTime(arr[:0], e.Time, false))
+		buf.WriteByte(' ')
+	} else if f.Flag&(log.Ldate|log.Ltime|log.Lmicroseconds) != 0 {
+		t := opts.adjust(e.Time)
+		var arr [32]byte
+		b := arr[:0]
+		if f.Flag&log.Ldate != 0 {
+			year, month, day := t.Date()
+			b = appendPadded(b, year, 4)
+			b = append(b, '/')
+			b = appendPadded(b, int(month), 2)
+			b = append(b, '/')
+			b = appendPadded(b, day, 2)
+			b = append(b, ' ')
+		}
+		if f.Flag&(log.Ltime|log.Lmicroseconds) != 0 {
+			hour, min, sec := t.Clock()
+			b = appendPadded(b, hour, 2)
+			b = append(b, ':')
+			b = appendPadded(b, min, 2)
+			b = append(b, ':')
+			b = appendPadded(b, sec, 2)
+			if f.Flag&log.Lmicroseconds != 0 {
+				b = append(b, '.')
+				b = appendPadded(b, t.Nanosecond()/1e3, 6)
+			}
+			b = append(b, ' ')
+		}
+		buf.Write(b)
+	}
+	if f.Flag&(log.Lshortfile|log.Llongfile) != 0 {
+		file := e.File
+		if file == "" {
+			file = "???"
+		}
+		if f.Flag&log.Lshortfile != 0 {
+			for i := len(file) - 1; i > 0; i-- {
+				if file[i] == '/' {
+					file = file[i+1:]
+					break
+				}
+			}
+		}
+		buf.WriteString(file)
+		buf.WriteByte(':')
+		var arr [20]byte
+		buf.Write(strconv.AppendInt(arr[:0], int64(e.Line), 10))
+		buf.WriteString(": ")
+	}
+}
+
+func appendPadded(b []byte, i, width int) []byte {
+	start := len(b)
+	b = strconv.AppendInt(b, int64(i), 10)
+	for n := len(b) - start; n < width; n++ {
+		b = append(b, '0')
+		copy(b[start+1:], b[start:])
+		b[start] = '0'
+	}
+	return b
+}
+
+// appendTextValue writes the field value, quoting it when it would not read
+// back as a single word. When the Error method of the value panics, its fmt
+// form is written instead and the panic is returned.
+func appendTextValue(buf *bytes.Buffer, f Field, v *ValueFormat) (err error) {
+	mark := buf.Len()
+	defer func() {
+		if r := recover(); r != nil {
+			buf.Truncate(mark)
+			appendTextString(buf, sprintValue(f.Value()))
+			err = fmt.Errorf("%s: panic: %v", f.Key, r)
+		}
+	}()
+	switch f.Type {
+	case StringType:
+		appendTextString(buf, f.Str)
+	case IntType:
+		var arr [20]byte
+		buf.Write(strconv.AppendInt(arr[:0], f.Integer, 10))
+	case FloatType:
+		var arr [32]byte
+		buf.Write(strconv.AppendFloat(arr[:0], math.Float64frombits(uint64(f.Integer)), 'g', -1, 64))
+	case BoolType:
+		buf.WriteString(strconv.FormatBool(f.Integer == 1))
+	case DurationType:
+		var arr [32]byte
+		b, _ := v.appendDuration(arr[:0], time.Duration(f.Integer))
+		buf.Write(b)
+	case TimeType:
+		var arr [64]byte
+		appendTextString(buf, string(v.appendTime(arr[:0], f.time())))
+	case ErrorType:
+		if err, ok := f.Interface.(error); ok && err != nil {
+			appendTextString(buf, err.Error())
+		} else {
+			buf.WriteString("<nil>")
+		}
+	default:
+		if p, ok := f.Interface.([]byte); ok && p != nil {
+			appendTextString(buf, v.bytesText(p))
+			return nil
+		}
+		if v.Expand && expandable(f.Interface) && !(mayCycle(f.Interface) && cyclic(reflect.ValueOf(f.Interface), nil)) {
+			if data, err := v.marshal(f.Interface); err == nil {
+				appendTextString(buf, string(data))
+				return nil
+			}
+		}
+		appendTextString(buf, sprintValue(f.Interface))
+	}
+	return nil
+}
+
+// expandable reports whether x is a struct, map, slice or array, or a
+// pointer to one, which ValueFormat.Expand renders as JSON.
+func expandable(x interface{}) bool {
+	t := reflect.TypeOf(x)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		return true
+	}
+	return false
+}
+
+// sprintValue is fmt.Sprint, which never returns for a map or slice
+// containing itself, with such values replaced by "<cyclic TYPE>".
+func sprintValue(v interface{}) string {
+	switch v.(type) {
+	case error, fmt.Stringer:
+		// fmt does not look inside
+	default:
+		if mayCycle(v) && cyclic(reflect.ValueOf(v), nil) {
+			return cyclicText(v)
+		}
+	}
+	return fmt.Sprint(v)
+}
+
+func cyclicText(v interface{}) string {
+	return fmt.Sprintf("<cyclic %T>", v)
+}
+
+// mayCycle reports whether the type of v allows cyclic to find a cycle, so
+// that the values which cannot hold a map or slice are not walked.
+func mayCycle(v interface{}) bool {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return false
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return mayContain(t)
+}
+
+// cyclic reports whether v holds a map or slice which is already on path.
+// Pointers are only followed at the top, like fmt which prints the address of
+// the others.
+func cyclic(v reflect.Value, path []uintptr) bool {
+	switch v.Kind() {
+	case reflect.Interface:
+		return !v.IsNil() && cyclic(v.Elem(), path)
+	case reflect.Ptr:
+		return path == nil && !v.IsNil() && cyclic(v.Elem(), []uintptr{})
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if cyclic(v.Field(i), path) {
+				return true
+			}
+		}
+	case reflect.Array:
+		if !mayContain(v.Type().Elem()) {
+			return false
+		}
+		for i := 0; i < v.Len(); i++ {
+			if cyclic(v.Index(i), path) {
+				return true
+			}
+		}
+	case reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			return false
+		}
+		p := v.Pointer()
+		for _, q := range path {
+			if p == q {
+				return true
+			}
+		}
+		path = append(path, p)
+		if v.Kind() == reflect.Slice {
+			if !mayContain(v.Type().Elem()) {
+				return false
+			}
+			for i := 0; i < v.Len(); i++ {
+				if cyclic(v.Index(i), path) {
+					return true
+				}
+			}
+			return false
+		}
+		if !mayContain(v.Type().Key()) && !mayContain(v.Type().Elem()) {
+			return false
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			if cyclic(iter.Key(), path) || cyclic(iter.Value(), path) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mayContain reports whether values of type t may hold a map or slice which
+// cyclic follows, pointers below the top being left to fmt.
+func mayContain(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Interface, reflect.Map, reflect.Slice:
+		return true
+	case reflect.Array:
+		return mayContain(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if mayContain(t.Field(i).Type) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func appendTextString(buf *bytes.Buffer, s string) {
+	if needsQuoting(s) {
+		buf.WriteString(strconv.Quote(s))
+		return
+	}
+	buf.WriteString(s)
+}
+
+func needsQuoting(s string) bool {
+	if s == "" {
+		return true
+	}
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError || r == 0x7f {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,22 @@
+package spoor
+
+import "bytes"
+
+// FormatterSelector is a Formatter choosing the formatter of every entry, so
+// that one writer can receive entries in several formats, e.g. access logs
+// in a line format and everything else as JSON:
+//
+//	json, access := &spoor.JSONFormatter{}, &accessFormatter{}
+//	spoor.WithFormatter(spoor.FormatterSelector(func(e *spoor.Entry) spoor.Formatter {
+//		if e.Message == "access" {
+//			return access
+//		}
+//		return json
+//	}))
+//
+// The fields of With are then encoded with every entry.
+type FormatterSelector func(e *Entry) Formatter
+
+func (s FormatterSelector) Format(buf *bytes.Buffer, e *Entry) {
+	s(e).Format(buf, e)
+}
@@ -0,0 +1,55 @@
+// Package ginspoor logs the requests served by Gin with spoor.
+package ginspoor
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/phuhao00/spoor"
+)
+
+// Middleware logs one entry per request with its route and handler, see
+// spoor.Spoor.LogRequest, and recovers the panics of the handlers, which are
+// logged with their stack and answered with a 500. The request scoped logger
+// is stored in the request context, see spoor.FromContext:
+//
+//	r := gin.New()
+//	r.Use(ginspoor.Middleware(l))
+func Middleware(l *spoor.Spoor) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		id, logger := l.RequestLogger(c.GetHeader)
+		c.Header(spoor.RequestIDHeader, id)
+		c.Request = c.Request.WithContext(spoor.NewContext(c.Request.Context(), id, logger))
+		defer func() {
+			if p := recover(); p != nil {
+				if p == http.ErrAbortHandler {
+					panic(p)
+				}
+				logger.LogPanic(p)
+				c.AbortWithStatus(http.StatusInternalServerError)
+			}
+			var err error
+			if last := c.Errors.Last(); last != nil {
+				err = last.Err
+			}
+			size := c.Writer.Size()
+			if size < 0 {
+				size = 0
+			}
+			logger.LogRequest(spoor.RequestLog{
+				Method:  c.Request.Method,
+				Path:    c.Request.URL.Path,
+				Route:   c.FullPath(),
+				Handler: c.HandlerName(),
+				Status:  c.Writer.Status(),
+				Bytes:   int64(size),
+				Latency: time.Since(start),
+				Remote:  c.ClientIP(),
+				Err:     err,
+			})
+		}()
+		c.Next()
+	}
+}
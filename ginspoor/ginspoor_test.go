@@ -0,0 +1,52 @@
+package ginspoor
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/phuhao00/spoor"
+)
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	mw := spoor.NewMemoryWriter(10)
+	l := spoor.NewSpoor(spoor.DEBUG, "", 0, spoor.WithConsoleWriter(mw))
+	r := gin.New()
+	r.Use(Middleware(l))
+	r.GET("/users/:id", func(c *gin.Context) {
+		spoor.FromContext(c.Request.Context()).Info("handling")
+		c.Error(errors.New("not found"))
+		c.String(http.StatusNotFound, "missing")
+	})
+	r.GET("/panic", func(c *gin.Context) { panic("boom") })
+
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	req.Header.Set(spoor.RequestIDHeader, "abc")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Header().Get(spoor.RequestIDHeader) != "abc" {
+		t.Fatalf("got headers %v", rec.Header())
+	}
+	lines := mw.Lines()
+	if len(lines) != 2 || !strings.Contains(string(lines[0]), "INFO handling request_id=abc") {
+		t.Fatalf("got %q", lines)
+	}
+	for _, want := range []string{"WARNING request", "path=/users/42 route=/users/:id handler=", "status=404 bytes=7", "error=\"not found\"", "request_id=abc"} {
+		if !strings.Contains(string(lines[1]), want) {
+			t.Errorf("%q not in %q", want, lines[1])
+		}
+	}
+
+	mw.Reset()
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	lines = mw.Lines()
+	if rec.Code != http.StatusInternalServerError || len(lines) != 2 ||
+		!strings.Contains(string(lines[0]), "ERROR panic serving request") || !strings.Contains(string(lines[1]), "ERROR request") {
+		t.Fatalf("status %d, got %q", rec.Code, lines)
+	}
+}
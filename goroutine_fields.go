@@ -0,0 +1,60 @@
+package spoor
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+var goroutineFields struct {
+	mu     sync.RWMutex
+	fields map[int64][]Field
+	count  int32 // len(fields), read without the lock
+}
+
+// SetGoroutineFields adds fields to the entries logged by the current
+// goroutine through loggers using GoroutineEnricher, e.g. in a middleware:
+//
+//	defer spoor.SetGoroutineFields(spoor.String("user", user))()
+//
+// The returned function restores the previous fields and must be called
+// before the goroutine exits. The fields are not seen by the goroutines it
+// starts, pass them a logger built with With instead.
+func SetGoroutineFields(fields ...Field) (restore func()) {
+	id := currentGoroutineID()
+	goroutineFields.mu.Lock()
+	if goroutineFields.fields == nil {
+		goroutineFields.fields = make(map[int64][]Field)
+	}
+	prev, had := goroutineFields.fields[id]
+	goroutineFields.fields[id] = append(prev[:len(prev):len(prev)], fields...)
+	atomic.StoreInt32(&goroutineFields.count, int32(len(goroutineFields.fields)))
+	goroutineFields.mu.Unlock()
+	return func() {
+		goroutineFields.mu.Lock()
+		if had {
+			goroutineFields.fields[id] = prev
+		} else {
+			delete(goroutineFields.fields, id)
+		}
+		atomic.StoreInt32(&goroutineFields.count, int32(len(goroutineFields.fields)))
+		goroutineFields.mu.Unlock()
+	}
+}
+
+// GoroutineEnricher adds the fields set with SetGoroutineFields. It is opt-in
+// because, while some goroutine has fields, every entry costs a stack trace
+// to find the id of the logging goroutine.
+func GoroutineEnricher() Enricher {
+	return func(e *Entry) {
+		if atomic.LoadInt32(&goroutineFields.count) == 0 {
+			return
+		}
+		id := currentGoroutineID()
+		goroutineFields.mu.RLock()
+		fields := goroutineFields.fields[id]
+		goroutineFields.mu.RUnlock()
+		if len(fields) > 0 {
+			e.AddFields(fields...)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package spoor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// HealthChecker is implemented by writers which can tell whether they are
+// able to deliver entries.
+type HealthChecker interface {
+	HealthCheck(ctx context.Context) error
+}
+
+// WriterHealth is the state of one registered writer.
+type WriterHealth struct {
+	Writer  string `json:"writer"`
+	Healthy bool   `json:"healthy"`
+	Error   string `json:"error,omitempty"`
+	Pending int    `json:"pending,omitempty"` // lines waiting to be sent
+}
+
+// Health checks the registered writers implementing HealthChecker, see
+// Register.
+func Health(ctx context.Context) []WriterHealth {
+	var states []WriterHealth
+	for _, c := range registered() {
+		hc, ok := c.(HealthChecker)
+		if !ok {
+			continue
+		}
+		state := WriterHealth{Writer: fmt.Sprintf("%T", c), Healthy: true}
+		if err := hc.HealthCheck(ctx); err != nil {
+			state.Healthy, state.Error = false, err.Error()
+		}
+		if p, ok := c.(interface{ Pending() int }); ok {
+			state.Pending = p.Pending()
+		}
+		states = append(states, state)
+	}
+	return states
+}
+
+// HealthHandler serves Health as JSON, with status 503 if a writer is
+// unhealthy, for readiness probes.
+func HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		states := Health(r.Context())
+		status := http.StatusOK
+		for _, s := range states {
+			if !s.Healthy {
+				status = http.StatusServiceUnavailable
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(states)
+	})
+}
@@ -0,0 +1,110 @@
+package spoor
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Hook is called with every entry logged at one of its levels, before the
+// entry is formatted and written, so Fire may change it, e.g. to add a trace
+// id with AddFields or to redact a value with SetField or RemoveField; the
+// Fields slice itself must not be modified in place. The entry must not be
+// retained after Fire returns.
+//
+// Hooks run after the enrichers, the field transform, the pipeline, the
+// schema and the limits of the logger, so their changes are not checked
+// against those, and after the flight recorder and the debug buffer kept the
+// entry. They run one at a time, in the order they were added, each seeing
+// the changes of the previous ones, on the goroutine of the logging call.
+// A hook returning an error does not stop the others nor the write.
+type Hook interface {
+	Levels() []Level // nil means all levels
+	Fire(e *Entry) error
+}
+
+// WithHook adds a hook, hooks are fired in the order they were added.
+func WithHook(hook Hook) Option {
+	return func(spoor *Spoor) {
+		spoor.hooks = append(spoor.hooks[:len(spoor.hooks):len(spoor.hooks)], hook)
+	}
+}
+
+// HookMatcher selects the entries a hook is fired for, see WithHookIf.
+type HookMatcher func(e *Entry) bool
+
+// LevelRange matches the entries logged from min to max included.
+func LevelRange(min, max Level) HookMatcher {
+	return func(e *Entry) bool { return e.Level >= min && e.Level <= max }
+}
+
+// FieldEquals matches the entries with a field key whose value has the same
+// text form as value, so that 500 and "500" are equal.
+func FieldEquals(key string, value interface{}) HookMatcher {
+	want := fmt.Sprint(value)
+	return func(e *Entry) bool {
+		for _, f := range e.Fields {
+			if f.Key == key && fmt.Sprint(f.Value()) == want {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// MessageMatches matches the entries whose message matches re.
+func MessageMatches(re *regexp.Regexp) HookMatcher {
+	return func(e *Entry) bool { return re.MatchString(e.Message) }
+}
+
+// WithHookIf adds a hook fired only for the entries of its levels matching
+// all of matchers, e.g. to send the errors of the payment service to an
+// alerting hook:
+//
+//	spoor.WithHookIf(alerts, spoor.LevelRange(spoor.ERROR, spoor.FATAL), spoor.FieldEquals("service", "payment"))
+func WithHookIf(hook Hook, matchers ...HookMatcher) Option {
+	return WithHook(&matchedHook{Hook: hook, matchers: matchers})
+}
+
+type matchedHook struct {
+	Hook
+	matchers []HookMatcher
+}
+
+func (h *matchedHook) match(e *Entry) bool {
+	for _, m := range h.matchers {
+		if !m(e) {
+			return false
+		}
+	}
+	return true
+}
+
+func (l *Spoor) fireHooks(e *Entry) {
+	for _, hook := range l.hooks {
+		if !hookLevel(hook, e.Level) {
+			continue
+		}
+		if m, ok := hook.(*matchedHook); ok {
+			if !m.match(e) {
+				continue
+			}
+			hook = m.Hook
+		}
+		if err := hook.Fire(e); err != nil {
+			l.entryError(e, fmt.Errorf("spoor: hook %T: %w", hook, err))
+		}
+	}
+}
+
+func hookLevel(hook Hook, level Level) bool {
+	levels := hook.Levels()
+	if levels == nil {
+		return true
+	}
+	for _, lvl := range levels {
+		if lvl == level {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package spoor
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// HTTPMiddleware logs the requests served by next: on top of
+// RequestIDMiddleware it logs one entry per request with LogRequest and
+// recovers panics of next, which are logged with LogPanic and answered with
+// a 500. The Gin, Echo and Fiber middlewares built on the same functions are
+// in the ginspoor, echospoor and fiberspoor modules.
+func (l *Spoor) HTTPMiddleware(next http.Handler) http.Handler {
+	return l.RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rw := &statusWriter{ResponseWriter: w}
+		logger := FromContext(r.Context())
+		defer func() {
+			if p := recover(); p != nil {
+				if p == http.ErrAbortHandler {
+					panic(p)
+				}
+				logger.LogPanic(p)
+				if rw.status == 0 {
+					rw.WriteHeader(http.StatusInternalServerError)
+				}
+			}
+			if rw.status == 0 {
+				rw.status = http.StatusOK
+			}
+			logger.LogRequest(RequestLog{
+				Method:  r.Method,
+				Path:    r.URL.Path,
+				Status:  rw.status,
+				Bytes:   rw.bytes,
+				Latency: time.Since(start),
+				Remote:  r.RemoteAddr,
+			})
+		}()
+		next.ServeHTTP(rw, r)
+	}))
+}
+
+// RequestLog describes a served request, see LogRequest.
+type RequestLog struct {
+	Method  string
+	Path    string
+	Route   string // pattern of the matched route, if known
+	Handler string // name of the handler, if known
+	Status  int
+	Bytes   int64
+	Latency time.Duration
+	Remote  string
+	Err     error // returned by the handler, if any
+}
+
+// LogRequest logs the request entry of the HTTP middlewares, at WARN for 4xx
+// and ERROR for 5xx statuses.
+func (l *Spoor) LogRequest(r RequestLog) {
+	level := INFO
+	switch {
+	case r.Status >= 500:
+		level = ERROR
+	case r.Status >= 400:
+		level = WARN
+	}
+	fields := []Field{String("method", r.Method), String("path", r.Path)}
+	if r.Route != "" {
+		fields = append(fields, String("route", r.Route))
+	}
+	if r.Handler != "" {
+		fields = append(fields, String("handler", r.Handler))
+	}
+	fields = append(fields,
+		Int("status", r.Status),
+		Int64("bytes", r.Bytes),
+		Dur("latency", r.Latency),
+		String("remote", r.Remote),
+	)
+	if r.Err != nil {
+		fields = append(fields, Err(r.Err))
+	}
+	l.log(2, level, "request", fields)
+}
+
+// LogPanic logs a panic recovered while serving a request, with its stack.
+func (l *Spoor) LogPanic(p interface{}) {
+	l.log(2, ERROR, "panic serving request", []Field{Err(WithStack(fmt.Errorf("%v", p)))})
+}
+
+// statusWriter records the status and size of a response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Hijack lets websocket upgrades through, the request is logged with status
+// 101.
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("spoor: response writer does not support hijacking")
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil && w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the original writer, for http.ResponseController.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,93 @@
+package spoor
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// httpSender posts the batches of its batcher to an HTTP endpoint, the part
+// shared by LogbusWriter, DatadogWriter and SplunkWriter: encode turns the
+// lines into a payload, which is gzipped if asked and posted with the
+// headers set by header, with retries. The lines of a payload which could
+// not be delivered go to the dead letter writer.
+type httpSender struct {
+	*batcher
+	name        string // in errors, e.g. "datadog"
+	url         string
+	contentType string
+	gzip        bool
+	retry       RetryPolicy
+	deadLetter  io.Writer
+	transport   HTTPTransport
+	client      *http.Client
+	encode      func(lines [][]byte) ([]byte, error) // an empty payload is not sent
+	header      func(h http.Header)
+}
+
+func newHTTPSender(s *httpSender, maxCount, maxBytes int, interval time.Duration) *httpSender {
+	s.batcher = newBatcher(maxCount, maxBytes, interval, s.send)
+	return s
+}
+
+func (s *httpSender) send(lines [][]byte) {
+	payload, err := s.encode(lines)
+	if err == nil && len(payload) == 0 {
+		return
+	}
+	if err == nil && s.gzip {
+		payload, err = gzipBytes(payload)
+	}
+	if err == nil {
+		err = s.retry.Do(func() error { return s.post(payload) })
+	}
+	if err != nil {
+		s.drop(lines, err)
+	}
+}
+
+// drop hands lines which cannot be delivered to the dead letter writer, or
+// reports them as dropped without one.
+func (s *httpSender) drop(lines [][]byte, err error) {
+	if s.deadLetter == nil {
+		internalError(fmt.Errorf("spoor: %s dropped %d entries: %w", s.name, len(lines), err))
+		return
+	}
+	if err := writeDeadLetters(s.deadLetter, lines, err); err != nil {
+		internalError(fmt.Errorf("spoor: %s dead letter: %w", s.name, err))
+	}
+}
+
+// post sends one payload, the failures worth a retry match
+// ErrRemoteUnavailable.
+func (s *httpSender) post(payload []byte) error {
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", s.contentType)
+	if s.gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if s.header != nil {
+		s.header(req.Header)
+	}
+	s.transport.prepare(req, payload)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return unavailable(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		err = fmt.Errorf("%s: %s: %s", s.name, resp.Status, bytes.TrimSpace(msg))
+		if retryableStatus(resp.StatusCode) {
+			return unavailable(err)
+		}
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
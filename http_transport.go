@@ -0,0 +1,112 @@
+package spoor
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// HTTPTransport holds the connection options shared by the writers posting
+// over HTTP. They apply to the client the writer builds, a Client given in
+// its config is used as is apart from Headers and SigV4.
+type HTTPTransport struct {
+	// Proxy is the proxy URL, nil uses the HTTP_PROXY, HTTPS_PROXY and
+	// NO_PROXY environment variables.
+	Proxy   *url.URL
+	TLS     *tls.Config
+	Headers map[string]string // added to every request
+	// SigV4 signs the requests with AWS Signature Version 4, e.g. for an
+	// Amazon OpenSearch Service domain.
+	SigV4 *SigV4Config
+}
+
+type SigV4Config struct {
+	Credentials AWSCredentials // defaults to AWSCredentialsFromEnv
+	Region      string         // defaults to AWS_REGION
+	Service     string         // defaults to "es"
+}
+
+// client returns an http.Client with the proxy and TLS options of t.
+func (t *HTTPTransport) client(timeout time.Duration) *http.Client {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	if t.Proxy != nil {
+		tr.Proxy = http.ProxyURL(t.Proxy)
+	}
+	if t.TLS != nil {
+		tr.TLSClientConfig = t.TLS
+	}
+	return &http.Client{Timeout: timeout, Transport: tr}
+}
+
+// prepare adds the headers to req and signs it, last, with body as payload.
+func (t *HTTPTransport) prepare(req *http.Request, body []byte) {
+	t.setHeaders(req)
+	if s := t.SigV4; s != nil {
+		creds := s.Credentials
+		if creds.AccessKeyID == "" {
+			creds = AWSCredentialsFromEnv()
+		}
+		signV4(req, body, creds, awsRegion(s.Region), defaultString(s.Service, "es"), time.Now())
+	}
+}
+
+// setHeaders adds the headers to req, for the writers signing the requests
+// themselves.
+func (t *HTTPTransport) setHeaders(req *http.Request) {
+	for k, v := range t.Headers {
+		req.Header.Set(k, v)
+	}
+}
+
+// httpTransportConfig is the config file form of HTTPTransport.
+type httpTransportConfig struct {
+	Proxy              string            `json:"proxy"`
+	Headers            map[string]string `json:"headers"`
+	CAFile             string            `json:"ca_file"`
+	CertFile           string            `json:"cert_file"`
+	KeyFile            string            `json:"key_file"`
+	InsecureSkipVerify bool              `json:"insecure_skip_verify"`
+	SigV4              *struct {
+		Region  string `json:"region"`
+		Service string `json:"service"`
+	} `json:"sigv4"`
+}
+
+func (c *httpTransportConfig) transport() (HTTPTransport, error) {
+	t := HTTPTransport{Headers: c.Headers}
+	if c.Proxy != "" {
+		u, err := url.Parse(c.Proxy)
+		if err != nil {
+			return t, fmt.Errorf("invalid proxy: %v", err)
+		}
+		t.Proxy = u
+	}
+	if c.CAFile != "" || c.CertFile != "" || c.InsecureSkipVerify {
+		t.TLS = &tls.Config{InsecureSkipVerify: c.InsecureSkipVerify}
+		if c.CAFile != "" {
+			pem, err := os.ReadFile(c.CAFile)
+			if err != nil {
+				return t, err
+			}
+			t.TLS.RootCAs = x509.NewCertPool()
+			if !t.TLS.RootCAs.AppendCertsFromPEM(pem) {
+				return t, fmt.Errorf("no certificate in %s", c.CAFile)
+			}
+		}
+		if c.CertFile != "" {
+			cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+			if err != nil {
+				return t, err
+			}
+			t.TLS.Certificates = []tls.Certificate{cert}
+		}
+	}
+	if c.SigV4 != nil {
+		t.SigV4 = &SigV4Config{Region: c.SigV4.Region, Service: c.SigV4.Service}
+	}
+	return t, nil
+}
@@ -0,0 +1,69 @@
+package spoor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+)
+
+var (
+	// ErrQueueFull is returned by writers dropping lines because their queue
+	// is full, such as an AsyncWriter with DropFull.
+	ErrQueueFull = errors.New("spoor: queue is full")
+	// ErrWriterClosed is returned by writers written to after Close.
+	ErrWriterClosed = errors.New("spoor: writer is closed")
+	// ErrRemoteUnavailable matches, with errors.Is, the errors of writers
+	// which could not reach their remote service or got a retryable status.
+	ErrRemoteUnavailable = errors.New("spoor: remote is unavailable")
+)
+
+// remoteError marks err as an ErrRemoteUnavailable while keeping its message.
+type remoteError struct {
+	err error
+}
+
+func unavailable(err error) error {
+	return &remoteError{err: err}
+}
+
+func (e *remoteError) Error() string { return e.err.Error() }
+
+func (e *remoteError) Unwrap() error { return e.err }
+
+func (e *remoteError) Is(target error) bool { return target == ErrRemoteUnavailable }
+
+var internalErrorHandler atomic.Value // func(error)
+
+// SetInternalErrorHandler sets the function called with the errors which
+// cannot be returned to a caller, such as failed writes of a logger, batches
+// dropped by remote writers or hook failures. The default handler prints them
+// to stderr. The handler must not log to the writer which failed.
+func SetInternalErrorHandler(handler func(err error)) {
+	internalErrorHandler.Store(handler)
+}
+
+// WithErrorHandler sets the function called with the entries which could not
+// be written, or whose hooks failed, instead of the internal error handler.
+// The entry must not be retained.
+func WithErrorHandler(handler func(e *Entry, err error)) Option {
+	return func(spoor *Spoor) {
+		spoor.onError = handler
+	}
+}
+
+func (l *Spoor) entryError(e *Entry, err error) {
+	if l.onError != nil {
+		l.onError(e, err)
+		return
+	}
+	internalError(err)
+}
+
+func internalError(err error) {
+	if handler, _ := internalErrorHandler.Load().(func(error)); handler != nil {
+		handler(err)
+		return
+	}
+	fmt.Fprintln(os.Stderr, err)
+}
@@ -0,0 +1,144 @@
+package spoor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var journaldSocket = "/run/systemd/journal/socket"
+
+// JournaldWriter sends every line to systemd-journald with its native
+// protocol. Lines of the JSONFormatter are sent as structured entries, their
+// fields becoming journal fields, e.g. user_id as USER_ID.
+type JournaldWriter struct {
+	conn *net.UnixConn
+}
+
+func NewJournaldWriter() (*JournaldWriter, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journaldSocket, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	return &JournaldWriter{conn: conn}, nil
+}
+
+func (jw *JournaldWriter) Write(p []byte) (n int, err error) {
+	var msg bytes.Buffer
+	line := bytes.TrimRight(p, "\n")
+	writeJournalField(&msg, "SYSLOG_IDENTIFIER", program)
+	if !jw.appendJSON(&msg, line) {
+		level, _ := lineLevel(line)
+		writeJournalField(&msg, "PRIORITY", strconv.Itoa(journalPriority(level)))
+		writeJournalField(&msg, "MESSAGE", string(line))
+	}
+	if _, err := jw.conn.Write(msg.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// appendJSON writes the fields of a JSONFormatter line, it returns false if
+// line is not one.
+func (jw *JournaldWriter) appendJSON(msg *bytes.Buffer, line []byte) bool {
+	if len(line) == 0 || line[0] != '{' {
+		return false
+	}
+	var e struct {
+		Level  string                     `json:"level"`
+		Msg    string                     `json:"msg"`
+		Caller string                     `json:"caller"`
+		Fields map[string]json.RawMessage `json:"fields"`
+	}
+	if json.Unmarshal(line, &e) != nil || e.Level == "" {
+		return false
+	}
+	level, _ := lineLevel(line)
+	writeJournalField(msg, "PRIORITY", strconv.Itoa(journalPriority(level)))
+	writeJournalField(msg, "MESSAGE", e.Msg)
+	if i := strings.LastIndexByte(e.Caller, ':'); i > 0 {
+		writeJournalField(msg, "CODE_FILE", e.Caller[:i])
+		writeJournalField(msg, "CODE_LINE", e.Caller[i+1:])
+	}
+	for key, raw := range e.Fields {
+		value := string(raw)
+		var s string
+		if json.Unmarshal(raw, &s) == nil {
+			value = s
+		}
+		if name := journalKey(key); name != "" {
+			writeJournalField(msg, name, value)
+		}
+	}
+	return true
+}
+
+func (jw *JournaldWriter) Close() error {
+	return jw.conn.Close()
+}
+
+// writeJournalField writes KEY=value, or the binary safe form for values
+// containing newlines.
+func writeJournalField(buf *bytes.Buffer, key, value string) {
+	buf.WriteString(key)
+	if strings.IndexByte(value, '\n') < 0 {
+		buf.WriteByte('=')
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+		return
+	}
+	buf.WriteByte('\n')
+	var size [8]byte
+	binary.LittleEndian.PutUint64(size[:], uint64(len(value)))
+	buf.Write(size[:])
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+// journalKey turns key into a valid journal field name: upper case letters,
+// digits and underscores, not starting with an underscore.
+func journalKey(key string) string {
+	name := strings.TrimLeft(strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, key), "_0123456789")
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
+
+// journalPriority maps levels to syslog priorities.
+func journalPriority(level Level) int {
+	switch level {
+	case DEBUG:
+		return 7
+	case WARN:
+		return 4
+	case ERROR:
+		return 3
+	case FATAL:
+		return 2
+	}
+	return 6
+}
+
+// NewPlatformWriter returns a JournaldWriter when journald is running, and
+// os.Stderr otherwise.
+func NewPlatformWriter() io.Writer {
+	jw, err := NewJournaldWriter()
+	if err != nil {
+		return os.Stderr
+	}
+	return jw
+}
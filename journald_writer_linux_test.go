@@ -0,0 +1,36 @@
+package spoor
+
+import (
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestJournaldWriter(t *testing.T) {
+	journaldSocket = filepath.Join(t.TempDir(), "socket")
+	server, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: journaldSocket, Net: "unixgram"})
+	if err != nil {
+		t.Skip(err)
+	}
+	defer server.Close()
+	jw, err := NewJournaldWriter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer jw.Close()
+
+	l := NewSpoor(DEBUG, "", 0, WithConsoleWriter(jw), WithFormatter(&JSONFormatter{}))
+	l.Warn("disk\nfull", Int("free_mb", 12))
+	buf := make([]byte, 4096)
+	n, err := server.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(buf[:n])
+	for _, want := range []string{"PRIORITY=4\n", "MESSAGE\n\x09\x00\x00\x00\x00\x00\x00\x00disk\nfull\n", "FREE_MB=12\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("%q missing from %q", want, got)
+		}
+	}
+}
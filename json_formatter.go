@@ -0,0 +1,304 @@
+package spoor
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// JSONFormatter writes one JSON object per line:
+// {"time":"...","level":"INFO","msg":"...","caller":"file:line","fields":{...}}
+type JSONFormatter struct {
+	TimeFormat string         // defaults to time.RFC3339Nano
+	Location   *time.Location // zone of the timestamp, nil keeps the entry's
+	Epoch      EpochUnit      // write the timestamp as a number instead
+	Precision  time.Duration  // truncate the timestamp, e.g. to time.Millisecond
+	LevelKey   string         // defaults to "level"
+	TimeKey    string         // defaults to "time"
+	MessageKey string         // defaults to "msg"
+	// Flatten writes the fields next to the time, level and message instead
+	// of under "fields". A field named like one of those keys, or "caller",
+	// is written as "fields.<key>".
+	Flatten bool
+	// SortFields writes the fields in key order instead of the order they
+	// were added in, fields with the same key keep their order.
+	SortFields bool
+	// OmitEmpty skips the fields whose value is an empty string, nil or an
+	// empty slice or map.
+	OmitEmpty bool
+	Values    ValueFormat // rendering of durations, times, bytes and errors
+}
+
+func (f *JSONFormatter) timeOptions() timeOptions {
+	layout := f.TimeFormat
+	if layout == "" {
+		layout = time.RFC3339Nano
+	}
+	return timeOptions{location: f.Location, layout: layout, epoch: f.Epoch, precision: f.Precision}
+}
+
+func (f *JSONFormatter) Format(buf *bytes.Buffer, e *Entry) {
+	var arr [64]byte
+	buf.WriteByte('{')
+	appendJSONString(buf, defaultString(f.TimeKey, "time"))
+	buf.WriteByte(':')
+	buf.Write(f.timeOptions().appendTime(arr[:0], e.Time, true))
+	buf.WriteByte(',')
+	appendJSONString(buf, defaultString(f.LevelKey, "level"))
+	buf.WriteString(`:"`)
+	buf.WriteString(e.Level.String())
+	buf.WriteString(`",`)
+	appendJSONString(buf, defaultString(f.MessageKey, "msg"))
+	buf.WriteByte(':')
+	appendJSONString(buf, e.Message)
+	if e.File != "" {
+		buf.WriteString(`,"caller":`)
+		appendJSONString(buf, e.File+":"+strconv.Itoa(e.Line))
+	}
+	if len(e.Fields) > 0 {
+		mark := buf.Len()
+		buf.WriteByte(',')
+		if !f.Flatten {
+			buf.WriteString(`"fields":{`)
+		}
+		start := buf.Len()
+		fields := e.Fields
+		if f.SortFields {
+			// the pre-encoded fields are not in key order
+			fields = make([]Field, len(e.Fields))
+			copy(fields, e.Fields)
+			sort.SliceStable(fields, func(i, j int) bool { return fields[i].Key < fields[j].Key })
+		} else if e.Encoded != nil {
+			buf.Write(e.Encoded)
+			fields = fields[e.EncodedFields:]
+		}
+		f.EncodeFields(buf, fields)
+		switch {
+		case buf.Len() == start:
+			buf.Truncate(mark) // every field was omitted
+		case f.Flatten:
+			buf.Truncate(buf.Len() - 1) // trailing comma
+		default:
+			buf.Truncate(buf.Len() - 1)
+			buf.WriteByte('}')
+		}
+	}
+	buf.WriteString("}\n")
+}
+
+// EncodeFields writes `"key":value,` for every field, Format removes the
+// trailing comma.
+func (f *JSONFormatter) EncodeFields(buf *bytes.Buffer, fields []Field) {
+	var failed []string
+	for _, field := range fields {
+		if f.OmitEmpty && emptyField(field) {
+			continue
+		}
+		if f.Flatten && f.reserved(field.Key) {
+			appendJSONString(buf, "fields."+field.Key)
+		} else {
+			appendJSONString(buf, field.Key)
+		}
+		buf.WriteByte(':')
+		if err := appendJSONValue(buf, field, &f.Values); err != nil {
+			failed = append(failed, err.Error())
+		}
+		buf.WriteByte(',')
+	}
+	if len(failed) > 0 {
+		buf.WriteString(`"encoding_error":`)
+		appendJSONString(buf, strings.Join(failed, "; "))
+		buf.WriteByte(',')
+	}
+}
+
+// reserved reports whether key is one of the top level keys.
+func (f *JSONFormatter) reserved(key string) bool {
+	return key == defaultString(f.TimeKey, "time") || key == defaultString(f.LevelKey, "level") ||
+		key == defaultString(f.MessageKey, "msg") || key == "caller"
+}
+
+func emptyField(f Field) bool {
+	switch f.Type {
+	case StringType:
+		return f.Str == ""
+	case ErrorType:
+		return f.Interface == nil
+	case IntType, FloatType, BoolType, DurationType, TimeType:
+		return false
+	}
+	if f.Interface == nil {
+		return true
+	}
+	switch v := reflect.ValueOf(f.Interface); v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+// appendJSONValue writes the value of f. When it cannot be encoded, because
+// its MarshalJSON or Error method fails or panics, its fmt form is written
+// instead and the reason is returned.
+func appendJSONValue(buf *bytes.Buffer, f Field, v *ValueFormat) (err error) {
+	mark := buf.Len()
+	defer func() {
+		if r := recover(); r != nil {
+			buf.Truncate(mark)
+			appendJSONString(buf, sprintValue(f.Value()))
+			err = fmt.Errorf("%s: panic: %v", f.Key, r)
+		}
+	}()
+	var arr [64]byte
+	switch f.Type {
+	case StringType:
+		appendJSONString(buf, f.Str)
+	case IntType:
+		buf.Write(strconv.AppendInt(arr[:0], f.Integer, 10))
+	case FloatType:
+		n := math.Float64frombits(uint64(f.Integer))
+		if math.IsNaN(n) || math.IsInf(n, 0) {
+			// not representable as a JSON number
+			appendJSONString(buf, strconv.FormatFloat(n, 'g', -1, 64))
+			return nil
+		}
+		buf.Write(strconv.AppendFloat(arr[:0], n, 'g', -1, 64))
+	case BoolType:
+		buf.WriteString(strconv.FormatBool(f.Integer == 1))
+	case DurationType:
+		if b, number := v.appendDuration(arr[:0], time.Duration(f.Integer)); number {
+			buf.Write(b)
+		} else {
+			appendJSONString(buf, string(b))
+		}
+	case TimeType:
+		buf.WriteByte('"')
+		buf.Write(v.appendTime(arr[:0], f.time()))
+		buf.WriteByte('"')
+	case ErrorType:
+		fieldErr, _ := f.Interface.(error)
+		if v.ErrorMessage && fieldErr != nil {
+			appendJSONString(buf, fieldErr.Error())
+			return nil
+		}
+		appendJSONError(buf, fieldErr)
+	default:
+		if p, ok := f.Interface.([]byte); ok && p != nil {
+			appendJSONString(buf, v.bytesText(p))
+			return nil
+		}
+		// json.Marshal only gives up on cycles a thousand levels deep
+		if mayCycle(f.Interface) && cyclic(reflect.ValueOf(f.Interface), nil) {
+			appendJSONString(buf, cyclicText(f.Interface))
+			return nil
+		}
+		data, err := v.marshal(f.Interface)
+		if err != nil {
+			appendJSONString(buf, sprintValue(f.Interface))
+			return fmt.Errorf("%s: %v", f.Key, err)
+		}
+		buf.Write(data)
+	}
+	return nil
+}
+
+// appendJSONError renders err as an object with its message, type, the
+// chain of wrapped causes and the stack trace when one was recorded.
+func appendJSONError(buf *bytes.Buffer, err error) {
+	if err == nil {
+		buf.WriteString("null")
+		return
+	}
+	buf.WriteString(`{"message":`)
+	appendJSONString(buf, err.Error())
+	buf.WriteString(`,"type":`)
+	appendJSONString(buf, errorType(err))
+	if causes := errorCauses(err); len(causes) > 0 {
+		buf.WriteString(`,"causes":[`)
+		for i, cause := range causes {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(`{"message":`)
+			appendJSONString(buf, cause.Error())
+			buf.WriteString(`,"type":`)
+			appendJSONString(buf, errorType(cause))
+			buf.WriteByte('}')
+		}
+		buf.WriteByte(']')
+	}
+	if stack := errorStack(err); len(stack) > 0 {
+		buf.WriteString(`,"stack":[`)
+		for i, frame := range stack {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			appendJSONString(buf, frame)
+		}
+		buf.WriteByte(']')
+	}
+	buf.WriteByte('}')
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSONString writes s as a JSON string, replacing invalid UTF-8.
+func appendJSONString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+			buf.WriteString(s[start:i])
+			switch c {
+			case '"', '\\':
+				buf.WriteByte('\\')
+				buf.WriteByte(c)
+			case '\n':
+				buf.WriteString(`\n`)
+			case '\r':
+				buf.WriteString(`\r`)
+			case '\t':
+				buf.WriteString(`\t`)
+			default:
+				buf.WriteString(`\u00`)
+				buf.WriteByte(hexDigits[c>>4])
+				buf.WriteByte(hexDigits[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf.WriteString(s[start:i])
+			buf.WriteString(`\ufffd`)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			buf.WriteString(s[start:i])
+			buf.WriteString(`\u202`)
+			buf.WriteByte(hexDigits[r&0xf])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	buf.WriteString(s[start:])
+	buf.WriteByte('"')
+}
@@ -0,0 +1,423 @@
+package spoor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONFormatter(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewSpoor(DEBUG, "", 0, WithConsoleWriter(&buf), WithFormatter(&JSONFormatter{})).With(String("service", "a\"b\n"))
+	err := fmt.Errorf("open config: %w", WithStack(&fs.PathError{Op: "open", Path: "x", Err: fs.ErrNotExist}))
+	l.Error("failed", Err(err), Float64("nan", math.NaN()), Dur("took", time.Second))
+
+	var got struct {
+		Level  string
+		Msg    string
+		Fields struct {
+			Service string
+			NaN     string
+			Took    string
+			Error   struct {
+				Message string
+				Type    string
+				Causes  []struct{ Message, Type string }
+				Stack   []string
+			}
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	if got.Level != "ERROR" || got.Msg != "failed" || got.Fields.Service != "a\"b\n" || got.Fields.NaN != "NaN" || got.Fields.Took != "1s" {
+		t.Fatalf("unexpected entry %+v", got)
+	}
+	e := got.Fields.Error
+	if e.Type != "*fmt.wrapError" || len(e.Causes) != 2 || e.Causes[0].Type != "*fs.PathError" || e.Causes[1].Message != fs.ErrNotExist.Error() {
+		t.Fatalf("unexpected error %+v", e)
+	}
+	if len(e.Stack) == 0 || !strings.Contains(e.Stack[0], "TestJSONFormatter") {
+		t.Fatalf("missing stack %v", e.Stack)
+	}
+	if lvl, ok := lineLevel(buf.Bytes()); !ok || lvl != ERROR {
+		t.Fatalf("lineLevel = %v, %v", lvl, ok)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatal("WithStack broke the error chain")
+	}
+}
+
+func TestTimeOptions(t *testing.T) {
+	ts := time.Date(2021, 3, 4, 5, 6, 7, 891234567, time.UTC)
+	tokyo := time.FixedZone("JST", 9*3600)
+	cases := []struct {
+		f    Formatter
+		want string
+	}{
+		{&JSONFormatter{Epoch: EpochMillis}, `{"time":1614834367891,`},
+		{&JSONFormatter{Location: tokyo, Precision: time.Millisecond}, `{"time":"2021-03-04T14:06:07.891+09:00",`},
+		{&TextFormatter{TimeFormat: time.RFC3339, Location: tokyo}, `2021-03-04T14:06:07+09:00 INFO`},
+		{&TextFormatter{Epoch: EpochNanos}, `1614834367891234567 INFO`},
+	}
+	for _, c := range cases {
+		var buf bytes.Buffer
+		c.f.Format(&buf, &Entry{Time: ts, Level: INFO})
+		if !strings.HasPrefix(buf.String(), c.want) {
+			t.Errorf("got %q, want prefix %q", buf.String(), c.want)
+		}
+	}
+}
+
+func TestJSONFormatterOptions(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewSpoor(DEBUG, "", 0, WithConsoleWriter(&buf), WithClock(fixedClock(time.Unix(0, 0).UTC())),
+		WithFormatter(&JSONFormatter{TimeKey: "@timestamp", MessageKey: "message", Flatten: true, SortFields: true, OmitEmpty: true})).With(String("z", "1"), String("empty", ""))
+	l.Info("m", String("message", "x"), Any("tags", nil), Int("a", 0))
+	want := `{"@timestamp":"1970-01-01T00:00:00Z","level":"INFO","message":"m","a":0,"fields.message":"x","z":"1"}` + "\n"
+	if buf.String() != want {
+		t.Fatalf("got %q, want %q", buf.String(), want)
+	}
+	buf.Reset()
+	l.Info("m", String("empty", ""))
+	if !strings.HasSuffix(buf.String(), `"message":"m","z":"1"}`+"\n") {
+		t.Fatalf("got %q", buf.String())
+	}
+}
+
+func TestECSFormatter(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewSpoor(DEBUG, "", 0, WithConsoleWriter(&buf), WithClock(fixedClock(time.Unix(1, 5).UTC())),
+		WithFormatter(&ECSFormatter{ServiceName: "api", KeyMap: map[string]string{"user": "user.name"}}))
+	l.Error("failed", String("trace_id", "abc"), String("user", "bob"), Err(errors.New("boom")))
+	want := `{"@timestamp":"1970-01-01T00:00:01.000000005Z","log.level":"error","message":"failed","ecs.version":"` + ecsVersion +
+		`","service.name":"api","trace.id":"abc","user.name":"bob","error.message":"boom","error.type":"*errors.errorString"}` + "\n"
+	if buf.String() != want {
+		t.Fatalf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestParseJSONEntry(t *testing.T) {
+	var buf bytes.Buffer
+	ts := time.Date(2021, 2, 3, 4, 5, 6, 7, time.UTC)
+	l := NewSpoor(DEBUG, "", 0, WithConsoleWriter(&buf), WithFormatter(&JSONFormatter{}), WithClock(fixedClock(ts)))
+	l.Warn("slow", String("path", "/a"), Int("ms", 12), Float64("ratio", 0.5), Bool("ok", false), Any("tags", []string{"x"}))
+	e, err := ParseJSONEntry(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !e.Time.Equal(ts) || e.Level != WARN || e.Message != "slow" || len(e.Fields) != 5 {
+		t.Fatalf("got %+v", e)
+	}
+	var out bytes.Buffer
+	(&JSONFormatter{}).Format(&out, &e)
+	if out.String() != buf.String() {
+		t.Fatalf("got %q, want %q", out.String(), buf.String())
+	}
+	e, err = ParseJSONEntry([]byte(`{"time":1612325106,"severity":"error","message":"m","user":"u"}`))
+	if err != nil || e.Time.Unix() != 1612325106 || e.Level != ERROR || e.Message != "m" || e.Fields[0].Str != "u" {
+		t.Fatalf("got %+v, %v", e, err)
+	}
+	if _, err := ParseJSONEntry([]byte("INFO text")); err == nil {
+		t.Fatal("text line parsed")
+	}
+}
+
+func fuzzEntry(msg, key, str string, num float64, raw []byte) *Entry {
+	cycle := map[string]interface{}{"k": str}
+	cycle["self"] = cycle
+	list := []interface{}{raw}
+	list = append(list, list)
+	return &Entry{Level: ERROR, Message: msg, Fields: []Field{
+		String(key, str), Float64("num", num), Any("raw", raw), Any("map", map[string]interface{}{key: num}),
+		Any("cycle", cycle), Any("list", list), Err(errors.New(str)), Any(key, struct{ S string }{str}),
+	}}
+}
+
+var fuzzSeeds = []struct {
+	msg, key, str string
+	num           float64
+	raw           []byte
+}{
+	{"ok", "k", "v", 1, []byte("x")},
+	{"new\nline", "a b=c", "\x00\x1b[31m\"\\", math.NaN(), []byte{0xff, 0xfe}},
+	{" ", "", strings.Repeat("long ", 1000), math.Inf(-1), nil},
+}
+
+func FuzzJSONFormatter(f *testing.F) {
+	for _, s := range fuzzSeeds {
+		f.Add(s.msg, s.key, s.str, s.num, s.raw)
+	}
+	f.Fuzz(func(t *testing.T, msg, key, str string, num float64, raw []byte) {
+		var buf bytes.Buffer
+		(&JSONFormatter{}).Format(&buf, fuzzEntry(msg, key, str, num, raw))
+		if !json.Valid(buf.Bytes()) || bytes.IndexByte(buf.Bytes(), '\n') != buf.Len()-1 {
+			t.Fatalf("not a single JSON line: %q", buf.String())
+		}
+	})
+}
+
+func FuzzTextFormatter(f *testing.F) {
+	for _, s := range fuzzSeeds {
+		f.Add(s.msg, s.key, s.str, s.num, s.raw)
+	}
+	f.Fuzz(func(t *testing.T, msg, key, str string, num float64, raw []byte) {
+		var buf bytes.Buffer
+		(&TextFormatter{Flag: log.LstdFlags}).Format(&buf, fuzzEntry(msg, key, str, num, raw))
+		if n := bytes.Count(buf.Bytes(), []byte{'\n'}); n != 1 || buf.Bytes()[buf.Len()-1] != '\n' {
+			t.Fatalf("entry spans several lines: %q", buf.String())
+		}
+	})
+}
+
+func TestFormatterSelector(t *testing.T) {
+	var buf bytes.Buffer
+	text := &TextFormatter{}
+	l := NewSpoor(DEBUG, "", 0, WithConsoleWriter(&buf), WithFormatter(FormatterSelector(func(e *Entry) Formatter {
+		if e.Level >= ERROR {
+			return text
+		}
+		return &JSONFormatter{}
+	})))
+	l = l.With(String("k", "v"))
+	l.Info("structured")
+	l.Error("plain")
+	want := `"msg":"structured","fields":{"k":"v"}}` + "\nERROR plain k=v\n"
+	if got := buf.String(); !strings.HasSuffix(got, want) {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestTextMultiline(t *testing.T) {
+	msg := "panic: boom\r\ngoroutine 1:\n\tmain.go:10\n"
+	for _, c := range []struct {
+		f    TextFormatter
+		want string
+	}{
+		{TextFormatter{Multiline: MultilineRaw}, "ERROR panic: boom\r\ngoroutine 1:\n\tmain.go:10\n k=\"a\\nb\"\n"},
+		{TextFormatter{}, `ERROR panic: boom\r\ngoroutine 1:\n` + "\tmain.go:10" + `\n k="a\nb"` + "\n"},
+		{TextFormatter{Multiline: MultilineIndent, Continuation: "  | "}, "ERROR panic: boom\n  | goroutine 1:\n  | \tmain.go:10 k=\"a\\nb\"\n"},
+	} {
+		var buf bytes.Buffer
+		c.f.Format(&buf, &Entry{Level: ERROR, Message: msg, Fields: []Field{String("k", "a\nb")}})
+		if buf.String() != c.want {
+			t.Errorf("mode %d: got %q, want %q", c.f.Multiline, buf.String(), c.want)
+		}
+	}
+}
+
+func TestValueFormat(t *testing.T) {
+	e := &Entry{Level: INFO, Message: "m", Fields: []Field{
+		Dur("took", 1500*time.Microsecond),
+		Time("at", time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)),
+		Any("body", []byte("hello world")),
+		Err(errors.New("boom")),
+	}}
+	values := ValueFormat{Duration: DurationMillis, TimeLayout: time.RFC3339, Bytes: BytesHex, MaxBytes: 5, ErrorMessage: true}
+	var buf bytes.Buffer
+	(&JSONFormatter{Values: values}).Format(&buf, e)
+	want := `"fields":{"took":1.5,"at":"2024-05-01T12:00:00Z","body":"68656c6c6f...(11 bytes)","error":"boom"}}` + "\n"
+	if !strings.HasSuffix(buf.String(), want) {
+		t.Fatalf("got %s", buf.String())
+	}
+	buf.Reset()
+	(&TextFormatter{Values: ValueFormat{Duration: DurationNanos}}).Format(&buf, e)
+	if want := "INFO m took=1500000 at=2024-05-01T12:00:00Z body=\"aGVsbG8gd29ybGQ=\" error=boom\n"; buf.String() != want {
+		t.Fatalf("got %q, want %q", buf.String(), want)
+	}
+}
+
+type panicMarshaler struct{}
+
+func (panicMarshaler) MarshalJSON() ([]byte, error) { panic("boom") }
+
+func (panicMarshaler) String() string { return "panicky" }
+
+type failMarshaler struct{}
+
+func (failMarshaler) MarshalJSON() ([]byte, error) { return nil, errors.New("no") }
+
+type panicError struct{}
+
+func (*panicError) Error() string { panic("no message") }
+
+func TestEncodingErrors(t *testing.T) {
+	e := &Entry{Level: INFO, Message: "m", Fields: []Field{
+		Any("a", panicMarshaler{}), Any("b", failMarshaler{}), Int("c", 1), NamedErr("d", (*panicError)(nil)),
+	}}
+	var buf bytes.Buffer
+	(&JSONFormatter{}).Format(&buf, e)
+	var out struct{ Fields map[string]interface{} }
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("%v: %s", err, buf.String())
+	}
+	if out.Fields["a"] != "panicky" || out.Fields["c"] != 1.0 ||
+		!strings.HasPrefix(out.Fields["encoding_error"].(string), "a: panic: boom; b: json: error calling MarshalJSON") ||
+		!strings.HasSuffix(out.Fields["encoding_error"].(string), "; d: panic: no message") {
+		t.Fatalf("got %s", buf.String())
+	}
+	buf.Reset()
+	(&TextFormatter{}).Format(&buf, e)
+	if got := buf.String(); !strings.Contains(got, " c=1 ") || !strings.HasSuffix(got, ` encoding_error="d: panic: no message"`+"\n") {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestValueExpansion(t *testing.T) {
+	type user struct {
+		Name  string            `json:"name"`
+		Tags  []string          `json:"tags"`
+		Attrs map[string]string `json:"attrs"`
+		Boss  *user             `json:"boss,omitempty"`
+	}
+	u := user{Name: "ann", Tags: []string{"a", "b", "c"}, Attrs: map[string]string{"x": "1", "y": "2", "z": "3"},
+		Boss: &user{Name: "bob"}}
+	e := &Entry{Level: INFO, Message: "m", Fields: []Field{Any("user", u)}}
+	values := ValueFormat{Expand: true, MaxDepth: 2, MaxElements: 2}
+	var buf bytes.Buffer
+	(&JSONFormatter{Values: values}).Format(&buf, e)
+	want := `"fields":{"user":{"...":2,"attrs":{"...":1,"x":"1","y":"2"},"boss":{"...":1,"attrs":null,"name":"bob"}}}}` + "\n"
+	if !strings.HasSuffix(buf.String(), want) {
+		t.Fatalf("got %s", buf.String())
+	}
+	buf.Reset()
+	(&JSONFormatter{Values: ValueFormat{MaxDepth: 1}}).Format(&buf, e)
+	if want := `{"attrs":"...","boss":"...","name":"ann","tags":"..."}}}` + "\n"; !strings.HasSuffix(buf.String(), want) {
+		t.Fatalf("got %s", buf.String())
+	}
+	buf.Reset()
+	(&TextFormatter{Values: ValueFormat{Expand: true}}).Format(&buf, &Entry{Level: INFO, Message: "m", Fields: []Field{Any("tags", u.Tags)}})
+	if want := `INFO m tags="[\"a\",\"b\",\"c\"]"` + "\n"; buf.String() != want {
+		t.Fatalf("got %q, want %q", buf.String(), want)
+	}
+}
+
+type countedValue struct{ n *int }
+
+func (c countedValue) MarshalJSON() ([]byte, error) {
+	*c.n++
+	return []byte("1"), nil
+}
+
+func TestValueExpansionBounds(t *testing.T) {
+	encoded := 0
+	values := make([]countedValue, 10000)
+	for i := range values {
+		values[i] = countedValue{&encoded}
+	}
+	type node struct {
+		Value countedValue `json:"value"`
+		Next  *node        `json:"next,omitempty"`
+	}
+	deep := &node{Value: countedValue{&encoded}}
+	for i := 0; i < 100; i++ {
+		deep = &node{Value: countedValue{&encoded}, Next: deep}
+	}
+	e := &Entry{Level: INFO, Message: "m", Fields: []Field{Any("values", values), Any("deep", deep)}}
+	var buf bytes.Buffer
+	(&JSONFormatter{Values: ValueFormat{MaxDepth: 2, MaxElements: 3}}).Format(&buf, e)
+	want := `"fields":{"values":[1,1,1,"...(9997 more)"],"deep":{"next":{"next":"...","value":1},"value":1}}}` + "\n"
+	if !strings.HasSuffix(buf.String(), want) {
+		t.Fatalf("got %s", buf.String())
+	}
+	if encoded != 5 {
+		t.Fatalf("%d values encoded, want the 5 kept", encoded)
+	}
+
+	// a cycle through pointers is reported like json.Marshal does
+	cycle := &node{}
+	cycle.Next = cycle
+	buf.Reset()
+	(&JSONFormatter{Values: ValueFormat{MaxElements: 3}}).Format(&buf, &Entry{Level: INFO, Message: "m", Fields: []Field{Any("cycle", cycle)}})
+	if !strings.Contains(buf.String(), "encoding_error") {
+		t.Fatalf("got %s", buf.String())
+	}
+}
+
+func TestSIEMFormatters(t *testing.T) {
+	e := &Entry{
+		Time:    time.Date(2021, 2, 3, 4, 5, 6, 7e6, time.UTC),
+		Level:   WARN,
+		Message: "login|failed\\n\nagain",
+		Fields:  []Field{String("user", "a=b\\c\nd"), String("event", "4625"), Int("bad key!", 1)},
+	}
+	var buf bytes.Buffer
+	(&CEFFormatter{Vendor: "Ac|me", Product: `Gate\way`, Version: "1.0", SignatureKey: "event", KeyMap: map[string]string{"user": "suser"}}).Format(&buf, e)
+	want := `CEF:0|Ac\|me|Gate\\way|1.0|4625|login\|failed\\n again|5|rt=1612325106007 suser=a\=b\\c\nd badkey=1` + "\n"
+	if buf.String() != want {
+		t.Errorf("CEF got %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	(&LEEFFormatter{Vendor: "Ac|me", Product: "Gate\tway", Version: "1.0", EventIDKey: "event"}).Format(&buf, e)
+	want = "LEEF:2.0|Ac\\|me|Gate way|1.0|4625|x09|devTime=Feb 03 2021 04:05:06.007 UTC\tdevTimeFormat=MMM dd yyyy HH:mm:ss.SSS z" +
+		"\tsev=5\tmsg=login|failed\\n again\tuser=a=b\\c d\tbadkey=1\n"
+	if buf.String() != want {
+		t.Errorf("LEEF got %q, want %q", buf.String(), want)
+	}
+
+	for level, sev := range map[Level]string{DEBUG: "|1|", INFO: "|3|", WARN: "|5|", ERROR: "|8|", FATAL: "|10|"} {
+		buf.Reset()
+		(&CEFFormatter{}).Format(&buf, &Entry{Level: level, Message: "m"})
+		if !strings.Contains(buf.String(), "|"+level.String()+"|m"+sev) {
+			t.Errorf("%v: got %q, want severity %s", level, buf.String(), sev)
+		}
+	}
+}
+
+func TestDevFormatter(t *testing.T) {
+	at := time.Date(2021, 2, 3, 4, 5, 6, 7e6, time.UTC)
+	e := &Entry{
+		Time:    at,
+		Level:   ERROR,
+		File:    "/src/app/handlers/user.go",
+		Line:    42,
+		Message: "save failed\nretrying",
+		Fields:  []Field{String("user", "bob"), String("dump", "a\nb"), Err(fmt.Errorf("save: %w", errors.New("disk full")))},
+	}
+	var buf bytes.Buffer
+	(&DevFormatter{}).Format(&buf, e)
+	want := "04:05:06.007 ERROR   handlers/user.go:42      save failed\nretrying\n" +
+		"    user: bob\n" +
+		"    dump: a\n      b\n" +
+		"    error: save: disk full\n      caused by: disk full (*errors.errorString)\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	(&DevFormatter{Color: true}).Format(&buf, e)
+	want = "\x1b[90m04:05:06.007\x1b[0m \x1b[31mERROR\x1b[0m   \x1b[90mhandlers/user.go:42\x1b[0m      \x1b[1msave failed\nretrying\x1b[0m\n" +
+		"    \x1b[34muser\x1b[0m: bob\n" +
+		"    \x1b[34mdump\x1b[0m: a\n      b\n" +
+		"    \x1b[34merror\x1b[0m: \x1b[31msave: disk full\x1b[0m\n      caused by: disk full (*errors.errorString)\n"
+	if buf.String() != want {
+		t.Errorf("colored got %q, want %q", buf.String(), want)
+	}
+
+	// the caller column is padded to CallerWidth and left as is when longer
+	for _, tt := range []struct {
+		width int
+		file  string
+		want  string
+	}{
+		{0, "main.go", "04:05:06.007 INFO    main.go:7                ready\n"},
+		{12, "/src/cmd/main.go", "04:05:06.007 INFO    cmd/main.go:7 ready\n"},
+		{16, "main.go", "04:05:06.007 INFO    main.go:7        ready\n"},
+		{12, "/src/app/handlers/user.go", "04:05:06.007 INFO    handlers/user.go:7 ready\n"},
+		{0, "", "04:05:06.007 INFO    ready\n"},
+	} {
+		buf.Reset()
+		(&DevFormatter{CallerWidth: tt.width}).Format(&buf, &Entry{Time: at, Level: INFO, File: tt.file, Line: 7, Message: "ready"})
+		if buf.String() != tt.want {
+			t.Errorf("width %d, file %q: got %q, want %q", tt.width, tt.file, buf.String(), tt.want)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package spoor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseJSONEntry parses a line written by JSONFormatter back into an entry,
+// keeping the order of the fields, so that logs can be filtered, formatted
+// again or replayed. Keys other than the time, level, severity, msg, caller
+// and fields ones are added as fields. Objects and arrays are kept as raw
+// JSON values.
+func ParseJSONEntry(line []byte) (Entry, error) {
+	var e Entry
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return e, errors.New("spoor: not a JSON object")
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return e, err
+		}
+		key, _ := tok.(string)
+		var raw json.RawMessage
+		if key == "fields" {
+			if e.Fields, err = parseJSONFields(dec, e.Fields); err != nil {
+				return e, err
+			}
+			continue
+		}
+		if err := dec.Decode(&raw); err != nil {
+			return e, err
+		}
+		var s string
+		switch key {
+		case "time", "timestamp":
+			if e.Time, err = parseJSONTime(raw); err != nil {
+				return e, err
+			}
+			continue
+		case "level", "severity":
+			json.Unmarshal(raw, &s)
+			if e.Level, err = parseLevelName(s); err != nil {
+				return e, err
+			}
+			continue
+		case "msg", "message":
+			if json.Unmarshal(raw, &s) == nil {
+				e.Message = s
+				continue
+			}
+		case "caller":
+			if json.Unmarshal(raw, &s) == nil {
+				if i := strings.LastIndexByte(s, ':'); i > 0 {
+					e.File = s[:i]
+					e.Line, _ = strconv.Atoi(s[i+1:])
+				}
+				continue
+			}
+		}
+		e.Fields = append(e.Fields, jsonField(key, raw))
+	}
+	return e, nil
+}
+
+func parseJSONFields(dec *json.Decoder, fields []Field) ([]Field, error) {
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return fields, errors.New("spoor: fields is not a JSON object")
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return fields, err
+		}
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return fields, err
+		}
+		fields = append(fields, jsonField(tok.(string), raw))
+	}
+	_, err := dec.Token()
+	return fields, err
+}
+
+// rawJSON is a JSON object or array, written as is by the formatters.
+type rawJSON json.RawMessage
+
+func (r rawJSON) String() string { return string(r) }
+
+func (r rawJSON) MarshalJSON() ([]byte, error) { return r, nil }
+
+func jsonField(key string, raw json.RawMessage) Field {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v interface{}
+	dec.Decode(&v)
+	switch v := v.(type) {
+	case string:
+		return String(key, v)
+	case bool:
+		return Bool(key, v)
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return Int64(key, n)
+		}
+		f, _ := v.Float64()
+		return Float64(key, f)
+	case nil:
+		return Any(key, nil)
+	}
+	return Any(key, rawJSON(raw))
+}
+
+// lineTime returns the timestamp of a JSON line, for the writers which send
+// one with every entry.
+func lineTime(line []byte) (time.Time, bool) {
+	if len(line) == 0 || line[0] != '{' {
+		return time.Time{}, false
+	}
+	var v struct {
+		Time      json.RawMessage `json:"time"`
+		Timestamp json.RawMessage `json:"timestamp"`
+	}
+	if json.Unmarshal(line, &v) != nil {
+		return time.Time{}, false
+	}
+	raw := v.Time
+	if raw == nil {
+		raw = v.Timestamp
+	}
+	if raw == nil {
+		return time.Time{}, false
+	}
+	t, err := parseJSONTime(raw)
+	return t, err == nil
+}
+
+// parseJSONTime parses a formatted time or an epoch number, whose unit is
+// guessed from its magnitude.
+func parseJSONTime(raw json.RawMessage) (time.Time, error) {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return time.Parse(time.RFC3339Nano, s)
+	}
+	var num json.Number
+	if err := json.Unmarshal(raw, &num); err != nil {
+		return time.Time{}, fmt.Errorf("spoor: invalid time %s", raw)
+	}
+	n, err := num.Int64()
+	if err != nil {
+		f, err := num.Float64()
+		sec, frac := math.Modf(f)
+		return time.Unix(int64(sec), int64(frac*1e9)), err
+	}
+	switch {
+	case n < 1e11:
+		return time.Unix(n, 0), nil
+	case n < 1e14:
+		return time.UnixMilli(n), nil
+	}
+	return time.Unix(0, n), nil
+}
+
+// parseLevelName parses the level names written by the formatters.
+func parseLevelName(s string) (Level, error) {
+	for lvl := DEBUG; lvl <= FATAL; lvl++ {
+		if strings.EqualFold(s, lvl.String()) {
+			return lvl, nil
+		}
+	}
+	return ParseLogLevel(s)
+}
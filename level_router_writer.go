@@ -0,0 +1,70 @@
+package spoor
+
+import (
+	"io"
+	"os"
+)
+
+// LevelRouterWriter sends every entry to the writer of its level, e.g. the
+// debug and info entries to stdout and the others to stderr, as container
+// platforms and command line tools expect:
+//
+//	l := spoor.NewSpoor(spoor.DEBUG, "", 0, spoor.WithConsoleWriter(spoor.NewStdRouter()))
+//
+// The level is the entry's when the logger writes to it directly, it is
+// found with the level names of the line otherwise; lines without one are
+// routed as INFO.
+type LevelRouterWriter struct {
+	routes [FATAL + 1]io.Writer
+}
+
+// NewLevelRouterWriter returns a LevelRouterWriter with routes, the entries of
+// the levels without a route are dropped.
+func NewLevelRouterWriter(routes map[Level]io.Writer) *LevelRouterWriter {
+	w := &LevelRouterWriter{}
+	for level, out := range routes {
+		if level >= DEBUG && level <= FATAL {
+			w.routes[level] = out
+		}
+	}
+	return w
+}
+
+// NewStdRouter routes DEBUG and INFO to stdout and the levels above to
+// stderr.
+func NewStdRouter() *LevelRouterWriter {
+	return NewLevelRouterWriter(map[Level]io.Writer{
+		DEBUG: os.Stdout, INFO: os.Stdout, WARN: os.Stderr, ERROR: os.Stderr, FATAL: os.Stderr,
+	})
+}
+
+func (w *LevelRouterWriter) Write(p []byte) (int, error) {
+	level, ok := lineLevel(p)
+	if !ok {
+		level = INFO
+	}
+	return w.WriteLevel(level, p)
+}
+
+func (w *LevelRouterWriter) WriteLevel(level Level, p []byte) (int, error) {
+	if level < DEBUG || level > FATAL || w.routes[level] == nil {
+		return len(p), nil
+	}
+	return w.routes[level].Write(p)
+}
+
+// Flush flushes the writers of the routes, see Spoor.Sync.
+func (w *LevelRouterWriter) Flush() error {
+	var first error
+	seen := make(map[io.Writer]bool)
+	for _, out := range w.routes {
+		if out == nil || seen[out] {
+			continue
+		}
+		seen[out] = true
+		if err := flushWriter(out); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
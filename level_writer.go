@@ -0,0 +1,64 @@
+package spoor
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+)
+
+// WriterLevel returns a writer logging every line written to it at level,
+// e.g. to capture the output of a subprocess:
+//
+//	w := l.WriterLevel(spoor.WARN)
+//	defer w.Close()
+//	cmd.Stderr = w
+//
+// An incomplete last line is kept until the next write or Close.
+func (l *Spoor) WriterLevel(level Level) io.WriteCloser {
+	return &levelWriter{l: l, level: level}
+}
+
+type levelWriter struct {
+	mu    sync.Mutex
+	l     *Spoor
+	level Level
+	buf   []byte
+}
+
+func (w *levelWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.emit(w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+	if len(w.buf) == 0 {
+		w.buf = nil
+	}
+	return len(p), nil
+}
+
+// Close logs the incomplete last line, if any.
+func (w *levelWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) > 0 {
+		w.emit(w.buf)
+		w.buf = nil
+	}
+	return nil
+}
+
+func (w *levelWriter) emit(line []byte) {
+	msg := strings.TrimSuffix(string(line), "\r")
+	if msg == "" {
+		return
+	}
+	w.l.log(3, w.level, msg, nil) // the caller of Write or Close
+}
@@ -0,0 +1,126 @@
+package spoor
+
+import (
+	"bytes"
+	"strconv"
+	"unicode/utf8"
+)
+
+// TruncatedMarker is appended to the values shortened by Limits.
+const TruncatedMarker = "...[truncated]"
+
+// Limits caps the size of entries, e.g. to protect Elasticsearch from
+// accidental multi-megabyte payloads. Zero means no limit.
+type Limits struct {
+	MaxMessage int // bytes of the message
+	// MaxFieldValue is the size in bytes of a string, error, byte slice or
+	// Any value. The values which are not strings are measured by their text
+	// and replaced by their truncated text when longer.
+	MaxFieldValue int
+	// MaxEntry is the size in bytes of the formatted entry. A larger entry is
+	// written without its fields and with a truncated field telling its
+	// original size, and its message is shortened if needed. When the header
+	// alone is larger, the entry is written without message nor fields rather
+	// than cut, which would break formats like JSON.
+	MaxEntry int
+}
+
+// WithLimits sets the limits applied to the entries of the logger.
+func WithLimits(limits Limits) Option {
+	return func(spoor *Spoor) {
+		spoor.limits = limits
+	}
+}
+
+// apply shortens the message and the values of e.
+func (lim *Limits) apply(e *Entry) {
+	if lim.MaxMessage > 0 && len(e.Message) > lim.MaxMessage {
+		e.Message = truncateBytes(e.Message, lim.MaxMessage)
+	}
+	if lim.MaxFieldValue <= 0 {
+		return
+	}
+	copied := false
+	for i, f := range e.Fields {
+		text, ok := valueText(f)
+		if !ok || len(text) <= lim.MaxFieldValue {
+			continue
+		}
+		if !copied {
+			e.Fields = append([]Field(nil), e.Fields...)
+			copied = true
+		}
+		e.Fields[i] = String(f.Key, truncateBytes(text, lim.MaxFieldValue))
+		if i < e.EncodedFields {
+			e.Encoded, e.EncodedFields = nil, 0
+		}
+	}
+}
+
+// format formats e into buf, keeping it within MaxEntry.
+func (lim *Limits) format(f Formatter, buf *bytes.Buffer, e *Entry) {
+	f.Format(buf, e)
+	if lim.MaxEntry <= 0 || buf.Len() <= lim.MaxEntry {
+		return
+	}
+	size := buf.Len()
+	short := *e
+	short.Fields = []Field{String("truncated", strconv.Itoa(size)+" bytes")}
+	short.Encoded, short.EncodedFields = nil, 0
+	buf.Reset()
+	f.Format(buf, &short)
+	if excess := buf.Len() - lim.MaxEntry; excess > 0 {
+		if n := len(short.Message) - excess; n >= len(TruncatedMarker) {
+			short.Message = truncateBytes(short.Message, n)
+		} else {
+			// the header alone is too long
+			short.Message, short.Fields = "", nil
+		}
+		buf.Reset()
+		f.Format(buf, &short)
+	}
+}
+
+// valueText returns the text of the string, error, byte slice and Any values,
+// which are the ones which may be long or hold secrets.
+func valueText(f Field) (string, bool) {
+	switch f.Type {
+	case StringType:
+		return f.Str, true
+	case ErrorType:
+		if err, ok := f.Interface.(error); ok && err != nil {
+			return err.Error(), true
+		}
+	case AnyType:
+		switch v := f.Interface.(type) {
+		case nil:
+		case []byte:
+			return string(v), true
+		case string:
+			return v, true
+		default:
+			return sprintValue(v), true
+		}
+	}
+	return "", false
+}
+
+// truncateBytes cuts s to at most n bytes on a rune boundary, marker included.
+func truncateBytes(s string, n int) string {
+	n -= len(TruncatedMarker)
+	if n < 0 {
+		n = 0
+	}
+	return s[:runeBoundary(s, n)] + TruncatedMarker
+}
+
+// runeBoundary returns the largest length up to n cutting s between runes.
+func runeBoundary(s string, n int) int {
+	if n >= len(s) {
+		return len(s)
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return n
+}
@@ -1,6 +1,7 @@
 package spoor
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"strings"
@@ -12,6 +13,8 @@ const (
 	WARN  = Level(3)
 	ERROR = Level(4)
 	FATAL = Level(5)
+	// Disabled is above every level, a logger at Disabled writes nothing.
+	Disabled = Level(6)
 )
 
 type AppLogFunc func(lvl Level, f string, args ...interface{})
@@ -46,6 +49,8 @@ func (l Level) String() string {
 		return "ERROR"
 	case FATAL:
 		return "FATAL"
+	case Disabled:
+		return "DISABLED"
 	}
 	return "invalid"
 }
@@ -62,6 +67,45 @@ func ParseLogLevel(levelStr string) (Level, error) {
 		return ERROR, nil
 	case "fatal":
 		return FATAL, nil
+	case "disabled", "off":
+		return Disabled, nil
 	}
-	return 0, fmt.Errorf("invalid log level '%s' (debug, info, warn, error, fatal)", levelStr)
+	return 0, fmt.Errorf("invalid log level '%s' (debug, info, warn, error, fatal, disabled)", levelStr)
+}
+
+// lineLevel finds the level of a formatted line: the "level" or "severity" key
+// of JSON lines, otherwise the first word naming a level.
+func lineLevel(p []byte) (Level, bool) {
+	if bytes.HasPrefix(bytes.TrimSpace(p), []byte("{")) {
+		for _, key := range []string{`"level":"`, `"severity":"`} {
+			i := bytes.Index(p, []byte(key))
+			if i < 0 {
+				continue
+			}
+			word := p[i+len(key):]
+			if end := bytes.IndexByte(word, '"'); end >= 0 {
+				word = word[:end]
+			}
+			for lvl := DEBUG; lvl <= FATAL; lvl++ {
+				if string(word) == lvl.String() {
+					return lvl, true
+				}
+			}
+			return 0, false
+		}
+	}
+	for len(p) > 0 {
+		p = bytes.TrimLeft(p, " \t\r\n")
+		end := bytes.IndexAny(p, " \t\r\n")
+		if end < 0 {
+			end = len(p)
+		}
+		for lvl := DEBUG; lvl <= FATAL; lvl++ {
+			if string(p[:end]) == lvl.String() {
+				return lvl, true
+			}
+		}
+		p = p[end:]
+	}
+	return 0, false
 }
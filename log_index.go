@@ -0,0 +1,251 @@
+package spoor
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// IndexSuffix is appended to the name of a log file to name its index.
+const IndexSuffix = ".idx"
+
+// indexBloomBits is the size of the bloom filter of the field values, 8KB
+// keeps false positives rare up to about 10000 distinct values per file.
+const indexBloomBits = 8 * 8192
+
+// LogIndex summarizes a JSON log file so that QueryLogs can skip it without
+// reading it.
+type LogIndex struct {
+	From    time.Time      `json:"from"`
+	To      time.Time      `json:"to"`
+	Entries int            `json:"entries"`
+	Levels  map[string]int `json:"levels"` // entries per level name
+	// Fields is a bloom filter of the key=value pairs of the string, int and
+	// bool fields.
+	Fields []byte `json:"fields"`
+}
+
+// WithIndex writes a LogIndex next to every file the writer rotated away,
+// built in the background, for QueryLogs. It is only useful with
+// JSONFormatter.
+func WithIndex() FileOption {
+	return func(fw *FileWriter) {
+		fw.index = true
+	}
+}
+
+func indexRotated(path string) {
+	if _, err := BuildIndex(path); err != nil {
+		internalError(fmt.Errorf("spoor: index %s: %w", path, err))
+	}
+}
+
+// BuildIndex reads the log file path and writes its index to path+IndexSuffix.
+func BuildIndex(path string) (*LogIndex, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	idx := &LogIndex{Levels: make(map[string]int), Fields: make([]byte, indexBloomBits/8)}
+	err = scanEntries(f, func(e *Entry) bool {
+		if idx.Entries == 0 || e.Time.Before(idx.From) {
+			idx.From = e.Time
+		}
+		if e.Time.After(idx.To) {
+			idx.To = e.Time
+		}
+		idx.Entries++
+		idx.Levels[e.Level.String()]++
+		for _, field := range e.Fields {
+			if value, ok := indexValue(field); ok {
+				bloomAdd(idx.Fields, field.Key+"="+value)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return nil, err
+	}
+	return idx, os.WriteFile(path+IndexSuffix, data, 0666)
+}
+
+// ReadIndex reads the index of the log file path.
+func ReadIndex(path string) (*LogIndex, error) {
+	data, err := os.ReadFile(path + IndexSuffix)
+	if err != nil {
+		return nil, err
+	}
+	var idx LogIndex
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, err
+	}
+	return &idx, nil
+}
+
+// LogQuery selects the entries returned by QueryLogs.
+type LogQuery struct {
+	Since, Until time.Time // zero means unbounded, Until is excluded
+	Level        Level     // minimum level, if set
+	// Fields are compared to the text of the field values, e.g. "7" for an
+	// int field.
+	Fields map[string]string
+}
+
+// QueryLogs calls fn with the entries matching q of the JSON log files
+// matching the glob pattern, oldest file first, until fn returns false. The
+// files whose index shows they have no matching entry are skipped, the
+// others, such as the current file, are read.
+func QueryLogs(pattern string, q LogQuery, fn func(e *Entry) bool) error {
+	names, err := filepath.Glob(pattern)
+	if err != nil {
+		return err
+	}
+	type file struct {
+		name string
+		mod  time.Time
+	}
+	var files []file
+	for _, name := range names {
+		fi, err := os.Stat(name)
+		if err != nil || fi.IsDir() || strings.HasSuffix(name, IndexSuffix) {
+			continue
+		}
+		files = append(files, file{name, fi.ModTime()})
+	}
+	sort.SliceStable(files, func(i, j int) bool { return files[i].mod.Before(files[j].mod) })
+	for _, file := range files {
+		// an index older than its file, e.g. built while it was written, is
+		// not trusted
+		if fi, err := os.Stat(file.name + IndexSuffix); err == nil && !fi.ModTime().Before(file.mod) {
+			if idx, err := ReadIndex(file.name); err == nil && !q.mayMatch(idx) {
+				continue
+			}
+		}
+		f, err := os.Open(file.name)
+		if err != nil {
+			return err
+		}
+		more := true
+		err = scanEntries(f, func(e *Entry) bool {
+			if q.match(e) {
+				more = fn(e)
+			}
+			return more
+		})
+		f.Close()
+		if err != nil || !more {
+			return err
+		}
+	}
+	return nil
+}
+
+func (q *LogQuery) mayMatch(idx *LogIndex) bool {
+	if idx.Entries == 0 || !q.Since.IsZero() && idx.To.Before(q.Since) || !q.Until.IsZero() && !idx.From.Before(q.Until) {
+		return false
+	}
+	found := false
+	for name, n := range idx.Levels {
+		if level, err := parseLevelName(name); err == nil && level >= q.Level && n > 0 {
+			found = true
+		}
+	}
+	if !found {
+		return false
+	}
+	for key, value := range q.Fields {
+		if !bloomHas(idx.Fields, key+"="+value) {
+			return false
+		}
+	}
+	return true
+}
+
+func (q *LogQuery) match(e *Entry) bool {
+	if e.Level < q.Level || !q.Since.IsZero() && e.Time.Before(q.Since) || !q.Until.IsZero() && !e.Time.Before(q.Until) {
+		return false
+	}
+	for key, value := range q.Fields {
+		found := false
+		for _, field := range e.Fields {
+			if v, ok := indexValue(field); ok && field.Key == key && v == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// scanEntries calls fn with every JSON entry of r until it returns false.
+func scanEntries(r io.Reader, fn func(e *Entry) bool) error {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			if e, perr := ParseJSONEntry(line); perr == nil && !fn(&e) {
+				return nil
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// indexValue returns the text of the field values which are indexed.
+func indexValue(f Field) (string, bool) {
+	switch f.Type {
+	case StringType, IntType, BoolType:
+		return fmt.Sprint(f.Value()), true
+	}
+	return "", false
+}
+
+func bloomPositions(s string) [4]uint32 {
+	h := fnv.New64a()
+	io.WriteString(h, s)
+	sum := h.Sum64()
+	h1, h2 := uint32(sum), uint32(sum>>32)
+	var pos [4]uint32
+	for i := range pos {
+		pos[i] = (h1 + uint32(i)*h2) % indexBloomBits
+	}
+	return pos
+}
+
+func bloomAdd(bits []byte, s string) {
+	for _, p := range bloomPositions(s) {
+		bits[p/8] |= 1 << (p % 8)
+	}
+}
+
+func bloomHas(bits []byte, s string) bool {
+	if len(bits)*8 != indexBloomBits {
+		return true
+	}
+	for _, p := range bloomPositions(s) {
+		if bits[p/8]&(1<<(p%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
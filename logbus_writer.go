@@ -0,0 +1,134 @@
+package spoor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+type LogbusConfig struct {
+	Endpoint      string
+	Token         string
+	Gzip          bool
+	BatchSize     int
+	BatchBytes    int // also flush once this many bytes are pending, if not 0
+	FlushInterval time.Duration
+	// MaxRetries and RetryBackoff are shorthands for Retry.MaxRetries and
+	// Retry.InitialBackoff.
+	MaxRetries   int
+	RetryBackoff time.Duration
+	Retry        RetryPolicy
+	Timeout      time.Duration
+	DeadLetter   io.Writer
+	Transport    HTTPTransport // proxy, TLS, extra headers or SigV4 signing
+	Client       *http.Client
+}
+
+// LoadLogbusConfig reads a LogbusConfig from a JSON file, durations are
+// written as strings such as "3s".
+func LoadLogbusConfig(path string) (LogbusConfig, error) {
+	var raw struct {
+		Endpoint      string `json:"endpoint"`
+		Token         string `json:"token"`
+		Gzip          bool   `json:"gzip"`
+		BatchSize     int    `json:"batch_size"`
+		BatchBytes    int    `json:"batch_bytes"`
+		FlushInterval string `json:"flush_interval"`
+		MaxRetries    int    `json:"max_retries"`
+		RetryBackoff  string `json:"retry_backoff"`
+		Timeout       string `json:"timeout"`
+		// proxy, headers, ca_file, cert_file, key_file,
+		// insecure_skip_verify and sigv4 {region, service}
+		Transport httpTransportConfig `json:"transport"`
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return LogbusConfig{}, err
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return LogbusConfig{}, fmt.Errorf("cannot parse logbus config %s: %v", path, err)
+	}
+	cfg := LogbusConfig{
+		Endpoint:   raw.Endpoint,
+		Token:      raw.Token,
+		Gzip:       raw.Gzip,
+		BatchSize:  raw.BatchSize,
+		BatchBytes: raw.BatchBytes,
+		MaxRetries: raw.MaxRetries,
+	}
+	if cfg.Transport, err = raw.Transport.transport(); err != nil {
+		return LogbusConfig{}, fmt.Errorf("cannot parse logbus config %s: %v", path, err)
+	}
+	for _, d := range []struct {
+		s   string
+		dst *time.Duration
+	}{
+		{raw.FlushInterval, &cfg.FlushInterval},
+		{raw.RetryBackoff, &cfg.RetryBackoff},
+		{raw.Timeout, &cfg.Timeout},
+	} {
+		if d.s == "" {
+			continue
+		}
+		if *d.dst, err = time.ParseDuration(d.s); err != nil {
+			return LogbusConfig{}, fmt.Errorf("cannot parse logbus config %s: %v", path, err)
+		}
+	}
+	return cfg, nil
+}
+
+// LogbusWriter posts batches of entries as NDJSON to a logbus endpoint.
+type LogbusWriter struct {
+	*httpSender
+	cfg LogbusConfig
+}
+
+func NewLogbusWriter(cfg LogbusConfig) *LogbusWriter {
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = 500
+	}
+	if cfg.FlushInterval == 0 {
+		cfg.FlushInterval = time.Second * 3
+	}
+	cfg.Retry = cfg.Retry.withDefaults(cfg.MaxRetries, cfg.RetryBackoff)
+	if cfg.Timeout == 0 {
+		cfg.Timeout = time.Second * 10
+	}
+	if cfg.Client == nil {
+		cfg.Client = cfg.Transport.client(cfg.Timeout)
+	}
+	lw := &LogbusWriter{cfg: cfg}
+	lw.httpSender = newHTTPSender(&httpSender{
+		name:        "logbus",
+		url:         cfg.Endpoint,
+		contentType: "application/x-ndjson",
+		gzip:        cfg.Gzip,
+		retry:       cfg.Retry,
+		deadLetter:  cfg.DeadLetter,
+		transport:   cfg.Transport,
+		client:      cfg.Client,
+		encode:      lw.encode,
+		header: func(h http.Header) {
+			if cfg.Token != "" {
+				h.Set("Authorization", "Bearer "+cfg.Token)
+			}
+		},
+	}, cfg.BatchSize, cfg.BatchBytes, cfg.FlushInterval)
+	return lw
+}
+
+func (lw *LogbusWriter) encode(lines [][]byte) ([]byte, error) {
+	var body bytes.Buffer
+	now := time.Now()
+	for _, line := range lines {
+		if doc := lineDocument(line, now); doc != nil {
+			body.Write(doc)
+			body.WriteByte('\n')
+		}
+	}
+	return body.Bytes(), nil
+}
@@ -0,0 +1,107 @@
+package spoor
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type logbusRequest struct {
+	header http.Header
+	docs   []string
+}
+
+func TestLogbusWriter(t *testing.T) {
+	var mu sync.Mutex
+	var requests []logbusRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			t.Errorf("body not gzipped: %v", err)
+			return
+		}
+		data, _ := io.ReadAll(zr)
+		mu.Lock()
+		requests = append(requests, logbusRequest{r.Header, strings.Split(strings.TrimSpace(string(data)), "\n")})
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	lw := NewLogbusWriter(LogbusConfig{Endpoint: srv.URL, Token: "tok", Gzip: true, BatchBytes: 40, FlushInterval: time.Hour})
+	lw.Write([]byte(`{"msg":"one","n":1}` + "\n"))
+	lw.Write([]byte(`{"msg":"two","n":2}` + "\n"))
+	// BatchBytes reached, the first two are sent without waiting for the interval
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		mu.Lock()
+		n := len(requests)
+		mu.Unlock()
+		if n == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("BatchBytes did not flush")
+		}
+	}
+	lw.Write([]byte(`{"msg":"three","n":3}` + "\n"))
+	lw.Close()
+
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(requests))
+	}
+	h := requests[0].header
+	if h.Get("Authorization") != "Bearer tok" || h.Get("Content-Encoding") != "gzip" || h.Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("got headers %v", h)
+	}
+	if got := strings.Join(requests[0].docs, ","); got != `{"msg":"one","n":1},{"msg":"two","n":2}` {
+		t.Fatalf("first batch %s", got)
+	}
+	if got := strings.Join(requests[1].docs, ","); got != `{"msg":"three","n":3}` {
+		t.Fatalf("second batch %s", got)
+	}
+}
+
+func TestLogbusWriterDeadLetter(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			t.Error("Authorization set without a token")
+		}
+		http.Error(w, "bad request", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	var dead bytes.Buffer
+	lw := NewLogbusWriter(LogbusConfig{Endpoint: srv.URL, RetryBackoff: time.Millisecond, DeadLetter: &dead})
+	lw.Write([]byte("ERROR rejected\n"))
+	lw.Close()
+	var replayed bytes.Buffer
+	if n, err := Replay(&dead, &replayed); err != nil || n != 1 || replayed.String() != "ERROR rejected\n" {
+		t.Fatalf("Replay = %d, %v, %q", n, err, replayed.String())
+	}
+}
+
+func TestLoadLogbusConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logbus.json")
+	os.WriteFile(path, []byte(`{"endpoint":"http://x","token":"tok","gzip":true,"batch_size":10,"batch_bytes":1024,
+		"flush_interval":"250ms","max_retries":2,"retry_backoff":"1s","timeout":"1m"}`), 0666)
+	cfg, err := LoadLogbusConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Endpoint != "http://x" || cfg.Token != "tok" || !cfg.Gzip || cfg.BatchSize != 10 || cfg.BatchBytes != 1024 || cfg.MaxRetries != 2 ||
+		cfg.FlushInterval != time.Millisecond*250 || cfg.RetryBackoff != time.Second || cfg.Timeout != time.Minute {
+		t.Fatalf("got %+v", cfg)
+	}
+
+	os.WriteFile(path, []byte(`{"flush_interval":"3 parsecs"}`), 0666)
+	if _, err := LoadLogbusConfig(path); err == nil || !strings.Contains(err.Error(), "3 parsecs") {
+		t.Fatalf("got %v for an invalid duration", err)
+	}
+}
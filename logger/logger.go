@@ -3,7 +3,10 @@ package logger
 import (
 	"fmt"
 	"github.com/phuhao00/spoor"
+	"io"
 	"log"
+	"os"
+	"strings"
 	"sync"
 )
 
@@ -18,57 +21,140 @@ func GetLogger() *spoor.Spoor {
 
 type LoggingSetting struct {
 	Dir          string
+	File         string // file name in Dir, see spoor.WithFileName
 	Level        int
 	Prefix       string
 	WriterOption spoor.Option
+	V            int    // verbosity, see spoor.Spoor.V
+	VModule      string // see spoor.Verbosity.SetModules
 }
 
 func SetLogging(setting *LoggingSetting) {
 	onceInitLogger.Do(func() {
 		var opt spoor.Option
 		if setting.WriterOption == nil {
-			fileWriter := spoor.NewFileWriter(setting.Dir, 0, 0, 0)
+			var fileOpts []spoor.FileOption
+			if setting.File != "" {
+				fileOpts = append(fileOpts, spoor.WithFileName(setting.File))
+			}
+			fileWriter := spoor.NewFileWriter(setting.Dir, 0, 0, 0, fileOpts...)
 			opt = spoor.WithFileWriter(fileWriter)
 		} else {
 			opt = setting.WriterOption
 		}
-		l := spoor.NewSpoor(spoor.Level(setting.Level), setting.Prefix, log.Ldate|log.Ltime|log.Lmicroseconds|log.Llongfile, opt)
+		v := spoor.NewVerbosity(setting.V)
+		if err := v.SetModules(setting.VModule); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		l := spoor.NewSpoor(spoor.Level(setting.Level), setting.Prefix, log.Ldate|log.Ltime|log.Lmicroseconds|log.Llongfile, opt, spoor.WithVerbosity(v))
 		sp = l
 	})
 }
 
+// SetLogger makes l the logger behind the package functions, so code using
+// them can be moved to a logger built with spoor.NewSpoor, NewProduction or
+// NewDevelopment without being rewritten. It has no effect after SetLogging.
+func SetLogger(l *spoor.Spoor) {
+	onceInitLogger.Do(func() {
+		sp = l
+	})
+}
+
+// Enabled reports whether the package functions write entries at level, see
+// spoor.Spoor.Enabled.
+func Enabled(level spoor.Level) bool {
+	return sp != nil && sp.Enabled(level)
+}
+
+func IsDebugEnabled() bool {
+	return Enabled(spoor.DEBUG)
+}
+
 // Debug Log line format: [IWEF]mmdd hh:mm:sLogger.uuuuuu threadid file:line] msg
 func Debug(f string, args ...interface{}) {
 	if sp.CheckLevel(spoor.DEBUG) {
 		return
 	}
-	sp.Output(2, fmt.Sprintf(spoor.DEBUG.String()+" "+f, args...))
+	sp.Log(2, spoor.DEBUG, fmt.Sprintf(f, args...))
 }
 
 func Error(f string, args ...interface{}) {
 	if sp.CheckLevel(spoor.ERROR) {
 		return
 	}
-	sp.Output(2, fmt.Sprintf(spoor.ERROR.String()+" "+f, args...))
+	sp.Log(2, spoor.ERROR, fmt.Sprintf(f, args...))
 }
 
 func Info(f string, args ...interface{}) {
 	if sp.CheckLevel(spoor.INFO) {
 		return
 	}
-	sp.Output(2, fmt.Sprintf(spoor.INFO.String()+" "+f, args...))
+	sp.Log(2, spoor.INFO, fmt.Sprintf(f, args...))
 }
 
 func Warn(f string, args ...interface{}) {
 	if sp.CheckLevel(spoor.WARN) {
 		return
 	}
-	sp.Output(2, fmt.Sprintf(spoor.WARN.String()+" "+f, args...))
+	sp.Log(2, spoor.WARN, fmt.Sprintf(f, args...))
 }
 
 func Fatal(f string, args ...interface{}) {
 	if sp.CheckLevel(spoor.FATAL) {
 		return
 	}
-	sp.Output(2, fmt.Sprintf(spoor.FATAL.String()+" "+f, args...))
+	sp.Log(2, spoor.FATAL, fmt.Sprintf(f, args...))
+}
+
+// LoggerFromSpoor returns l as a spoor.Logger, the Output based interface of
+// the legacy API, so that code written against it can be moved to l: every
+// string passed to Output is logged by l as one entry, at the level named by
+// its first word, such as "WARNING", or INFO, and SetOutput sets the writer
+// of l.
+func LoggerFromSpoor(l *spoor.Spoor) spoor.Logger {
+	return spoorLogger{l: l}
+}
+
+type spoorLogger struct {
+	l *spoor.Spoor
+}
+
+func (s spoorLogger) Output(callerSkip int, msg string) error {
+	msg = strings.TrimSuffix(msg, "\n")
+	level := spoor.INFO
+	if word := strings.SplitN(msg, " ", 2); len(word) == 2 {
+		for lvl := spoor.DEBUG; lvl <= spoor.FATAL; lvl++ {
+			if word[0] == lvl.String() {
+				level, msg = lvl, word[1]
+				break
+			}
+		}
+	}
+	s.l.Log(callerSkip+1, level, msg)
+	return nil
+}
+
+func (s spoorLogger) SetOutput(w io.Writer) {
+	s.l.SetOutput(w)
+}
+
+// SpoorFromLogger returns a *spoor.Spoor logging at level and above whose
+// entries, formatted without time nor caller, are written with lg.Output,
+// so that code holding a spoor.Logger of the legacy API, such as a
+// *log.Logger, can log with levels and fields. The time and caller, if
+// any, are those added by lg.
+func SpoorFromLogger(lg spoor.Logger, level spoor.Level) *spoor.Spoor {
+	return spoor.NewSpoor(level, "", 0, spoor.WithConsoleWriter(outputWriter{lg: lg}))
+}
+
+// outputWriter writes every formatted entry with one call to Output.
+type outputWriter struct {
+	lg spoor.Logger
+}
+
+func (w outputWriter) Write(p []byte) (int, error) {
+	if err := w.lg.Output(2, string(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
 }
@@ -0,0 +1,35 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"runtime"
+	"testing"
+
+	"github.com/phuhao00/spoor"
+)
+
+func TestLoggerFromSpoor(t *testing.T) {
+	var buf bytes.Buffer
+	lg := LoggerFromSpoor(spoor.NewSpoor(spoor.INFO, "", log.Lshortfile))
+	lg.SetOutput(&buf)
+	lg.Output(1, "WARNING disk almost full\n")
+	_, _, line, _ := runtime.Caller(0)
+	lg.Output(1, "started")
+	lg.Output(1, "DEBUG not logged")
+	want := fmt.Sprintf("logger_test.go:%d: WARNING disk almost full\nlogger_test.go:%d: INFO started\n", line-1, line+1)
+	if buf.String() != want {
+		t.Fatalf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestSpoorFromLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := SpoorFromLogger(log.New(&buf, "app: ", 0), spoor.INFO)
+	l.Info("started", spoor.Int("port", 80))
+	l.Debug("not logged")
+	if want := "app: INFO started port=80\n"; buf.String() != want {
+		t.Fatalf("got %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/phuhao00/spoor/logrspoor
+
+go 1.18
+
+require (
+	github.com/go-logr/logr v1.4.3
+	github.com/phuhao00/spoor v0.0.0
+)
+
+replace github.com/phuhao00/spoor => ../
@@ -0,0 +1,104 @@
+// Package logrspoor is a logr.LogSink writing with spoor, so that the
+// libraries logging with logr, such as controller-runtime and client-go,
+// write to the spoor writers.
+package logrspoor
+
+import (
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"github.com/phuhao00/spoor"
+)
+
+// New returns a logr.Logger writing with l. Info is logged at INFO for V(0)
+// and at DEBUG for the higher verbosities, which must be enabled by the
+// verbosity of l, see spoor.Spoor.V, and Error at ERROR with the error in
+// the error field. The key/value pairs become fields, and the names given
+// to WithName are joined with "/" in the logger field:
+//
+//	ctrl.SetLogger(logrspoor.New(l))
+func New(l *spoor.Spoor) logr.Logger {
+	return logr.New(&sink{l: l})
+}
+
+type sink struct {
+	l     *spoor.Spoor
+	name  string
+	depth int // frames between the caller and the sink, see logr.RuntimeInfo
+}
+
+func (s *sink) Init(info logr.RuntimeInfo) {
+	s.depth = info.CallDepth
+}
+
+func (s *sink) Enabled(level int) bool {
+	return s.l.Enabled(infoLevel(level)) && s.l.V(level).Enabled()
+}
+
+func (s *sink) Info(level int, msg string, keysAndValues ...interface{}) {
+	s.l.Log(s.depth+2, infoLevel(level), msg, s.fields(keysAndValues)...)
+}
+
+func (s *sink) Error(err error, msg string, keysAndValues ...interface{}) {
+	s.l.Log(s.depth+2, spoor.ERROR, msg, append(s.fields(keysAndValues), spoor.Err(err))...)
+}
+
+func (s *sink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	c := *s
+	c.l = s.l.With(fields(keysAndValues)...)
+	return &c
+}
+
+func (s *sink) WithName(name string) logr.LogSink {
+	c := *s
+	if c.name != "" {
+		name = c.name + "/" + name
+	}
+	c.name = name
+	return &c
+}
+
+// WithCallDepth implements logr.CallDepthLogSink, so that logr.Logger's
+// WithCallDepth keeps the caller of the helpers wrapping the logger.
+func (s *sink) WithCallDepth(depth int) logr.LogSink {
+	c := *s
+	c.depth += depth
+	return &c
+}
+
+func infoLevel(level int) spoor.Level {
+	if level > 0 {
+		return spoor.DEBUG
+	}
+	return spoor.INFO
+}
+
+// fields converts the key/value pairs of an entry, with the logger field.
+func (s *sink) fields(keysAndValues []interface{}) []spoor.Field {
+	fs := fields(keysAndValues)
+	if s.name != "" {
+		fs = append(fs, spoor.String("logger", s.name))
+	}
+	return fs
+}
+
+// fields converts key/value pairs as logr's funcr does with the keys which
+// are not strings and a missing last value.
+func fields(keysAndValues []interface{}) []spoor.Field {
+	fs := make([]spoor.Field, 0, (len(keysAndValues)+1)/2+2)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key, ok := keysAndValues[i].(string)
+		if !ok {
+			key = fmt.Sprintf("<non-string-key: %v>", keysAndValues[i])
+		}
+		var value interface{} = "<no-value>"
+		if i+1 < len(keysAndValues) {
+			value = keysAndValues[i+1]
+		}
+		if m, ok := value.(logr.Marshaler); ok {
+			value = m.MarshalLog()
+		}
+		fs = append(fs, spoor.Any(key, value))
+	}
+	return fs
+}
@@ -0,0 +1,42 @@
+package logrspoor
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/phuhao00/spoor"
+)
+
+type user struct{ name string }
+
+func (u user) MarshalLog() interface{} { return "user:" + u.name }
+
+func TestLogger(t *testing.T) {
+	mw := spoor.NewMemoryWriter(10)
+	v := spoor.NewVerbosity(1)
+	l := New(spoor.NewSpoor(spoor.DEBUG, "", log.Lshortfile, spoor.WithConsoleWriter(mw), spoor.WithVerbosity(v)))
+	l = l.WithName("controller").WithName("pods").WithValues("namespace", "default")
+	l.Info("reconciled", "pod", "web-1", "count", 3, "owner", user{"jane"})
+	l.V(1).Info("cache hit", 42, "x", "odd")
+	l.V(2).Info("not logged")
+	l.Error(errors.New("boom"), "reconcile failed", "pod", "web-2")
+	want := []string{
+		"logrspoor_test.go:21: INFO reconciled namespace=default pod=web-1 count=3 owner=user:jane logger=controller/pods",
+		`logrspoor_test.go:22: DEBUG cache hit namespace=default "<non-string-key: 42>"=x odd=<no-value> logger=controller/pods`,
+		"logrspoor_test.go:24: ERROR reconcile failed namespace=default pod=web-2 logger=controller/pods error=boom",
+	}
+	lines := mw.Lines()
+	if len(lines) != len(want) {
+		t.Fatalf("got %q", lines)
+	}
+	for i, line := range lines {
+		if got := strings.TrimSpace(string(line)); got != want[i] {
+			t.Errorf("got %q, want %q", got, want[i])
+		}
+	}
+	if !l.V(1).Enabled() || l.V(2).Enabled() {
+		t.Error("the verbosity of the logger is not used")
+	}
+}
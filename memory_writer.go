@@ -0,0 +1,91 @@
+package spoor
+
+import (
+	"io"
+	"sync"
+)
+
+// DiscardWriter accepts and drops everything, so that benchmarks measure the
+// logger rather than the output.
+type DiscardWriter struct{}
+
+func (DiscardWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+// MemoryWriter keeps the last writes, one entry each, in a ring buffer, e.g.
+// to serve the recent logs of a process on a debug endpoint.
+type MemoryWriter struct {
+	mu    sync.Mutex
+	lines [][]byte
+	next  int
+	full  bool
+}
+
+// NewMemoryWriter returns a writer keeping the last capacity entries,
+// 1000 if capacity is 0.
+func NewMemoryWriter(capacity int) *MemoryWriter {
+	if capacity <= 0 {
+		capacity = 1000
+	}
+	return &MemoryWriter{lines: make([][]byte, capacity)}
+}
+
+func (w *MemoryWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lines[w.next] = append(w.lines[w.next][:0], p...)
+	w.next++
+	if w.next == len(w.lines) {
+		w.next, w.full = 0, true
+	}
+	return len(p), nil
+}
+
+// Lines returns copies of the kept entries, oldest first.
+func (w *MemoryWriter) Lines() [][]byte {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var lines [][]byte
+	if w.full {
+		lines = appendCopies(lines, w.lines[w.next:])
+	}
+	return appendCopies(lines, w.lines[:w.next])
+}
+
+// Len returns the number of kept entries.
+func (w *MemoryWriter) Len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.full {
+		return len(w.lines)
+	}
+	return w.next
+}
+
+// WriteTo writes the kept entries to dst, oldest first.
+func (w *MemoryWriter) WriteTo(dst io.Writer) (int64, error) {
+	var total int64
+	for _, line := range w.Lines() {
+		n, err := dst.Write(line)
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// Reset drops the kept entries.
+func (w *MemoryWriter) Reset() {
+	w.mu.Lock()
+	w.next, w.full = 0, false
+	w.mu.Unlock()
+}
+
+func appendCopies(dst, src [][]byte) [][]byte {
+	for _, line := range src {
+		dst = append(dst, append([]byte(nil), line...))
+	}
+	return dst
+}
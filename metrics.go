@@ -0,0 +1,18 @@
+package spoor
+
+import "sync/atomic"
+
+// Metrics are the counters of a logger, shared with the loggers derived
+// from it.
+type Metrics struct {
+	Sampling SamplingMetrics // zero without a sampler
+}
+
+func (l *Spoor) GetMetrics() Metrics {
+	var m Metrics
+	if s := l.sampler; s != nil {
+		m.Sampling.Kept = atomic.LoadUint64(&s.kept)
+		m.Sampling.SampledOut = atomic.LoadUint64(&s.sampledOut)
+	}
+	return m
+}
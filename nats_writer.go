@@ -0,0 +1,151 @@
+package spoor
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+type NATSConfig struct {
+	Addr string // e.g. 127.0.0.1:4222
+	// Subject may use {program}, {host} and {level}.
+	Subject  string
+	User     string
+	Password string
+	Token    string
+	Timeout  time.Duration
+}
+
+// NATSWriter publishes every entry to a NATS subject using the plain text
+// client protocol. The connection is re-established on the next write after
+// a failure.
+type NATSWriter struct {
+	cfg  NATSConfig
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func NewNATSWriter(cfg NATSConfig) *NATSWriter {
+	if cfg.Addr == "" {
+		cfg.Addr = "127.0.0.1:4222"
+	}
+	if cfg.Subject == "" {
+		cfg.Subject = "logs.{program}.{level}"
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = time.Second * 5
+	}
+	return &NATSWriter{cfg: cfg}
+}
+
+func (nw *NATSWriter) Write(p []byte) (n int, err error) {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	if nw.conn == nil {
+		if err := nw.connect(); err != nil {
+			return 0, err
+		}
+	}
+	subject := expandSubject(nw.cfg.Subject, p)
+	nw.conn.SetWriteDeadline(time.Now().Add(nw.cfg.Timeout))
+	_, err = fmt.Fprintf(nw.conn, "PUB %s %d\r\n%s\r\n", subject, len(p), p)
+	if err != nil {
+		nw.conn.Close()
+		nw.conn = nil
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (nw *NATSWriter) connect() error {
+	conn, err := net.DialTimeout("tcp", nw.cfg.Addr, nw.cfg.Timeout)
+	if err != nil {
+		return err
+	}
+	r := bufio.NewReader(conn)
+	conn.SetReadDeadline(time.Now().Add(nw.cfg.Timeout))
+	info, err := r.ReadString('\n')
+	if err != nil || !strings.HasPrefix(info, "INFO") {
+		conn.Close()
+		return fmt.Errorf("nats %s: unexpected greeting %q: %v", nw.cfg.Addr, info, err)
+	}
+	conn.SetReadDeadline(time.Time{})
+	opts, _ := json.Marshal(map[string]interface{}{
+		"verbose":    false,
+		"pedantic":   false,
+		"name":       program,
+		"lang":       "go",
+		"user":       nw.cfg.User,
+		"pass":       nw.cfg.Password,
+		"auth_token": nw.cfg.Token,
+	})
+	conn.SetWriteDeadline(time.Now().Add(nw.cfg.Timeout))
+	if _, err := fmt.Fprintf(conn, "CONNECT %s\r\n", opts); err != nil {
+		conn.Close()
+		return err
+	}
+	nw.conn = conn
+	go nw.readLoop(conn, r)
+	return nil
+}
+
+// readLoop answers server pings, which keep the connection alive, and drops
+// the connection once the server closed it so that the next write reconnects.
+func (nw *NATSWriter) readLoop(conn net.Conn, r *bufio.Reader) {
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			nw.mu.Lock()
+			if nw.conn == conn {
+				conn.Close()
+				nw.conn = nil
+			}
+			nw.mu.Unlock()
+			return
+		}
+		switch {
+		case strings.HasPrefix(line, "PING"):
+			nw.mu.Lock()
+			if nw.conn == conn {
+				conn.SetWriteDeadline(time.Now().Add(nw.cfg.Timeout))
+				conn.Write([]byte("PONG\r\n"))
+			}
+			nw.mu.Unlock()
+		case strings.HasPrefix(line, "-ERR"):
+			internalError(fmt.Errorf("spoor: nats %s: %s", nw.cfg.Addr, line))
+		}
+	}
+}
+
+// Close closes the connection.
+func (nw *NATSWriter) Close() error {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	if nw.conn == nil {
+		return nil
+	}
+	err := nw.conn.Close()
+	nw.conn = nil
+	return err
+}
+
+// expandSubject fills in the {program}, {host} and {level} placeholders of a
+// subject or stream template for the line p.
+func expandSubject(tmpl string, p []byte) string {
+	if !strings.Contains(tmpl, "{") {
+		return tmpl
+	}
+	level := "unknown"
+	if lvl, ok := lineLevel(p); ok {
+		level = strings.ToLower(lvl.String())
+	}
+	return strings.NewReplacer(
+		"{program}", program,
+		"{host}", host,
+		"{level}", level,
+	).Replace(tmpl)
+}
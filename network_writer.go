@@ -0,0 +1,102 @@
+package spoor
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+type NetworkConfig struct {
+	Network string // "tcp" or "udp"
+	Addr    string
+	// TLSConfig enables TLS for tcp connections.
+	TLSConfig    *tls.Config
+	DialTimeout  time.Duration
+	WriteTimeout time.Duration
+	// ReconnectBackoff is the minimum delay between two dial attempts.
+	ReconnectBackoff time.Duration
+}
+
+// NetworkWriter streams entries over a TCP or UDP connection, e.g. to a
+// logstash tcp input, reconnecting when a write fails.
+type NetworkWriter struct {
+	cfg      NetworkConfig
+	mu       sync.Mutex
+	conn     net.Conn
+	lastDial time.Time
+}
+
+func NewNetworkWriter(cfg NetworkConfig) *NetworkWriter {
+	if cfg.Network == "" {
+		cfg.Network = "tcp"
+	}
+	if cfg.DialTimeout == 0 {
+		cfg.DialTimeout = time.Second * 5
+	}
+	if cfg.WriteTimeout == 0 {
+		cfg.WriteTimeout = time.Second * 5
+	}
+	if cfg.ReconnectBackoff == 0 {
+		cfg.ReconnectBackoff = time.Second
+	}
+	return &NetworkWriter{cfg: cfg}
+}
+
+func (nw *NetworkWriter) Write(p []byte) (n int, err error) {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	// a broken tcp connection is often only noticed by the write after the
+	// peer went away, so a write failing before sending anything is retried
+	// once on a new connection, a partly sent one is not to avoid duplicates
+	for attempt := 0; attempt < 2; attempt++ {
+		if nw.conn == nil {
+			if err = nw.dial(); err != nil {
+				return 0, err
+			}
+		}
+		nw.conn.SetWriteDeadline(time.Now().Add(nw.cfg.WriteTimeout))
+		if n, err = nw.conn.Write(p); err == nil {
+			return n, nil
+		}
+		nw.conn.Close()
+		nw.conn = nil
+		if n > 0 {
+			break
+		}
+	}
+	return n, err
+}
+
+func (nw *NetworkWriter) dial() error {
+	if wait := nw.cfg.ReconnectBackoff - time.Since(nw.lastDial); wait > 0 {
+		return fmt.Errorf("network writer: reconnecting to %s in %v", nw.cfg.Addr, wait)
+	}
+	nw.lastDial = time.Now()
+	dialer := &net.Dialer{Timeout: nw.cfg.DialTimeout}
+	var conn net.Conn
+	var err error
+	if nw.cfg.TLSConfig != nil && nw.cfg.Network != "udp" {
+		conn, err = tls.DialWithDialer(dialer, nw.cfg.Network, nw.cfg.Addr, nw.cfg.TLSConfig)
+	} else {
+		conn, err = dialer.Dial(nw.cfg.Network, nw.cfg.Addr)
+	}
+	if err != nil {
+		return unavailable(err)
+	}
+	nw.conn = conn
+	return nil
+}
+
+// Close closes the current connection.
+func (nw *NetworkWriter) Close() error {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	if nw.conn == nil {
+		return nil
+	}
+	err := nw.conn.Close()
+	nw.conn = nil
+	return err
+}
@@ -4,6 +4,13 @@ import "io"
 
 type NilLogger struct{}
 
+// NewNop returns a logger writing nothing, for libraries to use when they are
+// given no logger instead of checking for nil. Its methods do nothing, except
+// Fatal which still exits.
+func NewNop() *Spoor {
+	return NewSpoor(Disabled, "", 0)
+}
+
 func (l NilLogger) Output(callerSkip int, s string) error {
 	return nil
 }
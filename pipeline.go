@@ -0,0 +1,80 @@
+package spoor
+
+import "time"
+
+// Stage processes an entry of a pipeline, see WithPipeline. It may change
+// the entry and returns false to drop it.
+type Stage func(e *Entry) bool
+
+// WithPipeline runs stages in order on every entry, after the enrichers and
+// the field transform of the logger, so that filters, redactors, samplers
+// and enrichers can be composed in any order:
+//
+//	spoor.WithPipeline(spoor.TransformStage(pii), spoor.RedactStage("token"), spoor.SampleStage(0.1))
+func WithPipeline(stages ...Stage) Option {
+	return func(spoor *Spoor) {
+		spoor.pipeline = append(spoor.pipeline[:len(spoor.pipeline):len(spoor.pipeline)], stages...)
+	}
+}
+
+func EnricherStage(enricher Enricher) Stage {
+	return func(e *Entry) bool {
+		enricher(e)
+		return true
+	}
+}
+
+// TransformStage applies t to all the fields, including those of With.
+func TransformStage(t *FieldTransform) Stage {
+	return func(e *Entry) bool {
+		e.Fields = t.fields(e.Fields)
+		e.Encoded, e.EncodedFields = nil, 0
+		return true
+	}
+}
+
+// RedactStage replaces the values of the fields with one of keys by
+// [REDACTED].
+func RedactStage(keys ...string) Stage {
+	set := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		set[key] = true
+	}
+	return func(e *Entry) bool {
+		copied := false
+		for i, f := range e.Fields {
+			if !set[f.Key] {
+				continue
+			}
+			if !copied {
+				e.Fields = append([]Field(nil), e.Fields...)
+				copied = true
+			}
+			e.Fields[i] = String(f.Key, redacted)
+			if i < e.EncodedFields {
+				e.Encoded, e.EncodedFields = nil, 0
+			}
+		}
+		return true
+	}
+}
+
+// SampleStage keeps about rate, between 0 and 1, of the entries, see
+// WithSampleRate.
+func SampleStage(rate float64) Stage {
+	s := &sampler{rate: rate, byRate: true}
+	return func(e *Entry) bool {
+		return s.check(e.Level, e.Message, e.Time)
+	}
+}
+
+// RepeatSampleStage caps repeated entries like WithSampling.
+func RepeatSampleStage(tick time.Duration, first, thereafter int) Stage {
+	if thereafter < 1 {
+		thereafter = 1
+	}
+	s := &sampler{tick: tick, first: uint64(first), thereafter: uint64(thereafter)}
+	return func(e *Entry) bool {
+		return s.check(e.Level, e.Message, e.Time)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !windows
+
+package spoor
+
+import (
+	"io"
+	"os"
+)
+
+// NewPlatformWriter returns os.Stderr, there is no native log service
+// supported on this platform.
+func NewPlatformWriter() io.Writer {
+	return os.Stderr
+}
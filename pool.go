@@ -0,0 +1,58 @@
+package spoor
+
+import (
+	"bytes"
+	"sync"
+)
+
+const maxPooledBuffer = 64 * 1024
+
+var bufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+// WithFieldCapacity builds the fields of every entry, those of With
+// included, in a pooled slice of capacity n instead of a new one, which
+// AddFields then extends in place: entries with up to n fields in the end,
+// those added by the enrichers and hooks included, allocate none for them.
+// The slice is reused once the entry is written, so hooks, span recorders
+// and error handlers must not retain it.
+func WithFieldCapacity(n int) Option {
+	return func(spoor *Spoor) {
+		if n <= 0 {
+			spoor.fieldPool = nil
+			return
+		}
+		spoor.fieldPool = &sync.Pool{
+			New: func() interface{} {
+				fields := make([]Field, 0, n)
+				return &fields
+			},
+		}
+	}
+}
+
+// putFields returns the slice of WithFieldCapacity to the pool, cleared so
+// that it does not pin the values of the entry.
+func (l *Spoor) putFields(p *[]Field) {
+	fields := (*p)[:cap(*p)]
+	for i := range fields {
+		fields[i] = Field{}
+	}
+	*p = fields[:0]
+	l.fieldPool.Put(p)
+}
+
+// putBuffer returns buf to the pool, unusually large buffers are dropped so
+// a single huge entry does not pin its memory.
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBuffer {
+		return
+	}
+	buf.Reset()
+	bufferPool.Put(buf)
+}
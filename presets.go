@@ -0,0 +1,56 @@
+package spoor
+
+import (
+	"log"
+	"os"
+	"time"
+)
+
+// NewDevelopment returns a DEBUG logger writing to stderr with DevFormatter
+// and caller information, colored when stderr is a terminal.
+func NewDevelopment(opts ...Option) *Spoor {
+	formatter := &DevFormatter{Color: isTerminal(os.Stderr)}
+	opts = append([]Option{WithConsoleWriter(os.Stderr), WithFormatter(formatter)}, opts...)
+	return NewSpoor(DEBUG, "", log.Lshortfile, opts...)
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// NewProduction returns an INFO logger writing JSON with the caller to
+// stderr, sampling repeated entries to at most 100 per second and message
+// plus every 100th after that.
+func NewProduction(opts ...Option) *Spoor {
+	opts = append([]Option{
+		WithConsoleWriter(os.Stderr),
+		WithFormatter(&JSONFormatter{}),
+		WithSampling(time.Second, 100, 100),
+	}, opts...)
+	return NewSpoor(INFO, "", log.Llongfile, opts...)
+}
+
+// NewContainer returns an INFO logger for container platforms: one JSON line
+// per entry written straight to stdout, the level under the "severity" key
+// and RFC3339Nano timestamps, as expected by Docker and Kubernetes log
+// collectors. Pass WithConsoleWriter(NewAsyncWriter(os.Stdout, cfg)) to
+// trade the immediate writes for throughput.
+func NewContainer(opts ...Option) *Spoor {
+	opts = append([]Option{
+		WithConsoleWriter(os.Stdout),
+		WithFormatter(&JSONFormatter{TimeFormat: time.RFC3339Nano, LevelKey: "severity"}),
+	}, opts...)
+	return NewSpoor(INFO, "", 0, opts...)
+}
+
+// NewECS returns an INFO logger writing Elastic Common Schema JSON lines for
+// service to stdout, for Filebeat or Elastic Agent to ship, or to an
+// ElasticWriter given with WithConsoleWriter.
+func NewECS(service string, opts ...Option) *Spoor {
+	opts = append([]Option{
+		WithConsoleWriter(os.Stdout),
+		WithFormatter(&ECSFormatter{ServiceName: service}),
+	}, opts...)
+	return NewSpoor(INFO, "", log.Llongfile, opts...)
+}
@@ -0,0 +1,141 @@
+package spoor
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type RedisStreamConfig struct {
+	Addr     string // e.g. 127.0.0.1:6379
+	Password string
+	DB       int
+	// Stream may use {program}, {host} and {level}.
+	Stream string
+	// MaxLen trims the stream to about MaxLen entries, 0 disables trimming.
+	MaxLen  int
+	Timeout time.Duration
+}
+
+// RedisStreamWriter appends every entry to a Redis stream with XADD, storing
+// the line under "message" and its level under "level".
+type RedisStreamWriter struct {
+	cfg  RedisStreamConfig
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func NewRedisStreamWriter(cfg RedisStreamConfig) *RedisStreamWriter {
+	if cfg.Addr == "" {
+		cfg.Addr = "127.0.0.1:6379"
+	}
+	if cfg.Stream == "" {
+		cfg.Stream = "logs:{program}"
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = time.Second * 5
+	}
+	return &RedisStreamWriter{cfg: cfg}
+}
+
+func (rw *RedisStreamWriter) Write(p []byte) (n int, err error) {
+	level := "unknown"
+	if lvl, ok := lineLevel(p); ok {
+		level = lvl.String()
+	}
+	args := []string{"XADD", expandSubject(rw.cfg.Stream, p)}
+	if rw.cfg.MaxLen > 0 {
+		args = append(args, "MAXLEN", "~", strconv.Itoa(rw.cfg.MaxLen))
+	}
+	args = append(args, "*", "level", level, "message", string(bytes.TrimRight(p, "\n")))
+
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	if rw.conn == nil {
+		if err := rw.connect(); err != nil {
+			return 0, err
+		}
+	}
+	if err := rw.do(args...); err != nil {
+		if _, ok := err.(redisError); !ok {
+			rw.conn.Close()
+			rw.conn = nil
+		}
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (rw *RedisStreamWriter) connect() error {
+	conn, err := net.DialTimeout("tcp", rw.cfg.Addr, rw.cfg.Timeout)
+	if err != nil {
+		return err
+	}
+	rw.conn, rw.r = conn, bufio.NewReader(conn)
+	if rw.cfg.Password != "" {
+		err = rw.do("AUTH", rw.cfg.Password)
+	}
+	if err == nil && rw.cfg.DB != 0 {
+		err = rw.do("SELECT", strconv.Itoa(rw.cfg.DB))
+	}
+	if err != nil {
+		conn.Close()
+		rw.conn = nil
+	}
+	return err
+}
+
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+// do sends a command and reads a single reply, discarding its value.
+func (rw *RedisStreamWriter) do(args ...string) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&buf, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	rw.conn.SetDeadline(time.Now().Add(rw.cfg.Timeout))
+	if _, err := rw.conn.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	line, err := rw.r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return fmt.Errorf("redis: malformed reply %q", line)
+	}
+	switch line[0] {
+	case '-':
+		return redisError(line[1 : len(line)-2])
+	case '$':
+		size, err := strconv.Atoi(line[1 : len(line)-2])
+		if err != nil {
+			return err
+		}
+		if size >= 0 {
+			_, err = rw.r.Discard(size + 2)
+		}
+		return err
+	}
+	return nil
+}
+
+// Close closes the connection.
+func (rw *RedisStreamWriter) Close() error {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	if rw.conn == nil {
+		return nil
+	}
+	err := rw.conn.Close()
+	rw.conn = nil
+	return err
+}
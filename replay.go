@@ -0,0 +1,106 @@
+package spoor
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"time"
+)
+
+type ReplayOptions struct {
+	Since, Until time.Time // skip the entries outside, if set
+	Level        Level     // skip the entries below, if set
+	// Formatter renders the entries again, the lines are written as they
+	// are if nil.
+	Formatter Formatter
+	BatchSize int // lines handed over with WriteBatch, 500 by default
+	Rate      int // maximum lines per second, no limit if 0
+}
+
+// ReplayLog reads NDJSON logs, such as FileWriter files or the gzip compressed
+// objects of an S3Writer, and writes them to w, e.g. an ElasticWriter to
+// backfill an index after an outage. The lines keep their timestamp. Lines
+// which are not JSON are written only if no filter is set. w is flushed at
+// the end if it has a Flush method. ReplayLog returns the number of lines
+// written.
+func ReplayLog(r io.Reader, w io.Writer, opts ReplayOptions) (int, error) {
+	if opts.BatchSize == 0 {
+		opts.BatchSize = 500
+	}
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return 0, err
+		}
+		defer zr.Close()
+		br = bufio.NewReader(zr)
+	}
+	filtered := !opts.Since.IsZero() || !opts.Until.IsZero() || opts.Level != 0
+	var batch [][]byte
+	n := 0
+	start := time.Now()
+	write := func() error {
+		if err := WriteBatch(w, batch); err != nil {
+			return err
+		}
+		n += len(batch)
+		batch = batch[:0]
+		if opts.Rate > 0 {
+			// sleep until n lines are due
+			time.Sleep(time.Until(start.Add(time.Duration(n) * time.Second / time.Duration(opts.Rate))))
+		}
+		return nil
+	}
+	batchSize := opts.BatchSize
+	if opts.Rate > 0 && opts.Rate < batchSize {
+		batchSize = opts.Rate
+	}
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			if line, ok := replayLine(line, filtered, &opts); ok {
+				batch = append(batch, line)
+			}
+		}
+		if len(batch) >= batchSize || err != nil && len(batch) > 0 {
+			if werr := write(); werr != nil {
+				return n, werr
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+	if f, ok := w.(interface{ Flush() error }); ok {
+		return n, f.Flush()
+	}
+	return n, nil
+}
+
+// replayLine applies the filters and the formatter of opts to line.
+func replayLine(line []byte, filtered bool, opts *ReplayOptions) ([]byte, bool) {
+	if line[len(line)-1] != '\n' {
+		line = append(line, '\n')
+	}
+	if !filtered && opts.Formatter == nil {
+		return line, true
+	}
+	e, err := ParseJSONEntry(line)
+	if err != nil {
+		return line, !filtered
+	}
+	if e.Level < opts.Level || !opts.Since.IsZero() && e.Time.Before(opts.Since) || !opts.Until.IsZero() && !e.Time.Before(opts.Until) {
+		return nil, false
+	}
+	if opts.Formatter == nil {
+		return line, true
+	}
+	var buf bytes.Buffer
+	opts.Formatter.Format(&buf, &e)
+	return buf.Bytes(), true
+}
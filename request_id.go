@@ -0,0 +1,137 @@
+package spoor
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestID is the length of the longest X-Request-ID header accepted.
+const maxRequestID = 128
+
+type requestIDKey struct{}
+
+type loggerKey struct{}
+
+// RequestIDMiddleware takes the request id from X-Request-ID or the trace id
+// of a W3C traceparent header, generating one if neither is set. An
+// X-Request-ID longer than 128 bytes or with other characters than letters,
+// digits and "-._:" is ignored, so that clients cannot forge lines or blow
+// up the size of every entry of the request. The id is
+// echoed in the response headers, and a logger carrying request_id, and the
+// TraceFields of the traceparent header if any, is stored in the request
+// context, see FromContext.
+func (l *Spoor) RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, logger := l.RequestLogger(r.Header.Get)
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), id, logger)))
+	})
+}
+
+// RequestLogger returns the id of a request whose headers are read with
+// header, and the request scoped logger of RequestIDMiddleware, for the
+// middlewares of frameworks not built on net/http.
+func (l *Spoor) RequestLogger(header func(key string) string) (id string, logger *Spoor) {
+	id = requestID(header)
+	logger = l.WithRequestID(id)
+	if fields := TraceFields(header("traceparent")); fields != nil {
+		logger = logger.With(fields...)
+	}
+	return id, logger
+}
+
+// NewContext returns a copy of ctx carrying the request id and logger, see
+// FromContext and RequestIDFromContext.
+func NewContext(ctx context.Context, id string, logger *Spoor) context.Context {
+	ctx = context.WithValue(ctx, requestIDKey{}, id)
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// WithRequestID returns a logger whose entries carry request_id=id.
+func (l *Spoor) WithRequestID(id string) *Spoor {
+	c := l.With(String("request_id", id))
+	c.Logger = prefixLogger{Logger: l.Logger, prefix: "request_id=" + id + " "}
+	return c
+}
+
+// FromContext returns the request scoped logger stored by
+// RequestIDMiddleware, or nil.
+func FromContext(ctx context.Context) *Spoor {
+	l, _ := ctx.Value(loggerKey{}).(*Spoor)
+	return l
+}
+
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func requestID(header func(key string) string) string {
+	if id := header(RequestIDHeader); validRequestID(id) {
+		return id
+	}
+	if traceID, _, _, ok := parseTraceparent(header("traceparent")); ok {
+		return traceID
+	}
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestID {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		switch c := id[i]; {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '.', c == '_', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// TraceFields returns the trace_id, span_id and trace_flags fields of a W3C
+// traceparent header, so that entries can be joined with the traces of the
+// same request, or nil if the header is not valid.
+func TraceFields(traceparent string) []Field {
+	traceID, spanID, flags, ok := parseTraceparent(traceparent)
+	if !ok {
+		return nil
+	}
+	return []Field{String("trace_id", traceID), String("span_id", spanID), String("trace_flags", flags)}
+}
+
+// parseTraceparent splits version-traceid-parentid-flags, rejecting the
+// invalid all zero ids.
+func parseTraceparent(h string) (traceID, spanID, flags string, ok bool) {
+	parts := strings.Split(h, "-")
+	if len(parts) < 4 || parts[0] == "00" && len(parts) != 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return "", "", "", false
+	}
+	for _, part := range parts[:4] {
+		if _, err := hex.DecodeString(part); err != nil || strings.ToLower(part) != part {
+			return "", "", "", false
+		}
+	}
+	if strings.Trim(parts[1], "0") == "" || strings.Trim(parts[2], "0") == "" {
+		return "", "", "", false
+	}
+	return parts[1], parts[2], parts[3], true
+}
+
+// prefixLogger prepends prefix to every message passed to Output.
+type prefixLogger struct {
+	Logger
+	prefix string
+}
+
+func (p prefixLogger) Output(callerSkip int, s string) error {
+	return p.Logger.Output(callerSkip+1, p.prefix+s)
+}
@@ -0,0 +1,102 @@
+package spoor
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy decides whether and when the remote writers resend a failed
+// request: exponential backoff with jitter, bounded by a number of retries
+// and, optionally, by the time spent since the first attempt. Zero values
+// take the defaults.
+type RetryPolicy struct {
+	MaxRetries     int           // defaults to 3, negative disables the retries
+	InitialBackoff time.Duration // defaults to 200ms
+	MaxBackoff     time.Duration // defaults to 30s
+	Multiplier     float64       // defaults to 2
+	// Jitter randomizes every backoff by up to this fraction of it, 0.2 by
+	// default, negative disables it, so that clients do not retry in step.
+	Jitter float64
+	// MaxElapsed stops the retries once this long has passed since the
+	// first attempt, if set.
+	MaxElapsed time.Duration
+	// Retryable reports whether err is worth a retry, it defaults to the
+	// errors matching ErrRemoteUnavailable: network failures, 429 and 5xx.
+	Retryable func(err error) bool
+}
+
+// withDefaults fills the zero values of p, the writer's MaxRetries and
+// RetryBackoff first.
+func (p RetryPolicy) withDefaults(maxRetries int, backoff time.Duration) RetryPolicy {
+	if p.MaxRetries == 0 {
+		p.MaxRetries = maxRetries
+	}
+	if p.MaxRetries == 0 {
+		p.MaxRetries = 3
+	}
+	if p.InitialBackoff == 0 {
+		p.InitialBackoff = backoff
+	}
+	if p.InitialBackoff == 0 {
+		p.InitialBackoff = time.Millisecond * 200
+	}
+	if p.MaxBackoff == 0 {
+		p.MaxBackoff = time.Second * 30
+	}
+	if p.Multiplier == 0 {
+		p.Multiplier = 2
+	}
+	if p.Jitter == 0 {
+		p.Jitter = 0.2
+	}
+	if p.Retryable == nil {
+		p.Retryable = func(err error) bool { return errors.Is(err, ErrRemoteUnavailable) }
+	}
+	return p
+}
+
+// backoff returns the wait before the retry following attempt, counted from
+// 0.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	d := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(attempt))
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (2*rand.Float64() - 1)
+	}
+	if max := float64(p.MaxBackoff); d > max {
+		d = max
+	}
+	return time.Duration(d)
+}
+
+// next returns the wait before retrying after attempt failed with err, and
+// false if it should not be retried.
+func (p *RetryPolicy) next(attempt int, start time.Time, err error) (time.Duration, bool) {
+	if attempt >= p.MaxRetries || !p.Retryable(err) {
+		return 0, false
+	}
+	d := p.backoff(attempt)
+	if p.MaxElapsed > 0 && time.Since(start)+d > p.MaxElapsed {
+		return 0, false
+	}
+	return d, true
+}
+
+// Do calls fn until it succeeds or its error is not to be retried, and
+// returns the last error.
+func (p RetryPolicy) Do(fn func() error) error {
+	p = p.withDefaults(0, 0)
+	start := time.Now()
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		d, ok := p.next(attempt, start, err)
+		if !ok {
+			return err
+		}
+		time.Sleep(d)
+	}
+}
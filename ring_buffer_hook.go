@@ -0,0 +1,159 @@
+package spoor
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RingBufferHook keeps the last entries in memory. It is an http.Handler
+// rendering them, so that operators can look at the recent logs of a running
+// process:
+//
+//	GET /debug/logs?level=warn&field=user=alice&format=html
+//
+// level is the minimum level, field may be repeated and format is json, the
+// default, or html.
+type RingBufferHook struct {
+	levels  []Level
+	entries *ring
+}
+
+// NewRingBufferHook returns a hook keeping the last size entries, 1000 if
+// size is 0, logged at one of levels or at any level if none is given.
+func NewRingBufferHook(size int, levels ...Level) *RingBufferHook {
+	if size <= 0 {
+		size = 1000
+	}
+	return &RingBufferHook{levels: levels, entries: newRing(size)}
+}
+
+func (h *RingBufferHook) Levels() []Level {
+	return h.levels
+}
+
+func (h *RingBufferHook) Fire(e *Entry) error {
+	h.entries.add(e)
+	return nil
+}
+
+// Entries returns the kept entries, oldest first.
+func (h *RingBufferHook) Entries() []Entry {
+	return h.entries.list()
+}
+
+func (h *RingBufferHook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	minLevel := DEBUG
+	if s := q.Get("level"); s != "" {
+		lvl, err := ParseLogLevel(s)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		minLevel = lvl
+	}
+	var entries []Entry
+next:
+	for _, e := range h.Entries() {
+		if e.Level < minLevel {
+			continue
+		}
+		for _, kv := range q["field"] {
+			key, value, _ := strings.Cut(kv, "=")
+			if !hasField(e.Fields, key, value) {
+				continue next
+			}
+		}
+		entries = append(entries, e)
+	}
+
+	if q.Get("format") == "html" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		ringBufferTemplate.Execute(w, entries)
+		return
+	}
+	var buf bytes.Buffer
+	f := &JSONFormatter{}
+	buf.WriteByte('[')
+	for i := range entries {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		f.Format(&buf, &entries[i])
+	}
+	buf.WriteString("]\n")
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(buf.Bytes())
+}
+
+func hasField(fields []Field, key, value string) bool {
+	for _, f := range fields {
+		if f.Key == key && textValue(f) == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ring is a fixed size buffer of entries, the oldest is overwritten first.
+type ring struct {
+	mu      sync.Mutex
+	entries []Entry
+	next    int
+	full    bool
+}
+
+func newRing(size int) *ring {
+	return &ring{entries: make([]Entry, size)}
+}
+
+// add stores a copy of e which no longer refers to the caller's fields.
+func (r *ring) add(e *Entry) {
+	c := *e
+	c.Fields = append([]Field(nil), e.Fields...)
+	c.Encoded, c.EncodedFields, c.owned = nil, 0, false
+	r.mu.Lock()
+	r.entries[r.next] = c
+	r.next++
+	if r.next == len(r.entries) {
+		r.next, r.full = 0, true
+	}
+	r.mu.Unlock()
+}
+
+func (r *ring) list() []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var entries []Entry
+	if r.full {
+		entries = append(entries, r.entries[r.next:]...)
+	}
+	return append(entries, r.entries[:r.next]...)
+}
+
+// drain returns the entries like list and empties the ring.
+func (r *ring) drain() []Entry {
+	entries := r.list()
+	r.mu.Lock()
+	for i := range r.entries {
+		r.entries[i] = Entry{}
+	}
+	r.next, r.full = 0, false
+	r.mu.Unlock()
+	return entries
+}
+
+var ringBufferTemplate = template.Must(template.New("logs").Funcs(template.FuncMap{
+	"time":  func(t time.Time) string { return t.Format("2006-01-02 15:04:05.000") },
+	"value": textValue,
+}).Parse(`<!DOCTYPE html>
+<html><head><title>recent logs</title>
+<style>body{font-family:monospace}td{padding:0 8px;vertical-align:top}.WARNING{color:#b58900}.ERROR,.FATAL{color:#dc322f}</style>
+</head><body><table>
+{{range .}}<tr class="{{.Level}}"><td>{{time .Time}}</td><td>{{.Level}}</td><td>{{.Message}}</td><td>{{range .Fields}}{{.Key}}={{value .}} {{end}}</td></tr>
+{{end}}</table></body></html>
+`))
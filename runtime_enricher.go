@@ -0,0 +1,43 @@
+package spoor
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+var startTime = time.Now()
+
+// RuntimeEnricher adds the host, os, arch, go_version, pid and start_time
+// fields, and the goroutine id of the logging goroutine if goroutineID is set.
+// Getting the goroutine id costs a stack trace per entry.
+func RuntimeEnricher(goroutineID bool) Enricher {
+	static := StaticEnricher(
+		String("host", host),
+		String("os", runtime.GOOS),
+		String("arch", runtime.GOARCH),
+		String("go_version", runtime.Version()),
+		Int("pid", pid),
+		Time("start_time", startTime),
+	)
+	if !goroutineID {
+		return static
+	}
+	return func(e *Entry) {
+		static(e)
+		e.AddFields(Int64("goroutine", currentGoroutineID()))
+	}
+}
+
+// currentGoroutineID parses the id out of "goroutine 18 [running]:".
+func currentGoroutineID() int64 {
+	var arr [64]byte
+	b := arr[:runtime.Stack(arr[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseInt(string(b), 10, 64)
+	return id
+}
@@ -0,0 +1,286 @@
+package spoor
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ObjectStore is the minimal API the archive writer needs from an object
+// storage service.
+type ObjectStore interface {
+	PutObject(key string, body []byte) error
+}
+
+type S3Store struct {
+	Bucket      string
+	Region      string
+	Endpoint    string // optional, path-style endpoint for S3 compatible stores
+	Credentials AWSCredentials
+	// Transport sets the proxy, TLS or extra headers, the requests are
+	// signed with Credentials so its SigV4 is ignored.
+	Transport HTTPTransport
+	Client    *http.Client
+	once      sync.Once // builds Client from Transport if nil
+}
+
+func (s *S3Store) PutObject(key string, body []byte) error {
+	region := awsRegion(s.Region)
+	url := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", s.Bucket, region, key)
+	if s.Endpoint != "" {
+		url = fmt.Sprintf("%s/%s/%s", strings.TrimRight(s.Endpoint, "/"), s.Bucket, key)
+	}
+	req, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	req.Header.Set("Content-Encoding", "gzip")
+	s.Transport.setHeaders(req)
+	creds := s.Credentials
+	if creds.AccessKeyID == "" {
+		creds = AWSCredentialsFromEnv()
+	}
+	signV4(req, body, creds, region, "s3", time.Now())
+	s.once.Do(func() {
+		if s.Client == nil {
+			s.Client = s.Transport.client(time.Minute)
+		}
+	})
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return unavailable(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		err := fmt.Errorf("s3 put %s: %s: %s", key, resp.Status, bytes.TrimSpace(msg))
+		if retryableStatus(resp.StatusCode) {
+			err = unavailable(err)
+		}
+		return err
+	}
+	return nil
+}
+
+type S3Config struct {
+	Store ObjectStore
+	// KeyTemplate may use {service}, {date}, {hour} and {uuid}.
+	KeyTemplate string
+	Service     string
+	MaxBytes    int // uncompressed bytes per object
+	MaxAge      time.Duration
+	Retry       RetryPolicy
+	// DeadLetter receives a DeadLetterRecord for every entry of an object
+	// which could not be uploaded, see Replay.
+	DeadLetter io.Writer
+}
+
+// S3Writer archives entries as gzip compressed NDJSON objects, starting a new
+// object whenever the current one reaches MaxBytes or MaxAge.
+type S3Writer struct {
+	cfg     S3Config
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	zw      *gzip.Writer
+	size    int
+	opened  time.Time
+	closed  bool
+	uploads chan s3Object
+	closeC  chan struct{}
+	wg      sync.WaitGroup
+	sending sync.WaitGroup // the objects being handed to uploads
+	once    sync.Once
+}
+
+type s3Object struct {
+	key  string
+	body []byte
+}
+
+func NewS3Writer(cfg S3Config) *S3Writer {
+	if cfg.KeyTemplate == "" {
+		cfg.KeyTemplate = "logs/{service}/{date}/{hour}/{uuid}.json.gz"
+	}
+	if cfg.Service == "" {
+		cfg.Service = program
+	}
+	if cfg.MaxBytes == 0 {
+		cfg.MaxBytes = 64 * 1024 * 1024
+	}
+	if cfg.MaxAge == 0 {
+		cfg.MaxAge = time.Minute * 5
+	}
+	cfg.Retry = cfg.Retry.withDefaults(0, time.Second)
+	sw := &S3Writer{
+		cfg:     cfg,
+		uploads: make(chan s3Object, 4),
+		closeC:  make(chan struct{}),
+	}
+	sw.wg.Add(2)
+	go sw.uploadLoop()
+	go sw.ageLoop()
+	return sw
+}
+
+func (sw *S3Writer) Write(p []byte) (n int, err error) {
+	now := time.Now()
+	doc := lineDocument(p, now)
+	if doc == nil {
+		return len(p), nil
+	}
+	sw.mu.Lock()
+	if sw.closed {
+		sw.mu.Unlock()
+		return 0, ErrWriterClosed
+	}
+	if sw.zw == nil {
+		sw.zw = gzip.NewWriter(&sw.buf)
+		sw.opened = now
+	}
+	sw.zw.Write(doc)
+	sw.zw.Write([]byte{'\n'})
+	sw.size += len(doc) + 1
+	if sw.size >= sw.cfg.MaxBytes {
+		sw.upload()
+		return len(p), nil
+	}
+	sw.mu.Unlock()
+	return len(p), nil
+}
+
+// upload hands the current object to the uploader. sw.mu must be held, it is
+// released before waiting for room in the queue so that the other writes
+// and Flush are not blocked meanwhile.
+func (sw *S3Writer) upload() {
+	obj := sw.seal()
+	if obj == nil {
+		sw.mu.Unlock()
+		return
+	}
+	sw.sending.Add(1)
+	sw.mu.Unlock()
+	sw.uploads <- *obj
+	sw.sending.Done()
+}
+
+// seal finishes the current object, sw.mu must be held.
+func (sw *S3Writer) seal() *s3Object {
+	if sw.zw == nil {
+		return nil
+	}
+	sw.zw.Close()
+	body := make([]byte, sw.buf.Len())
+	copy(body, sw.buf.Bytes())
+	obj := &s3Object{key: sw.objectKey(sw.opened), body: body}
+	sw.buf.Reset()
+	sw.zw = nil
+	sw.size = 0
+	return obj
+}
+
+func (sw *S3Writer) objectKey(t time.Time) string {
+	t = t.UTC()
+	return strings.NewReplacer(
+		"{service}", sw.cfg.Service,
+		"{date}", t.Format("2006-01-02"),
+		"{hour}", t.Format("15"),
+		"{uuid}", newUUID(),
+	).Replace(sw.cfg.KeyTemplate)
+}
+
+// Flush uploads the current object, even if it is not full yet.
+func (sw *S3Writer) Flush() error {
+	sw.mu.Lock()
+	obj := sw.seal()
+	sw.mu.Unlock()
+	if obj != nil {
+		return sw.put(*obj)
+	}
+	return nil
+}
+
+// Close uploads the pending objects and stops the writer.
+func (sw *S3Writer) Close() error {
+	sw.once.Do(func() {
+		close(sw.closeC)
+		sw.wg.Wait()
+	})
+	return nil
+}
+
+func (sw *S3Writer) ageLoop() {
+	defer sw.wg.Done()
+	tick := time.Second
+	if sw.cfg.MaxAge < tick {
+		tick = sw.cfg.MaxAge
+	}
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			sw.mu.Lock()
+			if sw.zw != nil && time.Since(sw.opened) >= sw.cfg.MaxAge {
+				sw.upload()
+			} else {
+				sw.mu.Unlock()
+			}
+		case <-sw.closeC:
+			sw.mu.Lock()
+			sw.closed = true
+			sw.upload()
+			// no object is handed over once closed is set
+			sw.sending.Wait()
+			close(sw.uploads)
+			return
+		}
+	}
+}
+
+func (sw *S3Writer) uploadLoop() {
+	defer sw.wg.Done()
+	for obj := range sw.uploads {
+		sw.put(obj)
+	}
+}
+
+// put uploads obj with retries, its entries go to the dead letter writer if
+// it cannot be.
+func (sw *S3Writer) put(obj s3Object) error {
+	err := sw.cfg.Retry.Do(func() error { return sw.cfg.Store.PutObject(obj.key, obj.body) })
+	if err == nil {
+		return nil
+	}
+	if sw.cfg.DeadLetter == nil {
+		internalError(fmt.Errorf("spoor: s3 dropped object %s: %w", obj.key, err))
+		return err
+	}
+	zr, zerr := gzip.NewReader(bytes.NewReader(obj.body))
+	if zerr != nil {
+		return err
+	}
+	data, _ := io.ReadAll(zr)
+	lines := bytes.SplitAfter(data, []byte{'\n'})
+	if len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	if derr := writeDeadLetters(sw.cfg.DeadLetter, lines, err); derr != nil {
+		internalError(fmt.Errorf("spoor: s3 dead letter: %w", derr))
+	}
+	return err
+}
+
+func newUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
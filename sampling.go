@@ -0,0 +1,140 @@
+package spoor
+
+import (
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+const sampleBuckets = 1024
+
+// sampler lets through the first entries with a given level and message in
+// every tick, then only every thereafter-th one.
+type sampler struct {
+	tick       time.Duration
+	first      uint64
+	thereafter uint64
+	rate       float64 // see WithSampleRate
+	byRate     bool    // rate is used instead of the counters
+	counters   [FATAL + 1][sampleBuckets]sampleCounter
+	kept       uint64
+	sampledOut uint64
+	pending    uint64 // sampled out since the last summary
+	summaryAt  int64  // time of the next summary, see WithSamplingSummary
+}
+
+type sampleCounter struct {
+	resetAt int64
+	count   uint64
+}
+
+// WithSampling caps repeated entries: per tick, the first entries with the
+// same level and message are logged and after that only every thereafter-th.
+func WithSampling(tick time.Duration, first, thereafter int) Option {
+	return func(spoor *Spoor) {
+		if thereafter < 1 {
+			thereafter = 1
+		}
+		spoor.sampler = &sampler{tick: tick, first: uint64(first), thereafter: uint64(thereafter)}
+	}
+}
+
+// WithSampleRate keeps about rate, between 0 and 1, of the entries, picked
+// at random whatever their message, none at 0. It replaces WithSampling.
+func WithSampleRate(rate float64) Option {
+	return func(spoor *Spoor) {
+		spoor.sampler = &sampler{rate: rate, byRate: true}
+	}
+}
+
+// WithSamplingSummary makes the logger write a WARN entry, such as
+// "suppressed 1423 similar messages in last 10s", at most once per interval
+// when its sampler dropped entries, so that it is known the log is sampled.
+// The summary is written with the first entry logged once interval passed.
+func WithSamplingSummary(interval time.Duration) Option {
+	return func(spoor *Spoor) {
+		spoor.samplingSummary = interval
+	}
+}
+
+// SamplingMetrics counts the entries seen by the sampler of a logger.
+type SamplingMetrics struct {
+	Kept       uint64
+	SampledOut uint64
+}
+
+func (s *sampler) check(level Level, msg string, now time.Time) bool {
+	if level < DEBUG || level > FATAL {
+		return true
+	}
+	if s.sample(level, msg, now) {
+		atomic.AddUint64(&s.kept, 1)
+		return true
+	}
+	atomic.AddUint64(&s.sampledOut, 1)
+	atomic.AddUint64(&s.pending, 1)
+	return false
+}
+
+func (s *sampler) sample(level Level, msg string, now time.Time) bool {
+	if s.byRate {
+		return s.rate >= 1 || s.rate > 0 && rand.Float64() < s.rate
+	}
+	c := &s.counters[level][fnv32a(msg)%sampleBuckets]
+	n := c.inc(now.UnixNano(), s.tick.Nanoseconds())
+	if n <= s.first {
+		return true
+	}
+	return (n-s.first)%s.thereafter == 0
+}
+
+// summary returns the entries sampled out since the last summary, once per
+// interval.
+func (s *sampler) summary(now time.Time, interval time.Duration) (uint64, bool) {
+	at := atomic.LoadInt64(&s.summaryAt)
+	if at == 0 {
+		atomic.CompareAndSwapInt64(&s.summaryAt, 0, now.Add(interval).UnixNano())
+		return 0, false
+	}
+	if now.UnixNano() < at || !atomic.CompareAndSwapInt64(&s.summaryAt, at, now.Add(interval).UnixNano()) {
+		return 0, false
+	}
+	n := atomic.SwapUint64(&s.pending, 0)
+	return n, n > 0
+}
+
+func (l *Spoor) writeSamplingSummary(now time.Time) {
+	n, ok := l.sampler.summary(now, l.samplingSummary)
+	if !ok {
+		return
+	}
+	l.write(&Entry{
+		Time:    now,
+		Level:   WARN,
+		Message: fmt.Sprintf("suppressed %d similar messages in last %v", n, l.samplingSummary),
+		Fields:  []Field{Int64("sampled_out", int64(n))},
+	})
+}
+
+func (c *sampleCounter) inc(now, tick int64) uint64 {
+	resetAt := atomic.LoadInt64(&c.resetAt)
+	if resetAt > now {
+		return atomic.AddUint64(&c.count, 1)
+	}
+	atomic.StoreUint64(&c.count, 1)
+	if !atomic.CompareAndSwapInt64(&c.resetAt, resetAt, now+tick) {
+		// another goroutine started the new tick
+		return atomic.AddUint64(&c.count, 1)
+	}
+	return 1
+}
+
+func fnv32a(s string) uint32 {
+	h := uint32(2166136261)
+	for i := 0; i < len(s); i++ {
+		h ^= uint32(s[i])
+		h *= 16777619
+	}
+	return h
+}
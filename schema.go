@@ -0,0 +1,110 @@
+package spoor
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// SchemaPolicy tells what happens to entries violating a LogSchema.
+type SchemaPolicy int
+
+const (
+	// SchemaAnnotate writes the entry with a schema_violations field.
+	SchemaAnnotate SchemaPolicy = iota
+	// SchemaTruncate shortens values over MaxLength and annotates the other
+	// violations.
+	SchemaTruncate
+	// SchemaDrop does not write the entry.
+	SchemaDrop
+)
+
+// FieldRule constrains the field of a key. A zero Type accepts any type and a
+// zero MaxLength any length of string values.
+type FieldRule struct {
+	Type      FieldType
+	Required  bool
+	MaxLength int
+}
+
+// LogSchema is checked against every entry of a logger, see WithSchema.
+type LogSchema struct {
+	Fields map[string]FieldRule
+	// AllowUnknown accepts fields missing from Fields.
+	AllowUnknown bool
+	Policy       SchemaPolicy
+}
+
+// WithSchema validates the entries of the logger against schema before they
+// are written.
+func WithSchema(schema *LogSchema) Option {
+	return func(spoor *Spoor) {
+		spoor.schema = schema
+	}
+}
+
+// Validate returns the violations of the entry, nil if it conforms.
+func (s *LogSchema) Validate(e *Entry) []string {
+	var violations []string
+	seen := make(map[string]bool, len(e.Fields))
+	for _, f := range e.Fields {
+		seen[f.Key] = true
+		rule, ok := s.Fields[f.Key]
+		if !ok {
+			if !s.AllowUnknown {
+				violations = append(violations, fmt.Sprintf("unknown field %s", f.Key))
+			}
+			continue
+		}
+		if rule.Type != 0 && f.Type != rule.Type {
+			violations = append(violations, fmt.Sprintf("field %s is %s, want %s", f.Key, f.Type, rule.Type))
+		}
+		if rule.MaxLength > 0 && f.Type == StringType && utf8.RuneCountInString(f.Str) > rule.MaxLength {
+			violations = append(violations, fmt.Sprintf("field %s longer than %d", f.Key, rule.MaxLength))
+		}
+	}
+	for key, rule := range s.Fields {
+		if rule.Required && !seen[key] {
+			violations = append(violations, fmt.Sprintf("missing field %s", key))
+		}
+	}
+	return violations
+}
+
+// apply enforces the policy on e and reports whether e should be written.
+func (s *LogSchema) apply(e *Entry) bool {
+	violations := s.Validate(e)
+	if len(violations) == 0 {
+		return true
+	}
+	switch s.Policy {
+	case SchemaDrop:
+		return false
+	case SchemaTruncate:
+		e.Fields = append([]Field(nil), e.Fields...)
+		for i, f := range e.Fields {
+			rule := s.Fields[f.Key]
+			if rule.MaxLength > 0 && f.Type == StringType && utf8.RuneCountInString(f.Str) > rule.MaxLength {
+				e.Fields[i].Str = truncateRunes(f.Str, rule.MaxLength)
+				if i < e.EncodedFields {
+					e.Encoded, e.EncodedFields = nil, 0
+				}
+			}
+		}
+		if violations = s.Validate(e); len(violations) == 0 {
+			return true
+		}
+	}
+	e.AddFields(String("schema_violations", strings.Join(violations, "; ")))
+	return true
+}
+
+func truncateRunes(s string, n int) string {
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
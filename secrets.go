@@ -0,0 +1,126 @@
+package spoor
+
+import (
+	"math"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+var (
+	secretPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`),                            // AWS access key id
+		regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`), // JWT
+		regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`),
+	}
+	secretToken = regexp.MustCompile(`[A-Za-z0-9+/=_-]{20,}`)
+)
+
+// SecretsEnricher scans the message and the string, error, byte slice and
+// Any values of every entry for AWS keys, JWTs, private key headers and high
+// entropy tokens. It lists where it found some in a secrets_redacted field
+// and, if mask is set, replaces them with [REDACTED], the values which are
+// not strings becoming the masked string of their text. It is an Enricher
+// rather than a Hook so that secrets are masked before the entry reaches
+// hooks or the flight recorder.
+func SecretsEnricher(mask bool) Enricher {
+	return func(e *Entry) {
+		var found []string
+		if s, ok := scanSecrets(e.Message, mask); ok {
+			found = append(found, "msg")
+			e.Message = s
+		}
+		copied := false
+		for i, f := range e.Fields {
+			text, ok := valueText(f)
+			if !ok {
+				continue
+			}
+			s, ok := scanSecrets(text, mask)
+			if !ok {
+				continue
+			}
+			found = append(found, f.Key)
+			if !mask {
+				continue
+			}
+			if !copied {
+				e.Fields = append([]Field(nil), e.Fields...)
+				copied = true
+			}
+			e.Fields[i] = String(f.Key, s)
+			if i < e.EncodedFields {
+				e.Encoded, e.EncodedFields = nil, 0
+			}
+		}
+		if len(found) > 0 {
+			e.AddFields(String("secrets_redacted", strings.Join(found, ",")))
+		}
+	}
+}
+
+// scanSecrets reports whether s contains secrets and returns s with them
+// masked if mask is set.
+func scanSecrets(s string, mask bool) (string, bool) {
+	found := false
+	for _, re := range secretPatterns {
+		if re.MatchString(s) {
+			found = true
+			if !mask {
+				return s, true
+			}
+			s = re.ReplaceAllLiteralString(s, redacted)
+		}
+	}
+	s = secretToken.ReplaceAllStringFunc(s, func(token string) string {
+		if !randomToken(token) {
+			return token
+		}
+		found = true
+		if !mask {
+			return token
+		}
+		return redacted
+	})
+	return s, found
+}
+
+// randomToken reports whether token looks random rather than like a word,
+// path or identifier: it mixes letter cases or letters and digits, and its
+// entropy is at least 85% of the highest possible for its length with a
+// base64 alphabet, which a 20 bytes token can only reach with few repeated
+// characters.
+func randomToken(token string) bool {
+	var lower, upper, digit int
+	for i := 0; i < len(token); i++ {
+		switch c := token[i]; {
+		case c >= 'a' && c <= 'z':
+			lower = 1
+		case c >= 'A' && c <= 'Z':
+			upper = 1
+		case c >= '0' && c <= '9':
+			digit = 1
+		}
+	}
+	if lower+upper+digit < 2 {
+		return false
+	}
+	return entropy(token) >= 0.85*math.Log2(math.Min(float64(len(token)), 64))
+}
+
+// entropy returns the Shannon entropy of s in bits per byte.
+func entropy(s string) float64 {
+	var counts [256]int
+	for i := 0; i < len(s); i++ {
+		counts[s[i]]++
+	}
+	var h float64
+	for _, c := range counts {
+		if c > 0 {
+			p := float64(c) / float64(len(s))
+			h -= p * math.Log2(p)
+		}
+	}
+	return h
+}
@@ -0,0 +1,40 @@
+package spoor
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// The keys of the fields added by WithSequence.
+const (
+	SeqKey  = "seq"
+	MonoKey = "mono"
+)
+
+type sequence struct {
+	n     uint64
+	start time.Time // holds the monotonic clock reading
+	mono  bool
+}
+
+// WithSequence numbers the written entries of the logger, and of the loggers
+// derived from it with With, from 1 with the seq field, so that gaps reveal
+// lost entries and entries written out of order, e.g. by an AsyncWriter with
+// several workers, can be sorted back. With monotonic, the mono field holds
+// the nanoseconds elapsed since the option was applied, read from the
+// monotonic clock, which unlike the entry time does not jump with the wall
+// clock. Entries removed by the level or the sampler are not numbered.
+func WithSequence(monotonic bool) Option {
+	return func(spoor *Spoor) {
+		spoor.seq = &sequence{start: time.Now(), mono: monotonic}
+	}
+}
+
+func (s *sequence) stamp(e *Entry) {
+	seq := Int64(SeqKey, int64(atomic.AddUint64(&s.n, 1)))
+	if !s.mono {
+		e.AddFields(seq)
+		return
+	}
+	e.AddFields(seq, Int64(MonoKey, int64(time.Since(s.start))))
+}
@@ -0,0 +1,96 @@
+package spoor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var closers struct {
+	mu   sync.Mutex
+	list []io.Closer
+}
+
+// Register adds c to the writers and loggers closed by Shutdown. They are
+// closed in the reverse order of registration, so a writer wrapping another
+// one should be registered after it.
+func Register(c io.Closer) {
+	closers.mu.Lock()
+	closers.list = append(closers.list, c)
+	closers.mu.Unlock()
+}
+
+// registered returns a copy of the registered closers.
+func registered() []io.Closer {
+	closers.mu.Lock()
+	defer closers.mu.Unlock()
+	return append([]io.Closer(nil), closers.list...)
+}
+
+// Shutdown closes, which flushes, the registered closers and forgets them. It
+// returns ctx.Err() if ctx is done first, the remaining closers are still
+// closed in the background.
+func Shutdown(ctx context.Context) error {
+	closers.mu.Lock()
+	list := closers.list
+	closers.list = nil
+	closers.mu.Unlock()
+
+	done := make(chan error, 1)
+	go func() {
+		var msgs []string
+		for i := len(list) - 1; i >= 0; i-- {
+			if err := list[i].Close(); err != nil {
+				msgs = append(msgs, fmt.Sprintf("%T: %v", list[i], err))
+			}
+		}
+		if len(msgs) > 0 {
+			done <- fmt.Errorf("spoor: shutdown: %s", strings.Join(msgs, "; "))
+			return
+		}
+		done <- nil
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// HandleSignals calls Shutdown, with a deadline of timeout, and exits with
+// status 1 when the process receives one of signals, SIGINT and SIGTERM if
+// none is given. It returns a function stopping the handling.
+func HandleSignals(timeout time.Duration, signals ...os.Signal) (stop func()) {
+	if len(signals) == 0 {
+		signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	c := make(chan os.Signal, 1)
+	quit := make(chan struct{})
+	signal.Notify(c, signals...)
+	go func() {
+		select {
+		case <-c:
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			if err := Shutdown(ctx); err != nil {
+				internalError(err)
+			}
+			cancel()
+			os.Exit(1)
+		case <-quit:
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(c)
+			close(quit)
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package spoor
+
+import (
+	"bytes"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CEFFormatter writes ArcSight Common Event Format lines:
+// CEF:0|Vendor|Product|Version|SignatureID|message|severity|rt=... key=value
+type CEFFormatter struct {
+	Vendor  string // defaults to "spoor"
+	Product string // defaults to the program name
+	Version string
+	// SignatureKey names the field holding the event class id, the level is
+	// used when it is empty or missing.
+	SignatureKey string
+	// KeyMap maps field keys to CEF extension keys, e.g. "user" to "suser".
+	KeyMap map[string]string
+}
+
+var cefHeaderEscaper = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\n", " ", "\r", " ")
+
+var cefValueEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\n", `\n`, "\r", `\r`)
+
+func (f *CEFFormatter) Format(buf *bytes.Buffer, e *Entry) {
+	signature := e.Level.String()
+	buf.WriteString("CEF:0|")
+	buf.WriteString(cefHeaderEscaper.Replace(defaultString(f.Vendor, "spoor")))
+	buf.WriteByte('|')
+	buf.WriteString(cefHeaderEscaper.Replace(defaultString(f.Product, program)))
+	buf.WriteByte('|')
+	buf.WriteString(cefHeaderEscaper.Replace(f.Version))
+	buf.WriteByte('|')
+	for _, field := range e.Fields {
+		if f.SignatureKey != "" && field.Key == f.SignatureKey {
+			signature = textValue(field)
+		}
+	}
+	buf.WriteString(cefHeaderEscaper.Replace(signature))
+	buf.WriteByte('|')
+	buf.WriteString(cefHeaderEscaper.Replace(e.Message))
+	buf.WriteByte('|')
+	buf.WriteString(strconv.Itoa(siemSeverity(e.Level)))
+	buf.WriteString("|rt=")
+	buf.WriteString(strconv.FormatInt(e.Time.UnixNano()/1e6, 10))
+	if e.File != "" {
+		buf.WriteString(" fname=")
+		buf.WriteString(cefValueEscaper.Replace(e.File + ":" + strconv.Itoa(e.Line)))
+	}
+	for _, field := range e.Fields {
+		if f.SignatureKey != "" && field.Key == f.SignatureKey {
+			continue
+		}
+		buf.WriteByte(' ')
+		buf.WriteString(siemKey(field.Key, f.KeyMap))
+		buf.WriteByte('=')
+		buf.WriteString(cefValueEscaper.Replace(textValue(field)))
+	}
+	buf.WriteByte('\n')
+}
+
+// LEEFFormatter writes IBM QRadar LEEF 2.0 lines with tab separated
+// attributes:
+// LEEF:2.0|Vendor|Product|Version|EventID|x09|devTime=...	sev=...	msg=...
+type LEEFFormatter struct {
+	Vendor  string // defaults to "spoor"
+	Product string // defaults to the program name
+	Version string
+	// EventIDKey names the field holding the event id, the level is used
+	// when it is empty or missing.
+	EventIDKey string
+	// KeyMap maps field keys to LEEF attribute names, e.g. "user" to "usrName".
+	KeyMap map[string]string
+}
+
+const leefTimeFormat = "Jan 02 2006 15:04:05.000 MST"
+
+var leefHeaderEscaper = strings.NewReplacer("\t", " ", "\n", " ", "\r", " ", "|", `\|`)
+
+var leefValueEscaper = strings.NewReplacer("\t", " ", "\n", " ", "\r", " ")
+
+func (f *LEEFFormatter) Format(buf *bytes.Buffer, e *Entry) {
+	eventID := e.Level.String()
+	for _, field := range e.Fields {
+		if f.EventIDKey != "" && field.Key == f.EventIDKey {
+			eventID = textValue(field)
+		}
+	}
+	buf.WriteString("LEEF:2.0|")
+	buf.WriteString(leefHeaderEscaper.Replace(defaultString(f.Vendor, "spoor")))
+	buf.WriteByte('|')
+	buf.WriteString(leefHeaderEscaper.Replace(defaultString(f.Product, program)))
+	buf.WriteByte('|')
+	buf.WriteString(leefHeaderEscaper.Replace(f.Version))
+	buf.WriteByte('|')
+	buf.WriteString(leefHeaderEscaper.Replace(eventID))
+	buf.WriteString("|x09|devTime=")
+	buf.WriteString(e.Time.Format(leefTimeFormat))
+	buf.WriteString("\tdevTimeFormat=MMM dd yyyy HH:mm:ss.SSS z\tsev=")
+	buf.WriteString(strconv.Itoa(siemSeverity(e.Level)))
+	buf.WriteString("\tmsg=")
+	buf.WriteString(leefValueEscaper.Replace(e.Message))
+	for _, field := range e.Fields {
+		if f.EventIDKey != "" && field.Key == f.EventIDKey {
+			continue
+		}
+		buf.WriteByte('\t')
+		buf.WriteString(siemKey(field.Key, f.KeyMap))
+		buf.WriteByte('=')
+		buf.WriteString(leefValueEscaper.Replace(textValue(field)))
+	}
+	buf.WriteByte('\n')
+}
+
+// siemSeverity maps levels onto the 0-10 scale used by CEF and LEEF.
+func siemSeverity(level Level) int {
+	switch level {
+	case DEBUG:
+		return 1
+	case INFO:
+		return 3
+	case WARN:
+		return 5
+	case ERROR:
+		return 8
+	case FATAL:
+		return 10
+	}
+	return 0
+}
+
+// siemKey maps key through keyMap and strips the characters not allowed in
+// extension keys.
+func siemKey(key string, keyMap map[string]string) string {
+	if mapped, ok := keyMap[key]; ok {
+		return mapped
+	}
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '.' {
+			return r
+		}
+		return -1
+	}, key)
+}
+
+// textValue returns the field value as an unquoted string.
+func textValue(f Field) string {
+	switch f.Type {
+	case StringType:
+		return f.Str
+	case IntType:
+		return strconv.FormatInt(f.Integer, 10)
+	case FloatType:
+		return strconv.FormatFloat(math.Float64frombits(uint64(f.Integer)), 'g', -1, 64)
+	case DurationType:
+		return time.Duration(f.Integer).String()
+	case TimeType:
+		return f.time().Format(time.RFC3339Nano)
+	case ErrorType:
+		if err, ok := f.Interface.(error); ok && err != nil {
+			return err.Error()
+		}
+		return "<nil>"
+	}
+	return sprintValue(f.Value())
+}
+
+func defaultString(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
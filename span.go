@@ -0,0 +1,41 @@
+package spoor
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"sync/atomic"
+)
+
+// SpanKey is the key of the field set by WithSpan.
+const SpanKey = "span"
+
+type span struct {
+	id       string
+	children uint32
+}
+
+// WithSpan returns a child logger whose entries carry a span field with a
+// hierarchical id: a random root id for a logger without span, then the id of
+// the parent followed by a counter, e.g. 9f86d081, 9f86d081.1, 9f86d081.1.2.
+// Entries of a call flow share the prefix of its root span.
+func (l *Spoor) WithSpan() *Spoor {
+	c := *l
+	if l.span == nil {
+		var b [4]byte
+		rand.Read(b[:])
+		c.span = &span{id: hex.EncodeToString(b[:])}
+	} else {
+		n := atomic.AddUint32(&l.span.children, 1)
+		c.span = &span{id: l.span.id + "." + strconv.FormatUint(uint64(n), 10)}
+	}
+	return &c
+}
+
+// SpanID returns the span id set by WithSpan, or "".
+func (l *Spoor) SpanID() string {
+	if l.span == nil {
+		return ""
+	}
+	return l.span.id
+}
@@ -1,15 +1,91 @@
 package spoor
 
 import (
+	"bytes"
+	"context"
+	"fmt"
 	"io"
 	"log"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type Spoor struct {
 	Logger
-	cfgLevel Level
-	prefix   string
-	flag     int
+	cfgLevel  Level
+	prefix    string
+	flag      int
+	out       *output // shared with derived loggers
+	formatter Formatter
+	fields    []Field
+	tip       *int64 // length of fields claimed by a derived logger, see With
+	encoded   []byte // fields rendered by formatter, if it is a FieldEncoder
+	clock     Clock
+	sampler   *sampler
+	hooks     []Hook
+	recorder  *flightRecorder
+	enrichers []Enricher
+	pipeline  []Stage
+	schema    *LogSchema
+	transform *FieldTransform
+	limits    Limits
+	audit     *output
+	onError   func(e *Entry, err error)
+	debug     *ring // set by WithDebugBuffer
+	span      *span
+	verbosity *Verbosity
+	seq       *sequence // shared with derived loggers, see WithSequence
+	// contextFields and spanEvents are used by WithContext, which sets ctx
+	contextFields func(ctx context.Context) []Field
+	spanEvents    SpanRecorder
+	ctx           context.Context
+	// fatalDeadline bounds the flush of Fatal, see WithFatalDeadline
+	fatalDeadline time.Duration
+	// samplingSummary is the interval of WithSamplingSummary
+	samplingSummary time.Duration
+	fieldPool       *sync.Pool // of *[]Field, see WithFieldCapacity
+}
+
+type output struct {
+	mu    sync.Mutex
+	w     io.Writer
+	dests destinations // set by WithDestinations
+}
+
+// levelWriterTo is implemented by writers routing the entries by level, such
+// as LevelRouterWriter.
+type levelWriterTo interface {
+	WriteLevel(level Level, p []byte) (int, error)
+}
+
+// writeLevel writes p, an entry of level, to w, with WriteLevel if w has it.
+func writeLevel(w io.Writer, level Level, p []byte) error {
+	if lw, ok := w.(levelWriterTo); ok {
+		_, err := lw.WriteLevel(level, p)
+		return err
+	}
+	_, err := w.Write(p)
+	return err
+}
+
+func (o *output) write(level Level, p []byte) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.dests != nil {
+		return o.dests.writeLevel(level, p)
+	}
+	return writeLevel(o.w, level, p)
+}
+
+func (o *output) writeEntry(e *Entry, p []byte, format func(f Formatter, e *Entry) []byte) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.dests != nil {
+		return o.dests.writeEntry(e, p, format)
+	}
+	return writeLevel(o.w, e.Level, p)
 }
 
 type Option func(spoor *Spoor)
@@ -21,17 +97,40 @@ func WithFileWriter(writer *FileWriter) Option {
 	}
 }
 
+func WithElasticWriter(writer *ElasticWriter) Option {
+	return func(spoor *Spoor) {
+		spoor.SetOutput(writer)
+	}
+}
+
+func WithLogbusWriter(writer *LogbusWriter) Option {
+	return func(spoor *Spoor) {
+		spoor.SetOutput(writer)
+	}
+}
+
 func WithConsoleWriter(writer io.Writer) Option {
 	return func(spoor *Spoor) {
 		spoor.SetOutput(writer)
 	}
 }
 
+func WithFormatter(formatter Formatter) Option {
+	return func(spoor *Spoor) {
+		spoor.formatter = formatter
+	}
+}
+
 func NewSpoor(cfgLevel Level, prefix string, flag int, opts ...Option) *Spoor {
 	logger := log.New(io.Discard, prefix, flag)
 	s := &Spoor{
-		Logger:   logger,
-		cfgLevel: cfgLevel,
+		Logger:    logger,
+		cfgLevel:  cfgLevel,
+		prefix:    prefix,
+		flag:      flag,
+		out:       &output{w: io.Discard},
+		formatter: &TextFormatter{Flag: flag, Prefix: prefix},
+		clock:     systemClock{},
 	}
 	for _, opt := range opts {
 		opt(s)
@@ -39,16 +138,201 @@ func NewSpoor(cfgLevel Level, prefix string, flag int, opts ...Option) *Spoor {
 	return s
 }
 
+func (l *Spoor) SetOutput(w io.Writer) {
+	l.out.mu.Lock()
+	l.out.w, l.out.dests = w, nil
+	l.out.mu.Unlock()
+	l.Logger.SetOutput(w)
+}
+
 func (l *Spoor) CheckLevel(level Level) bool {
-	if level >= l.cfgLevel {
+	if level >= l.cfgLevel && level < Disabled {
 		return false
 	}
 	return true
 }
 
+// Enabled reports whether entries at level are written, so that callers can
+// skip building expensive messages or fields otherwise:
+//
+//	if l.Enabled(spoor.DEBUG) {
+//		l.Debug("state", spoor.Any("dump", state.Snapshot()))
+//	}
+func (l *Spoor) Enabled(level Level) bool {
+	return !l.CheckLevel(level)
+}
+
+func (l *Spoor) IsDebugEnabled() bool {
+	return l.Enabled(DEBUG)
+}
+
+// With returns a logger which adds fields to every entry.
+func (l *Spoor) With(fields ...Field) *Spoor {
+	if l.transform != nil {
+		fields = l.transform.fields(fields)
+	}
+	c := *l
+	// The first logger derived from l extends the fields and their encoding
+	// in the spare capacity of the arrays of l, so that building a context
+	// one field at a time does not copy it every time; the others copy them.
+	n := len(l.fields)
+	extend := l.tip != nil && cap(l.fields)-n >= len(fields) && atomic.CompareAndSwapInt64(l.tip, int64(n), int64(n+len(fields)))
+	if extend {
+		c.fields = append(l.fields, fields...)
+	} else {
+		c.fields = make([]Field, n, 2*(n+len(fields)))
+		copy(c.fields, l.fields)
+		c.fields = append(c.fields, fields...)
+		c.tip = new(int64)
+		*c.tip = int64(len(c.fields))
+	}
+	if enc, ok := l.formatter.(FieldEncoder); ok {
+		var buf *bytes.Buffer
+		if extend {
+			buf = bytes.NewBuffer(l.encoded)
+		} else {
+			buf = bytes.NewBuffer(make([]byte, 0, 2*len(l.encoded)+64))
+			buf.Write(l.encoded)
+		}
+		enc.EncodeFields(buf, fields)
+		c.encoded = buf.Bytes()
+	}
+	return &c
+}
+
+func (l *Spoor) Debug(msg string, fields ...Field) {
+	l.log(2, DEBUG, msg, fields)
+}
+
+func (l *Spoor) Info(msg string, fields ...Field) {
+	l.log(2, INFO, msg, fields)
+}
+
+func (l *Spoor) Warn(msg string, fields ...Field) {
+	l.log(2, WARN, msg, fields)
+}
+
+func (l *Spoor) Error(msg string, fields ...Field) {
+	l.log(2, ERROR, msg, fields)
+}
+
+// Fatal logs at FATAL level and exits the program with status 1, once the
+// queued entries are written and the writers flushed and closed, or the
+// deadline set with WithFatalDeadline passed.
+func (l *Spoor) Fatal(msg string, fields ...Field) {
+	l.log(2, FATAL, msg, fields)
+	l.exitFatal()
+}
+
+// Log writes an entry at level, callerSkip is the number of frames to skip
+// when recording the caller as for Logger.Output. It lets wrappers such as the
+// logger package keep the caller of the wrapper.
+func (l *Spoor) Log(callerSkip int, level Level, msg string, fields ...Field) {
+	l.log(callerSkip+1, level, msg, fields)
+}
+
+// log writes an entry, callerSkip counts the frames above log like the
+// argument of Logger.Output.
+func (l *Spoor) log(callerSkip int, level Level, msg string, fields []Field) {
+	// dropped entries are still kept by the flight recorder and debug buffer
+	dropped := l.CheckLevel(level)
+	if dropped && l.recorder == nil && l.debug == nil {
+		return
+	}
+	now := l.clock.Now()
+	if l.samplingSummary > 0 && l.sampler != nil {
+		l.writeSamplingSummary(now)
+	}
+	if !dropped && l.sampler != nil && !l.sampler.check(level, msg, now) {
+		if l.recorder == nil {
+			return
+		}
+		dropped = true
+	}
+	e := Entry{Time: now, Level: level, Message: msg, Fields: fields}
+	if l.fieldPool != nil {
+		p := l.fieldPool.Get().(*[]Field)
+		defer l.putFields(p)
+		e.Fields = append(append(*p, l.fields...), fields...)
+		e.owned = true
+	} else if len(l.fields) > 0 {
+		e.Fields = append(l.fields[:len(l.fields):len(l.fields)], fields...)
+	}
+	if len(l.fields) > 0 && l.encoded != nil {
+		e.Encoded, e.EncodedFields = l.encoded, len(l.fields)
+	}
+	if l.flag&(log.Lshortfile|log.Llongfile) != 0 {
+		_, e.File, e.Line, _ = runtime.Caller(callerSkip)
+	}
+	if l.span != nil {
+		e.AddFields(String(SpanKey, l.span.id))
+	}
+	l.enrich(&e)
+	if l.transform != nil {
+		// the fields of With were transformed already
+		n := len(l.fields)
+		e.Fields = append(e.Fields[:n:n], l.transform.fields(e.Fields[n:])...)
+	}
+	for _, stage := range l.pipeline {
+		if !stage(&e) {
+			return
+		}
+	}
+	if l.schema != nil && !l.schema.apply(&e) {
+		return
+	}
+	l.limits.apply(&e)
+	if l.recorder != nil {
+		l.recorder.entries.add(&e)
+	}
+	if dropped {
+		if l.debug != nil && l.CheckLevel(level) {
+			l.debug.add(&e)
+		}
+		return
+	}
+	if l.debug != nil && level >= ERROR {
+		for _, d := range l.debug.drain() {
+			l.write(&d)
+		}
+	}
+	l.write(&e)
+}
+
+// write fires the hooks and writes e to the output.
+func (l *Spoor) write(e *Entry) {
+	if l.seq != nil {
+		l.seq.stamp(e)
+	}
+	if len(l.hooks) > 0 {
+		l.fireHooks(e)
+	}
+	if l.ctx != nil && e.Level >= ERROR {
+		l.spanEvents.RecordEntry(l.ctx, e)
+	}
+	buf := getBuffer()
+	l.limits.format(l.formatter, buf, e)
+	if err := l.out.writeEntry(e, buf.Bytes(), l.formatFor); err != nil {
+		l.entryError(e, fmt.Errorf("spoor: write: %w", err))
+	}
+	putBuffer(buf)
+}
+
+// formatFor formats e with the formatter of a destination.
+func (l *Spoor) formatFor(f Formatter, e *Entry) []byte {
+	var buf bytes.Buffer
+	c := *e
+	c.Encoded, c.EncodedFields = nil, 0 // rendered by the logger's formatter
+	l.limits.format(f, &buf, &c)
+	return buf.Bytes()
+}
+
 type LoggingSetting struct {
 	Dir          string
+	File         string // file name in Dir, see WithFileName
 	Level        int
 	Prefix       string
 	WriterOption Option
+	V            int    // verbosity, see Spoor.V
+	VModule      string // see Verbosity.SetModules
 }
@@ -0,0 +1,199 @@
+// Package spoortest provides a logger recording its entries in memory and
+// assertions on them, for tests of code logging through spoor.
+package spoortest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/phuhao00/spoor"
+)
+
+// Entry is a logged entry as decoded by TestWriter. Field values are the
+// JSON decoded ones: numbers are float64, errors are objects.
+type Entry struct {
+	Time    time.Time
+	Level   spoor.Level
+	Message string
+	Caller  string
+	Fields  map[string]interface{}
+}
+
+// Matcher selects entries, see TestWriter.Find.
+type Matcher func(e Entry) bool
+
+// Level matches the entries logged at level.
+func Level(level spoor.Level) Matcher {
+	return func(e Entry) bool { return e.Level == level }
+}
+
+// Contains matches the entries whose message contains substr.
+func Contains(substr string) Matcher {
+	return func(e Entry) bool { return strings.Contains(e.Message, substr) }
+}
+
+// HasField matches the entries with field key, of any value.
+func HasField(key string) Matcher {
+	return func(e Entry) bool {
+		_, ok := e.Fields[key]
+		return ok
+	}
+}
+
+// FieldEquals matches the entries with field key equal to value, once both
+// are converted to their JSON representation, so FieldEquals("n", 1) matches
+// the field spoor.Int("n", 1).
+func FieldEquals(key string, value interface{}) Matcher {
+	return func(e Entry) bool {
+		v, ok := e.Fields[key]
+		if !ok {
+			return false
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return false
+		}
+		var want interface{}
+		if err := json.Unmarshal(data, &want); err != nil {
+			return false
+		}
+		return reflect.DeepEqual(v, want)
+	}
+}
+
+// TestWriter records the lines of a logger using spoor.JSONFormatter.
+type TestWriter struct {
+	mu      sync.Mutex
+	entries []Entry
+	partial []byte
+}
+
+// New returns a DEBUG logger recording its entries in the returned writer.
+// Options are applied after the writer and formatter are set.
+func New(opts ...spoor.Option) (*spoor.Spoor, *TestWriter) {
+	w := &TestWriter{}
+	opts = append([]spoor.Option{spoor.WithConsoleWriter(w), spoor.WithFormatter(&spoor.JSONFormatter{})}, opts...)
+	return spoor.NewSpoor(spoor.DEBUG, "", 0, opts...), w
+}
+
+// NewGlobal is New for code using the global loggers: spoor.GetLogger
+// returns the recording logger until the end of the test, see
+// spoor.WithTestGlobal.
+func NewGlobal(t testing.TB, opts ...spoor.Option) (*spoor.Spoor, *TestWriter) {
+	t.Helper()
+	l, w := New(opts...)
+	spoor.WithTestGlobal(t, l)
+	return l, w
+}
+
+// NewNop returns a logger discarding everything.
+func NewNop() *spoor.Spoor {
+	return spoor.NewNop()
+}
+
+func (w *TestWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		line := w.partial[:i]
+		w.partial = w.partial[i+1:]
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		e, err := decode(line)
+		if err != nil {
+			return 0, err
+		}
+		w.entries = append(w.entries, e)
+	}
+	return len(p), nil
+}
+
+func decode(line []byte) (Entry, error) {
+	var raw struct {
+		Time   time.Time              `json:"time"`
+		Level  string                 `json:"level"`
+		Msg    string                 `json:"msg"`
+		Caller string                 `json:"caller"`
+		Fields map[string]interface{} `json:"fields"`
+	}
+	if err := json.Unmarshal(line, &raw); err != nil {
+		return Entry{}, fmt.Errorf("spoortest: %v in %q", err, line)
+	}
+	e := Entry{Time: raw.Time, Message: raw.Msg, Caller: raw.Caller, Fields: raw.Fields}
+	for lvl := spoor.DEBUG; lvl <= spoor.FATAL; lvl++ {
+		if raw.Level == lvl.String() {
+			e.Level = lvl
+		}
+	}
+	return e, nil
+}
+
+// Entries returns the entries recorded so far.
+func (w *TestWriter) Entries() []Entry {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]Entry(nil), w.entries...)
+}
+
+// Find returns the entries matching all matchers.
+func (w *TestWriter) Find(matchers ...Matcher) []Entry {
+	var found []Entry
+next:
+	for _, e := range w.Entries() {
+		for _, m := range matchers {
+			if !m(e) {
+				continue next
+			}
+		}
+		found = append(found, e)
+	}
+	return found
+}
+
+// Reset forgets the recorded entries.
+func (w *TestWriter) Reset() {
+	w.mu.Lock()
+	w.entries = nil
+	w.mu.Unlock()
+}
+
+// AssertLogged fails t unless an entry at level whose message contains substr
+// and matching all matchers was recorded, and returns the first one.
+func (w *TestWriter) AssertLogged(t testing.TB, level spoor.Level, substr string, matchers ...Matcher) Entry {
+	t.Helper()
+	found := w.Find(append([]Matcher{Level(level), Contains(substr)}, matchers...)...)
+	if len(found) == 0 {
+		t.Errorf("no %s entry containing %q in:\n%s", level, substr, w.dump())
+		return Entry{}
+	}
+	return found[0]
+}
+
+// AssertNotLogged fails t if an entry at level whose message contains substr
+// and matching all matchers was recorded.
+func (w *TestWriter) AssertNotLogged(t testing.TB, level spoor.Level, substr string, matchers ...Matcher) {
+	t.Helper()
+	if found := w.Find(append([]Matcher{Level(level), Contains(substr)}, matchers...)...); len(found) > 0 {
+		t.Errorf("unexpected %s entry containing %q: %s", level, substr, found[0].Message)
+	}
+}
+
+func (w *TestWriter) dump() string {
+	var b strings.Builder
+	for _, e := range w.Entries() {
+		fmt.Fprintf(&b, "\t%s %s %v\n", e.Level, e.Message, e.Fields)
+	}
+	return b.String()
+}
@@ -0,0 +1,39 @@
+package spoortest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/phuhao00/spoor"
+)
+
+func TestTestWriter(t *testing.T) {
+	l, w := New()
+	l.With(spoor.String("user", "alice")).Warn("login failed", spoor.Int("attempt", 3), spoor.Err(errors.New("bad password")))
+	l.Debug("done")
+
+	e := w.AssertLogged(t, spoor.WARN, "login", FieldEquals("user", "alice"), FieldEquals("attempt", 3))
+	if e.Fields["error"].(map[string]interface{})["message"] != "bad password" {
+		t.Errorf("error field %v", e.Fields["error"])
+	}
+	w.AssertNotLogged(t, spoor.ERROR, "login")
+	if n := len(w.Find(HasField("user"))); n != 1 {
+		t.Errorf("found %d entries with user", n)
+	}
+	if n := len(w.Entries()); n != 2 {
+		t.Errorf("recorded %d entries", n)
+	}
+
+	NewNop().Error("dropped")
+}
+
+func TestNewGlobal(t *testing.T) {
+	t.Run("swap", func(t *testing.T) {
+		_, w := NewGlobal(t)
+		spoor.GetLogger("api").Info("handled")
+		w.AssertLogged(t, spoor.INFO, "handled")
+	})
+	if spoor.GetLogger("api") != nil {
+		t.Error("global logger not restored")
+	}
+}
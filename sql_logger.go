@@ -0,0 +1,256 @@
+package spoor
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"time"
+)
+
+type SQLConfig struct {
+	// Level of the query entries, DEBUG by default. Failed queries are
+	// logged at ERROR.
+	Level Level
+	// SlowThreshold, when set, logs the queries taking longer at WARN with
+	// slow=true.
+	SlowThreshold time.Duration
+	// LogArgs adds the query arguments as the args field, those for which
+	// Redact returns true are replaced by [REDACTED].
+	LogArgs bool
+	Redact  func(arg driver.NamedValue) bool
+}
+
+// WrapDriver returns a driver logging the statements run through d with l,
+// with their duration, rows affected and error:
+//
+//	sql.Register("postgres-logged", spoor.WrapDriver(&pq.Driver{}, l, spoor.SQLConfig{SlowThreshold: time.Second}))
+//	db, err := sql.Open("postgres-logged", dsn)
+func WrapDriver(d driver.Driver, l *Spoor, cfg SQLConfig) driver.Driver {
+	return &sqlDriver{Driver: d, log: newSQLLog(l, cfg)}
+}
+
+// WrapConnector is WrapDriver for sql.OpenDB.
+func WrapConnector(c driver.Connector, l *Spoor, cfg SQLConfig) driver.Connector {
+	return &sqlConnector{c: c, log: newSQLLog(l, cfg)}
+}
+
+type sqlLog struct {
+	l   *Spoor
+	cfg SQLConfig
+}
+
+func newSQLLog(l *Spoor, cfg SQLConfig) *sqlLog {
+	if cfg.Level == 0 {
+		cfg.Level = DEBUG
+	}
+	return &sqlLog{l: l, cfg: cfg}
+}
+
+// done logs a statement started at start, rows is -1 when unknown.
+func (s *sqlLog) done(msg, query string, args []driver.NamedValue, start time.Time, rows int64, err error) {
+	if errors.Is(err, driver.ErrSkip) {
+		return
+	}
+	took := time.Since(start)
+	level := s.cfg.Level
+	fields := []Field{String("query", query)}
+	if s.cfg.LogArgs && len(args) > 0 {
+		values := make([]interface{}, len(args))
+		for i, arg := range args {
+			values[i] = arg.Value
+			if s.cfg.Redact != nil && s.cfg.Redact(arg) {
+				values[i] = redacted
+			}
+		}
+		fields = append(fields, Any("args", values))
+	}
+	if rows >= 0 {
+		fields = append(fields, Int64("rows", rows))
+	}
+	fields = append(fields, Dur("latency", took))
+	if s.cfg.SlowThreshold > 0 && took > s.cfg.SlowThreshold {
+		level = WARN
+		fields = append(fields, Bool("slow", true))
+	}
+	if err != nil {
+		level = ERROR
+		fields = append(fields, Err(err))
+	}
+	s.l.log(3, level, msg, fields)
+}
+
+func (s *sqlLog) exec(query string, args []driver.NamedValue, start time.Time, res driver.Result, err error) {
+	rows := int64(-1)
+	if err == nil && res != nil {
+		if n, rerr := res.RowsAffected(); rerr == nil {
+			rows = n
+		}
+	}
+	s.done("sql exec", query, args, start, rows, err)
+}
+
+type sqlDriver struct {
+	driver.Driver
+	log *sqlLog
+}
+
+func (d *sqlDriver) Open(name string) (driver.Conn, error) {
+	c, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlConn{Conn: c, log: d.log}, nil
+}
+
+type sqlConnector struct {
+	c   driver.Connector
+	log *sqlLog
+}
+
+func (c *sqlConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.c.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlConn{Conn: conn, log: c.log}, nil
+}
+
+func (c *sqlConnector) Driver() driver.Driver {
+	return &sqlDriver{Driver: c.c.Driver(), log: c.log}
+}
+
+type sqlConn struct {
+	driver.Conn
+	log *sqlLog
+}
+
+func (c *sqlConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *sqlConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = p.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sqlStmt{Stmt: stmt, query: query, log: c.log}, nil
+}
+
+func (c *sqlConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *sqlConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	res, err := e.ExecContext(ctx, query, args)
+	c.log.exec(query, args, start, res, err)
+	return res, err
+}
+
+func (c *sqlConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	rows, err := q.QueryContext(ctx, query, args)
+	c.log.done("sql query", query, args, start, -1, err)
+	return rows, err
+}
+
+func (c *sqlConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *sqlConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *sqlConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *sqlConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if ch, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return ch.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type sqlStmt struct {
+	driver.Stmt
+	query string
+	log   *sqlLog
+}
+
+func (s *sqlStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	var res driver.Result
+	var err error
+	if e, ok := s.Stmt.(driver.StmtExecContext); ok {
+		res, err = e.ExecContext(ctx, args)
+	} else if values, verr := namedValues(args); verr != nil {
+		err = verr
+	} else {
+		res, err = s.Stmt.Exec(values)
+	}
+	s.log.exec(s.query, args, start, res, err)
+	return res, err
+}
+
+func (s *sqlStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var rows driver.Rows
+	var err error
+	if q, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = q.QueryContext(ctx, args)
+	} else if values, verr := namedValues(args); verr != nil {
+		err = verr
+	} else {
+		rows, err = s.Stmt.Query(values)
+	}
+	s.log.done("sql query", s.query, args, start, -1, err)
+	return rows, err
+}
+
+func (s *sqlStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if ch, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return ch.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// namedValues converts args for the drivers without the context methods,
+// which take no names.
+func namedValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, errors.New("spoor: sql: driver does not support named arguments")
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
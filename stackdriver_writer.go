@@ -0,0 +1,212 @@
+package spoor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	stackdriverMaxEntries    = 1000
+	stackdriverMaxEntryBytes = 256 * 1024
+	// below the 10MB of an entries.write request, leaving room for the JSON
+	// encoding of the lines and the fields of the entries
+	stackdriverMaxBatchBytes = 8 * 1024 * 1024
+	stackdriverMetadataToken = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+)
+
+type StackdriverConfig struct {
+	ProjectID string
+	LogID     string
+	// ResourceType is the monitored resource, e.g. "global", "k8s_container".
+	ResourceType   string
+	ResourceLabels map[string]string
+	Labels         map[string]string
+	// TokenSource returns an OAuth2 access token, it defaults to the token
+	// of the metadata server available on GCE, GKE and Cloud Run.
+	TokenSource   func() (string, error)
+	BatchSize     int
+	FlushInterval time.Duration
+	MaxRetries    int // shorthand for Retry.MaxRetries
+	Retry         RetryPolicy
+	Transport     HTTPTransport // proxy, TLS or extra headers
+	Client        *http.Client
+}
+
+// StackdriverWriter sends entries to Google Cloud Logging with entries:write,
+// mapping spoor levels to Cloud Logging severities.
+type StackdriverWriter struct {
+	*batcher
+	cfg StackdriverConfig
+}
+
+func NewStackdriverWriter(cfg StackdriverConfig) *StackdriverWriter {
+	if cfg.LogID == "" {
+		cfg.LogID = program
+	}
+	if cfg.ResourceType == "" {
+		cfg.ResourceType = "global"
+	}
+	if cfg.BatchSize == 0 || cfg.BatchSize > stackdriverMaxEntries {
+		cfg.BatchSize = stackdriverMaxEntries
+	}
+	if cfg.FlushInterval == 0 {
+		cfg.FlushInterval = time.Second * 5
+	}
+	cfg.Retry = cfg.Retry.withDefaults(cfg.MaxRetries, time.Second)
+	if cfg.Client == nil {
+		cfg.Client = cfg.Transport.client(time.Second * 10)
+	}
+	if cfg.TokenSource == nil {
+		cfg.TokenSource = metadataTokenSource(cfg.Client)
+	}
+	sw := &StackdriverWriter{cfg: cfg}
+	sw.batcher = newBatcher(cfg.BatchSize, stackdriverMaxBatchBytes, cfg.FlushInterval, sw.send)
+	return sw
+}
+
+func stackdriverSeverity(p []byte) string {
+	lvl, ok := lineLevel(p)
+	if !ok {
+		return "DEFAULT"
+	}
+	switch lvl {
+	case DEBUG:
+		return "DEBUG"
+	case INFO:
+		return "INFO"
+	case WARN:
+		return "WARNING"
+	case ERROR:
+		return "ERROR"
+	case FATAL:
+		return "CRITICAL"
+	}
+	return "DEFAULT"
+}
+
+func (sw *StackdriverWriter) send(lines [][]byte) {
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	var entries []map[string]interface{}
+	size := 0
+	for _, line := range lines {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		if len(line) > stackdriverMaxEntryBytes {
+			line = line[:runeBoundary(string(line), stackdriverMaxEntryBytes)]
+		}
+		ts := now
+		if t, ok := lineTime(line); ok {
+			ts = t.UTC().Format(time.RFC3339Nano)
+		}
+		entry := map[string]interface{}{
+			"severity":  stackdriverSeverity(line),
+			"timestamp": ts,
+		}
+		if line[0] == '{' && json.Valid(line) {
+			entry["jsonPayload"] = json.RawMessage(line)
+		} else {
+			entry["textPayload"] = string(line)
+		}
+		if size+len(line) > stackdriverMaxBatchBytes {
+			sw.post(entries)
+			entries, size = nil, 0
+		}
+		entries = append(entries, entry)
+		size += len(line)
+	}
+	if len(entries) > 0 {
+		sw.post(entries)
+	}
+}
+
+// post writes entries with a single entries.write request.
+func (sw *StackdriverWriter) post(entries []map[string]interface{}) {
+	body, err := json.Marshal(map[string]interface{}{
+		"logName":  fmt.Sprintf("projects/%s/logs/%s", sw.cfg.ProjectID, sw.cfg.LogID),
+		"resource": map[string]interface{}{"type": sw.cfg.ResourceType, "labels": sw.cfg.ResourceLabels},
+		"labels":   sw.cfg.Labels,
+		"entries":  entries,
+	})
+	if err != nil {
+		internalError(fmt.Errorf("spoor: stackdriver dropped %d entries: %w", len(entries), err))
+		return
+	}
+	err = sw.cfg.Retry.Do(func() error { return sw.write(body) })
+	if err != nil {
+		internalError(fmt.Errorf("spoor: stackdriver dropped %d entries: %w", len(entries), err))
+	}
+}
+
+func (sw *StackdriverWriter) write(body []byte) error {
+	token, err := sw.cfg.TokenSource()
+	if err != nil {
+		return unavailable(err)
+	}
+	req, err := http.NewRequest(http.MethodPost, "https://logging.googleapis.com/v2/entries:write", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	sw.cfg.Transport.prepare(req, body)
+	resp, err := sw.cfg.Client.Do(req)
+	if err != nil {
+		return unavailable(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		err = fmt.Errorf("stackdriver: %s: %s", resp.Status, bytes.TrimSpace(msg))
+		if retryableStatus(resp.StatusCode) {
+			return unavailable(err)
+		}
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// metadataTokenSource fetches and caches the default service account token.
+func metadataTokenSource(client *http.Client) func() (string, error) {
+	var mu sync.Mutex
+	var token string
+	var expiry time.Time
+	return func() (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if token != "" && time.Now().Before(expiry) {
+			return token, nil
+		}
+		req, err := http.NewRequest(http.MethodGet, stackdriverMetadataToken, nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Metadata-Flavor", "Google")
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		var t struct {
+			AccessToken string `json:"access_token"`
+			ExpiresIn   int    `json:"expires_in"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&t); err != nil {
+			return "", fmt.Errorf("cannot decode metadata token: %v", err)
+		}
+		if t.AccessToken == "" {
+			return "", fmt.Errorf("metadata server returned no token: %s", resp.Status)
+		}
+		token = strings.TrimSpace(t.AccessToken)
+		expiry = time.Now().Add(time.Duration(t.ExpiresIn)*time.Second - time.Minute)
+		return token, nil
+	}
+}
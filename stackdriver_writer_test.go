@@ -0,0 +1,122 @@
+package spoor
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestStackdriverWriter(t *testing.T) {
+	var bodies []map[string]interface{}
+	var auth []string
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls++; calls == 1 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		auth = append(auth, r.Header.Get("Authorization"))
+	}))
+	defer srv.Close()
+
+	sw := NewStackdriverWriter(StackdriverConfig{
+		ProjectID:      "proj",
+		LogID:          "api",
+		ResourceType:   "k8s_container",
+		ResourceLabels: map[string]string{"cluster_name": "prod"},
+		Labels:         map[string]string{"team": "core"},
+		TokenSource:    func() (string, error) { return "tok", nil },
+		BatchSize:      5000,
+		FlushInterval:  time.Hour,
+		Retry:          RetryPolicy{InitialBackoff: time.Millisecond},
+		Client:         redirectClient(srv),
+	})
+	defer sw.Close()
+	if sw.cfg.BatchSize != stackdriverMaxEntries {
+		t.Fatalf("BatchSize %d above the entries:write limit", sw.cfg.BatchSize)
+	}
+	sw.send([][]byte{
+		[]byte(`{"time":"2021-02-03T04:05:06Z","level":"ERROR","msg":"failed"}` + "\n"),
+		[]byte("WARNING plain text\n"),
+		[]byte("no level " + strings.Repeat("x", stackdriverMaxEntryBytes) + "\n"),
+	})
+
+	if calls != 2 || len(bodies) != 1 || auth[0] != "Bearer tok" {
+		t.Fatalf("got %d calls, %d bodies, auth %v", calls, len(bodies), auth)
+	}
+	body := bodies[0]
+	resource, _ := json.Marshal(body["resource"])
+	labels, _ := json.Marshal(body["labels"])
+	if body["logName"] != "projects/proj/logs/api" || string(resource) != `{"labels":{"cluster_name":"prod"},"type":"k8s_container"}` || string(labels) != `{"team":"core"}` {
+		t.Fatalf("got %v", body)
+	}
+	entries, _ := body["entries"].([]interface{})
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries", len(entries))
+	}
+	first := entries[0].(map[string]interface{})
+	payload, _ := json.Marshal(first["jsonPayload"])
+	if first["severity"] != "ERROR" || first["timestamp"] != "2021-02-03T04:05:06Z" || string(payload) != `{"level":"ERROR","msg":"failed","time":"2021-02-03T04:05:06Z"}` {
+		t.Fatalf("first entry %v", first)
+	}
+	second := entries[1].(map[string]interface{})
+	if second["severity"] != "WARNING" || second["textPayload"] != "WARNING plain text" {
+		t.Fatalf("second entry %v", second)
+	}
+	third := entries[2].(map[string]interface{})
+	if text, _ := third["textPayload"].(string); third["severity"] != "DEFAULT" || len(text) != stackdriverMaxEntryBytes {
+		t.Fatalf("third entry of %d bytes, severity %v", len(text), third["severity"])
+	}
+}
+
+func TestStackdriverWriterLimits(t *testing.T) {
+	var sizes []int
+	var texts []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Entries []struct {
+				TextPayload string `json:"textPayload"`
+			} `json:"entries"`
+		}
+		data, _ := io.ReadAll(r.Body)
+		json.Unmarshal(data, &body)
+		sizes = append(sizes, len(data))
+		for _, e := range body.Entries {
+			texts = append(texts, e.TextPayload)
+		}
+	}))
+	defer srv.Close()
+	sw := NewStackdriverWriter(StackdriverConfig{ProjectID: "proj", TokenSource: func() (string, error) { return "tok", nil }, FlushInterval: time.Hour, Client: redirectClient(srv)})
+	defer sw.Close()
+
+	// 50 entries of 256KB, above the 10MB of a request
+	big := []byte(strings.Repeat("x", stackdriverMaxEntryBytes) + "\n")
+	lines := make([][]byte, 50)
+	for i := range lines {
+		lines[i] = big
+	}
+	sw.send(lines)
+	if len(sizes) < 2 || len(texts) != 50 {
+		t.Fatalf("got %d requests with %d entries", len(sizes), len(texts))
+	}
+	for _, size := range sizes {
+		if size > 10*1024*1024 {
+			t.Fatalf("request of %d bytes", size)
+		}
+	}
+
+	// an entry cut in the middle of a rune keeps valid UTF-8
+	texts = nil
+	sw.send([][]byte{[]byte("x" + strings.Repeat("é", stackdriverMaxEntryBytes/2))})
+	if len(texts) != 1 || !utf8.ValidString(texts[0]) || len(texts[0]) != stackdriverMaxEntryBytes-1 {
+		t.Fatalf("got %d entries, the first of %d bytes", len(texts), len(texts[0]))
+	}
+}
@@ -0,0 +1,223 @@
+package spoor
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// statsdMaxPacket keeps the packets under the usual 1500 bytes MTU.
+const statsdMaxPacket = 1432
+
+type StatsDConfig struct {
+	Addr   string // udp host:port, 127.0.0.1:8125 by default
+	Prefix string // prepended to the metric names, "spoor." by default
+	// DogStatsD sends the level as a tag, with Tags, instead of in the
+	// metric name.
+	DogStatsD     bool
+	Tags          []string // e.g. "env:prod", DogStatsD only
+	FlushInterval time.Duration
+	// MaxTimings is the number of write latencies sent per interval, the
+	// others are accounted for with the sample rate. 500 by default.
+	MaxTimings int
+}
+
+// StatsDHook counts the entries by level and times the writes of a logger,
+// and sends them to a StatsD or DogStatsD server every FlushInterval:
+//
+//	h := spoor.NewStatsDHook(spoor.StatsDConfig{DogStatsD: true})
+//	l := spoor.NewSpoor(spoor.INFO, "", 0, spoor.WithHook(h), spoor.WithConsoleWriter(h.Writer(fw)))
+//
+// The metrics are <prefix>entries, a counter, and <prefix>write, a timer in
+// milliseconds, with <prefix>write_errors counting the failed writes.
+type StatsDHook struct {
+	cfg     StatsDConfig
+	counts  [FATAL + 1]uint64
+	errors  uint64
+	mu      sync.Mutex
+	timings []time.Duration
+	timed   int // writes timed this interval
+	sendMu  sync.Mutex
+	conn    net.Conn
+	closeC  chan struct{}
+	done    chan struct{}
+	once    sync.Once
+}
+
+func NewStatsDHook(cfg StatsDConfig) *StatsDHook {
+	if cfg.Addr == "" {
+		cfg.Addr = "127.0.0.1:8125"
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = "spoor."
+	}
+	if cfg.FlushInterval == 0 {
+		cfg.FlushInterval = time.Second * 10
+	}
+	if cfg.MaxTimings == 0 {
+		cfg.MaxTimings = 500
+	}
+	h := &StatsDHook{cfg: cfg, closeC: make(chan struct{}), done: make(chan struct{})}
+	go h.flushLoop()
+	return h
+}
+
+func (h *StatsDHook) Levels() []Level { return nil }
+
+func (h *StatsDHook) Fire(e *Entry) error {
+	if e.Level >= DEBUG && e.Level <= FATAL {
+		atomic.AddUint64(&h.counts[e.Level], 1)
+	}
+	return nil
+}
+
+// Writer returns a writer timing the writes to w.
+func (h *StatsDHook) Writer(w io.Writer) io.Writer {
+	return &statsdWriter{h: h, w: w}
+}
+
+type statsdWriter struct {
+	h *StatsDHook
+	w io.Writer
+}
+
+func (sw *statsdWriter) Write(p []byte) (n int, err error) {
+	start := time.Now()
+	n, err = sw.w.Write(p)
+	sw.h.time(time.Since(start), err)
+	return n, err
+}
+
+func (h *StatsDHook) time(d time.Duration, err error) {
+	if err != nil {
+		atomic.AddUint64(&h.errors, 1)
+	}
+	h.mu.Lock()
+	h.timed++
+	if len(h.timings) < h.cfg.MaxTimings {
+		h.timings = append(h.timings, d)
+	}
+	h.mu.Unlock()
+}
+
+func (h *StatsDHook) flushLoop() {
+	defer close(h.done)
+	ticker := time.NewTicker(h.cfg.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-h.closeC:
+			h.flush()
+			return
+		}
+		h.flush()
+	}
+}
+
+func (h *StatsDHook) flush() {
+	if err := h.Flush(); err != nil {
+		internalError(fmt.Errorf("spoor: statsd %s: %w", h.cfg.Addr, err))
+	}
+}
+
+// Flush sends the metrics gathered since the last flush.
+func (h *StatsDHook) Flush() error {
+	h.mu.Lock()
+	timings, timed := h.timings, h.timed
+	h.timings, h.timed = nil, 0
+	h.mu.Unlock()
+	var lines [][]byte
+	for lvl := DEBUG; lvl <= FATAL; lvl++ {
+		if n := atomic.SwapUint64(&h.counts[lvl], 0); n > 0 {
+			lines = append(lines, h.metric("entries", strconv.FormatUint(n, 10), "c", "", strings.ToLower(lvl.String())))
+		}
+	}
+	if n := atomic.SwapUint64(&h.errors, 0); n > 0 {
+		lines = append(lines, h.metric("write_errors", strconv.FormatUint(n, 10), "c", "", ""))
+	}
+	rate := ""
+	if timed > len(timings) {
+		rate = strconv.FormatFloat(float64(len(timings))/float64(timed), 'f', 4, 64)
+	}
+	for _, d := range timings {
+		lines = append(lines, h.metric("write", strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 3, 64), "ms", rate, ""))
+	}
+	return h.send(lines)
+}
+
+// metric formats a StatsD line, level is added as a tag or to the name.
+func (h *StatsDHook) metric(name, value, typ, rate, level string) []byte {
+	var b bytes.Buffer
+	b.WriteString(h.cfg.Prefix)
+	b.WriteString(name)
+	if level != "" && !h.cfg.DogStatsD {
+		b.WriteString("." + level)
+	}
+	b.WriteString(":" + value + "|" + typ)
+	if rate != "" {
+		b.WriteString("|@" + rate)
+	}
+	if h.cfg.DogStatsD {
+		tags := h.cfg.Tags
+		if level != "" {
+			tags = append(tags[:len(tags):len(tags)], "level:"+level)
+		}
+		if len(tags) > 0 {
+			b.WriteString("|#" + strings.Join(tags, ","))
+		}
+	}
+	return b.Bytes()
+}
+
+// send writes lines in as few packets as possible.
+func (h *StatsDHook) send(lines [][]byte) error {
+	if len(lines) == 0 {
+		return nil
+	}
+	h.sendMu.Lock()
+	defer h.sendMu.Unlock()
+	if h.conn == nil {
+		conn, err := net.Dial("udp", h.cfg.Addr)
+		if err != nil {
+			return err
+		}
+		h.conn = conn
+	}
+	var packet []byte
+	var err error
+	for _, line := range lines {
+		if len(packet) > 0 && len(packet)+1+len(line) > statsdMaxPacket {
+			if _, werr := h.conn.Write(packet); werr != nil {
+				err = werr
+			}
+			packet = packet[:0]
+		}
+		if len(packet) > 0 {
+			packet = append(packet, '\n')
+		}
+		packet = append(packet, line...)
+	}
+	if _, werr := h.conn.Write(packet); werr != nil {
+		err = werr
+	}
+	return err
+}
+
+// Close sends the pending metrics and stops the hook.
+func (h *StatsDHook) Close() error {
+	h.once.Do(func() { close(h.closeC) })
+	<-h.done
+	h.sendMu.Lock()
+	defer h.sendMu.Unlock()
+	if h.conn != nil {
+		return h.conn.Close()
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package spoor
+
+import (
+	"bytes"
+	"log"
+	"runtime"
+	"strings"
+)
+
+// NewStdLogger returns a *log.Logger whose output is logged by l at level, for
+// libraries which only accept a standard logger, e.g.
+//
+//	srv := &http.Server{ErrorLog: spoor.NewStdLogger(l, spoor.ERROR)}
+//
+// A line starting with a level name, such as "WARNING ...", is logged at that
+// level instead.
+func NewStdLogger(l *Spoor, level Level) *log.Logger {
+	return log.New(&stdWriter{l: l, level: level}, "", 0)
+}
+
+// stdWriter logs every line written by a standard logger as one entry.
+type stdWriter struct {
+	l     *Spoor
+	level Level
+}
+
+func (w *stdWriter) Write(p []byte) (int, error) {
+	skip := 0
+	if w.l.flag&(log.Lshortfile|log.Llongfile) != 0 {
+		skip = stdCallerSkip()
+	}
+	for _, line := range bytes.Split(bytes.TrimRight(p, "\n"), []byte{'\n'}) {
+		msg := string(line)
+		level := w.level
+		if word := strings.SplitN(msg, " ", 2); len(word) == 2 {
+			for lvl := DEBUG; lvl <= FATAL; lvl++ {
+				if word[0] == lvl.String() {
+					level, msg = lvl, word[1]
+					break
+				}
+			}
+		}
+		w.l.log(skip, level, msg, nil)
+	}
+	return len(p), nil
+}
+
+// stdCallerSkip returns the callerSkip for log which reports the caller of the
+// standard logger writing to a stdWriter, skipping the frames of package log.
+func stdCallerSkip() int {
+	pcs := make([]uintptr, 16)
+	n := runtime.Callers(3, pcs) // the caller of stdWriter.Write
+	frames := runtime.CallersFrames(pcs[:n])
+	skip := 2
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "log.") || !more {
+			return skip
+		}
+		skip++
+	}
+}
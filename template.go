@@ -0,0 +1,67 @@
+package spoor
+
+import "fmt"
+
+// DebugT logs a message template such as "user {user_id} bought {sku}".
+// Each placeholder is replaced by the next argument in the message and the
+// argument is also added as a field named after the placeholder.
+func (l *Spoor) DebugT(tmpl string, args ...interface{}) {
+	l.logT(DEBUG, tmpl, args)
+}
+
+func (l *Spoor) InfoT(tmpl string, args ...interface{}) {
+	l.logT(INFO, tmpl, args)
+}
+
+func (l *Spoor) WarnT(tmpl string, args ...interface{}) {
+	l.logT(WARN, tmpl, args)
+}
+
+func (l *Spoor) ErrorT(tmpl string, args ...interface{}) {
+	l.logT(ERROR, tmpl, args)
+}
+
+func (l *Spoor) logT(level Level, tmpl string, args []interface{}) {
+	if l.CheckLevel(level) {
+		return
+	}
+	msg, fields := expandTemplate(tmpl, args)
+	l.log(3, level, msg, fields)
+}
+
+// expandTemplate substitutes the {name} placeholders of tmpl. Placeholders
+// without a matching argument are kept as they are, surplus arguments are
+// added as fields named arg<N>.
+func expandTemplate(tmpl string, args []interface{}) (string, []Field) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	fields := make([]Field, 0, len(args))
+	next := 0
+	for i := 0; i < len(tmpl); i++ {
+		if tmpl[i] != '{' || next >= len(args) {
+			buf.WriteByte(tmpl[i])
+			continue
+		}
+		end := i + 1
+		for end < len(tmpl) && isPlaceholderChar(tmpl[end]) {
+			end++
+		}
+		if end == i+1 || end >= len(tmpl) || tmpl[end] != '}' {
+			buf.WriteByte(tmpl[i])
+			continue
+		}
+		field := Any(tmpl[i+1:end], args[next])
+		buf.WriteString(sprintValue(field.Value()))
+		fields = append(fields, field)
+		next++
+		i = end
+	}
+	for ; next < len(args); next++ {
+		fields = append(fields, Any(fmt.Sprintf("arg%d", next), args[next]))
+	}
+	return buf.String(), fields
+}
+
+func isPlaceholderChar(c byte) bool {
+	return c == '_' || c == '.' || c == '-' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
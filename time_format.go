@@ -0,0 +1,61 @@
+package spoor
+
+import (
+	"strconv"
+	"time"
+)
+
+// EpochUnit makes formatters write timestamps as a number since the Unix
+// epoch instead of a formatted date.
+type EpochUnit int
+
+const (
+	EpochNone EpochUnit = iota
+	EpochSeconds
+	EpochMillis
+	EpochNanos
+)
+
+// timeOptions holds the timestamp settings shared by the formatters.
+type timeOptions struct {
+	location  *time.Location
+	layout    string
+	epoch     EpochUnit
+	precision time.Duration
+}
+
+// adjust applies the zone and precision settings to t.
+func (o timeOptions) adjust(t time.Time) time.Time {
+	if o.location != nil {
+		t = t.In(o.location)
+	}
+	if o.precision > 0 {
+		t = t.Truncate(o.precision)
+	}
+	return t
+}
+
+// appendTime appends t as an epoch number or with layout, quoted unless it
+// is a number.
+func (o timeOptions) appendTime(b []byte, t time.Time, quote bool) []byte {
+	t = o.adjust(t)
+	switch o.epoch {
+	case EpochSeconds:
+		if o.precision > 0 && o.precision < time.Second {
+			return strconv.AppendFloat(b, float64(t.UnixNano())/1e9, 'f', -1, 64)
+		}
+		return strconv.AppendInt(b, t.Unix(), 10)
+	case EpochMillis:
+		return strconv.AppendInt(b, t.UnixNano()/1e6, 10)
+	case EpochNanos:
+		return strconv.AppendInt(b, t.UnixNano(), 10)
+	}
+	if quote {
+		b = append(b, '"')
+	}
+	b = t.AppendFormat(b, o.layout)
+	if quote {
+		b = append(b, '"')
+	}
+	return b
+}
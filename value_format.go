@@ -0,0 +1,301 @@
+package spoor
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type DurationFormat int
+
+const (
+	DurationString DurationFormat = iota // "1.5s"
+	DurationMillis                       // 1500, a float number of milliseconds
+	DurationNanos                        // 1500000000
+)
+
+type BytesFormat int
+
+const (
+	BytesBase64 BytesFormat = iota
+	BytesHex
+)
+
+// ValueFormat sets how JSONFormatter and TextFormatter render the values
+// which have several usual forms. The zero value keeps the defaults:
+// durations as strings, times in RFC 3339 with nanoseconds, byte slices in
+// base64 and, in JSON, errors as objects.
+type ValueFormat struct {
+	Duration   DurationFormat
+	TimeLayout string // defaults to time.RFC3339Nano
+	Bytes      BytesFormat
+	// MaxBytes cuts longer byte slices, which are then rendered followed by
+	// "...(N bytes)".
+	MaxBytes int
+	// ErrorMessage renders errors as their message in JSON instead of an
+	// object with their type, causes and stack.
+	ErrorMessage bool
+	// Expand renders the structs, maps and slices of Any fields as JSON in
+	// the text format, instead of their fmt form. JSONFormatter always does.
+	Expand bool
+	// MaxDepth and MaxElements, if set, bound the JSON of those values: the
+	// values nested deeper are replaced by "...", and the arrays and objects
+	// keep their first MaxElements elements, followed by "...(N more)" or a
+	// "..." key counting the others.
+	MaxDepth    int
+	MaxElements int
+}
+
+// appendDuration appends d, it returns false when it is not a number and
+// must be quoted in JSON.
+func (v *ValueFormat) appendDuration(b []byte, d time.Duration) ([]byte, bool) {
+	switch v.Duration {
+	case DurationMillis:
+		return strconv.AppendFloat(b, float64(d)/float64(time.Millisecond), 'f', -1, 64), true
+	case DurationNanos:
+		return strconv.AppendInt(b, int64(d), 10), true
+	}
+	return append(b, d.String()...), false
+}
+
+func (v *ValueFormat) appendTime(b []byte, t time.Time) []byte {
+	return t.AppendFormat(b, defaultString(v.TimeLayout, time.RFC3339Nano))
+}
+
+// marshal returns the JSON of x within the bounds of v. The parts of x cut
+// off by the bounds are skipped while walking it, so they are never encoded.
+func (v *ValueFormat) marshal(x interface{}) ([]byte, error) {
+	if v.MaxDepth <= 0 && v.MaxElements <= 0 {
+		return json.Marshal(x)
+	}
+	pruned, err := v.prune(reflect.ValueOf(x), 1)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(pruned)
+}
+
+// maxPruneDepth stops prune on pointer cycles, like json.Marshal.
+const maxPruneDepth = 1000
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// prune returns x found at depth, bounded by v, as a value json.Marshal
+// renders like x: structs and maps become maps, slices and arrays become
+// slices, and the other values are kept as they are.
+func (v *ValueFormat) prune(x reflect.Value, depth int) (interface{}, error) {
+	if depth > maxPruneDepth {
+		return nil, fmt.Errorf("spoor: value nested more than %d levels deep", maxPruneDepth)
+	}
+	if !x.IsValid() {
+		return nil, nil
+	}
+	if x.Kind() != reflect.Ptr && x.CanAddr() && marshals(reflect.PtrTo(x.Type())) {
+		return x.Addr().Interface(), nil
+	}
+	if marshals(x.Type()) {
+		return x.Interface(), nil
+	}
+	tooDeep := v.MaxDepth > 0 && depth > v.MaxDepth
+	switch x.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if x.IsNil() {
+			return nil, nil
+		}
+		return v.prune(x.Elem(), depth)
+	case reflect.Struct:
+		if tooDeep {
+			return "...", nil
+		}
+		fields := map[string]structField{}
+		jsonFields(x, 0, fields)
+		values := make(map[string]reflect.Value, len(fields))
+		for name, f := range fields {
+			values[name] = f.value
+		}
+		return v.pruneObject(values, depth)
+	case reflect.Map:
+		if x.IsNil() {
+			return nil, nil
+		}
+		if tooDeep {
+			return "...", nil
+		}
+		values := make(map[string]reflect.Value, x.Len())
+		iter := x.MapRange()
+		for iter.Next() {
+			key, err := mapKey(iter.Key())
+			if err != nil {
+				return nil, err
+			}
+			values[key] = iter.Value()
+		}
+		return v.pruneObject(values, depth)
+	case reflect.Slice:
+		if x.IsNil() {
+			return nil, nil
+		}
+		if x.Type().Elem().Kind() == reflect.Uint8 {
+			// base64, like json.Marshal
+			return x.Interface(), nil
+		}
+		fallthrough
+	case reflect.Array:
+		if tooDeep {
+			return "...", nil
+		}
+		n, keep := x.Len(), x.Len()
+		if v.MaxElements > 0 && n > v.MaxElements {
+			keep = v.MaxElements
+		}
+		elems := make([]interface{}, keep, keep+1)
+		for i := range elems {
+			var err error
+			if elems[i], err = v.prune(x.Index(i), depth+1); err != nil {
+				return nil, err
+			}
+		}
+		if keep < n {
+			elems = append(elems, fmt.Sprintf("...(%d more)", n-keep))
+		}
+		return elems, nil
+	}
+	return x.Interface(), nil
+}
+
+// pruneObject bounds the members of an object at depth, keeping the first
+// MaxElements keys in JSON order and counting the others under a "..." key.
+func (v *ValueFormat) pruneObject(values map[string]reflect.Value, depth int) (interface{}, error) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	obj := make(map[string]interface{}, len(keys)+1)
+	if v.MaxElements > 0 && len(keys) > v.MaxElements {
+		obj["..."] = len(keys) - v.MaxElements
+		keys = keys[:v.MaxElements]
+	}
+	for _, key := range keys {
+		var err error
+		if obj[key], err = v.prune(values[key], depth+1); err != nil {
+			return nil, err
+		}
+	}
+	return obj, nil
+}
+
+func marshals(t reflect.Type) bool {
+	return t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType)
+}
+
+// mapKey returns the JSON name of a map key, as json.Marshal does.
+func mapKey(k reflect.Value) (string, error) {
+	if k.Kind() == reflect.String {
+		return k.String(), nil
+	}
+	if tm, ok := k.Interface().(encoding.TextMarshaler); ok {
+		if k.Kind() == reflect.Ptr && k.IsNil() {
+			return "", nil
+		}
+		text, err := tm.MarshalText()
+		return string(text), err
+	}
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("spoor: unsupported map key type %s", k.Type())
+}
+
+// structField is a field json.Marshal renders, found at depth in the
+// embedded structs.
+type structField struct {
+	value reflect.Value
+	depth int
+}
+
+// jsonFields adds the fields of the struct x rendered by json.Marshal to
+// fields by name, with those of its embedded structs, the shallower ones
+// winning.
+func jsonFields(x reflect.Value, depth int, fields map[string]structField) {
+	t := x.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		fv := x.Field(i)
+		if sf.Anonymous && name == "" && sf.IsExported() {
+			if fv.Kind() == reflect.Ptr && !fv.IsNil() && fv.Elem().Kind() == reflect.Struct {
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct {
+				jsonFields(fv, depth+1, fields)
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		if strings.Contains(","+opts+",", ",omitempty,") && emptyValue(fv) {
+			continue
+		}
+		if f, ok := fields[name]; !ok || depth < f.depth {
+			fields[name] = structField{value: fv, depth: depth}
+		}
+	}
+}
+
+// emptyValue reports whether omitempty leaves v out, as json.Marshal does.
+func emptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+func (v *ValueFormat) bytesText(p []byte) string {
+	n := len(p)
+	if v.MaxBytes > 0 && n > v.MaxBytes {
+		p = p[:v.MaxBytes]
+	}
+	var s string
+	if v.Bytes == BytesHex {
+		s = hex.EncodeToString(p)
+	} else {
+		s = base64.StdEncoding.EncodeToString(p)
+	}
+	if len(p) < n {
+		s += "...(" + strconv.Itoa(n) + " bytes)"
+	}
+	return s
+}
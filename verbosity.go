@@ -0,0 +1,168 @@
+package spoor
+
+import (
+	"flag"
+	"fmt"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Verbosity is the glog style verbosity of loggers, see V. The level and the
+// per module overrides may be changed while logging, e.g. from flags:
+//
+//	v := spoor.NewVerbosity(0)
+//	v.RegisterFlags(flag.CommandLine)
+//	l := spoor.NewSpoor(spoor.DEBUG, "", 0, spoor.WithVerbosity(v))
+//	l.V(2).Debug("cache miss", spoor.String("key", key))
+type Verbosity struct {
+	level   int32
+	mu      sync.Mutex
+	modules atomic.Value // []vmodule
+	callers atomic.Value // *sync.Map of caller pc to verbosity
+}
+
+type vmodule struct {
+	pattern string
+	level   int32
+}
+
+func NewVerbosity(level int) *Verbosity {
+	v := &Verbosity{level: int32(level)}
+	v.modules.Store([]vmodule(nil))
+	v.callers.Store(new(sync.Map))
+	return v
+}
+
+// WithVerbosity sets the verbosity checked by V.
+func WithVerbosity(v *Verbosity) Option {
+	return func(spoor *Spoor) {
+		spoor.verbosity = v
+	}
+}
+
+func (v *Verbosity) SetLevel(level int) {
+	atomic.StoreInt32(&v.level, int32(level))
+}
+
+// SetModules sets the per module overrides from a comma separated list of
+// pattern=N, like the -vmodule flag of glog. A pattern is matched against the
+// name of the source file without .go, or against its full path when it has a
+// slash, e.g. "cache=3,*/handlers/*=1".
+func (v *Verbosity) SetModules(spec string) error {
+	var modules []vmodule
+	for _, item := range strings.Split(spec, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		pattern, level, ok := strings.Cut(item, "=")
+		n, err := strconv.Atoi(level)
+		if !ok || pattern == "" || err != nil {
+			return fmt.Errorf("spoor: invalid vmodule %q, want pattern=N", item)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("spoor: invalid vmodule pattern %q: %v", pattern, err)
+		}
+		modules = append(modules, vmodule{pattern: pattern, level: int32(n)})
+	}
+	v.mu.Lock()
+	v.modules.Store(modules)
+	v.callers.Store(new(sync.Map))
+	v.mu.Unlock()
+	return nil
+}
+
+// RegisterFlags adds the -v and -vmodule flags to fs.
+func (v *Verbosity) RegisterFlags(fs *flag.FlagSet) {
+	fs.Func("v", "log verbosity, see spoor.V", func(s string) error {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		v.SetLevel(n)
+		return nil
+	})
+	fs.Func("vmodule", "comma separated pattern=N log verbosity per source file", v.SetModules)
+}
+
+// enabled reports whether level is enabled for the code at pc.
+func (v *Verbosity) enabled(level int32, pc uintptr) bool {
+	modules := v.modules.Load().([]vmodule)
+	if len(modules) == 0 || pc == 0 {
+		return level <= atomic.LoadInt32(&v.level)
+	}
+	callers := v.callers.Load().(*sync.Map)
+	if n, ok := callers.Load(pc); ok {
+		return level <= n.(int32)
+	}
+	n := int32(-1) // not matched
+	if fn := runtime.FuncForPC(pc); fn != nil {
+		file, _ := fn.FileLine(pc)
+		file = strings.TrimSuffix(filepath.ToSlash(file), ".go")
+		for _, m := range modules {
+			name := path.Base(file)
+			if strings.Contains(m.pattern, "/") {
+				name = file
+			}
+			if ok, _ := path.Match(m.pattern, name); ok {
+				n = m.level
+				break
+			}
+		}
+	}
+	callers.Store(pc, n)
+	if n < 0 {
+		return level <= atomic.LoadInt32(&v.level)
+	}
+	return level <= n
+}
+
+// Verbose logs only if its verbosity is enabled, see V.
+type Verbose struct {
+	l *Spoor // nil when disabled
+}
+
+// V returns a handle logging only if the verbosity of the logger, or the
+// override of the calling source file, is at least level. A logger without
+// verbosity has verbosity 0. The check is cheap, so V can guard the
+// expensive fields as well:
+//
+//	if v := l.V(3); v.Enabled() {
+//		v.Debug("state", spoor.Any("dump", dump()))
+//	}
+func (l *Spoor) V(level int) Verbose {
+	if l.verbosity == nil {
+		if level <= 0 {
+			return Verbose{l: l}
+		}
+		return Verbose{}
+	}
+	var pc uintptr
+	if len(l.verbosity.modules.Load().([]vmodule)) > 0 {
+		pc, _, _, _ = runtime.Caller(1)
+	}
+	if l.verbosity.enabled(int32(level), pc) {
+		return Verbose{l: l}
+	}
+	return Verbose{}
+}
+
+func (v Verbose) Enabled() bool {
+	return v.l != nil
+}
+
+func (v Verbose) Debug(msg string, fields ...Field) {
+	if v.l != nil {
+		v.l.log(2, DEBUG, msg, fields)
+	}
+}
+
+func (v Verbose) Info(msg string, fields ...Field) {
+	if v.l != nil {
+		v.l.log(2, INFO, msg, fields)
+	}
+}
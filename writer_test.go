@@ -0,0 +1,990 @@
+package spoor
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type failingWriter struct {
+	err   error
+	calls int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	w.calls++
+	if w.err != nil {
+		return 0, w.err
+	}
+	return len(p), nil
+}
+
+func TestCircuitBreakerWriter(t *testing.T) {
+	remote := &failingWriter{err: errors.New("unavailable")}
+	var fallback bytes.Buffer
+	cw := NewCircuitBreakerWriter(remote, &fallback, 2, time.Millisecond*20)
+	for i := 0; i < 5; i++ {
+		cw.Write([]byte("x\n"))
+	}
+	if remote.calls != 2 || !cw.Open() {
+		t.Fatalf("remote called %d times, open=%v", remote.calls, cw.Open())
+	}
+	if fallback.Len() != 10 {
+		t.Fatalf("fallback got %d bytes, want 10", fallback.Len())
+	}
+
+	time.Sleep(time.Millisecond * 30)
+	remote.err = nil
+	cw.Write([]byte("x\n"))
+	if remote.calls != 3 || cw.Open() {
+		t.Fatalf("probe did not close the breaker: calls=%d open=%v", remote.calls, cw.Open())
+	}
+}
+
+func TestMemoryWriter(t *testing.T) {
+	w := NewMemoryWriter(3)
+	for _, s := range []string{"a\n", "b\n", "c\n", "d\n"} {
+		w.Write([]byte(s))
+	}
+	var buf bytes.Buffer
+	w.WriteTo(&buf)
+	if buf.String() != "b\nc\nd\n" || w.Len() != 3 {
+		t.Fatalf("got %q, %d entries", buf.String(), w.Len())
+	}
+	w.Reset()
+	if w.Len() != 0 || len(w.Lines()) != 0 {
+		t.Fatal("entries kept after Reset")
+	}
+}
+
+type closeRecorder struct {
+	name   string
+	closed *[]string
+}
+
+func (c closeRecorder) Close() error {
+	*c.closed = append(*c.closed, c.name)
+	return nil
+}
+
+func TestShutdown(t *testing.T) {
+	var closed []string
+	Register(closeRecorder{"writer", &closed})
+	Register(closeRecorder{"logger", &closed})
+	if err := Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(closed, ",") != "logger,writer" {
+		t.Fatalf("closed %v", closed)
+	}
+	if err := Shutdown(context.Background()); err != nil || len(closed) != 2 {
+		t.Fatal("closers not forgotten")
+	}
+}
+
+func TestFatalDrains(t *testing.T) {
+	code := 0
+	exit = func(c int) { code = c }
+	defer func() { exit = os.Exit }()
+	sw := &slowWriter{}
+	var closed []string
+	Register(closeRecorder{"writer", &closed})
+	l := NewSpoor(DEBUG, "", 0, WithConsoleWriter(NewAsyncWriter(sw, AsyncConfig{QueueSize: 100})), WithFatalDeadline(time.Second))
+	for i := 0; i < 20; i++ {
+		l.Info("queued")
+	}
+	l.Fatal("bye")
+	if code != 1 || strings.Count(sw.buf.String(), "\n") != 21 || len(closed) != 1 {
+		t.Fatalf("exit %d, closed %v, wrote %q", code, closed, sw.buf.String())
+	}
+}
+
+func TestHealth(t *testing.T) {
+	dir := t.TempDir()
+	fw := NewFileWriter(dir, 0, 0, 0)
+	Register(fw)
+	Register(NewFileWriter(dir+"/missing\x00", 0, 0, 0))
+	defer Shutdown(context.Background())
+
+	states := Health(context.Background())
+	if len(states) != 2 || !states[0].Healthy || states[1].Healthy {
+		t.Fatalf("got %+v", states)
+	}
+	rec := httptest.NewRecorder()
+	HealthHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status %d", rec.Code)
+	}
+}
+
+func TestBatcherMaxBytes(t *testing.T) {
+	sent := make(chan int, 2)
+	b := newBatcher(100, 10, time.Hour, func(lines [][]byte) { sent <- len(lines) })
+	defer b.close()
+	b.add([]byte("12345\n"))
+	b.add([]byte("12345\n"))
+	select {
+	case n := <-sent:
+		if n != 2 {
+			t.Fatalf("sent %d lines", n)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("byte threshold did not flush")
+	}
+}
+
+type slowWriter struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	batches int
+}
+
+func (w *slowWriter) Write(p []byte) (int, error) {
+	return len(p), w.WriteBatch([][]byte{p})
+}
+
+func (w *slowWriter) WriteBatch(lines [][]byte) error {
+	time.Sleep(time.Millisecond)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.batches++
+	for _, line := range lines {
+		w.buf.Write(line)
+	}
+	return nil
+}
+
+func TestAsyncWriter(t *testing.T) {
+	sw := &slowWriter{}
+	aw := NewAsyncWriter(sw, AsyncConfig{QueueSize: 1000, BatchSize: 10})
+	var want bytes.Buffer
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(aw, "line %d\n", i)
+		fmt.Fprintf(&want, "line %d\n", i)
+	}
+	if err := aw.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if aw.Pending() != 0 || sw.buf.String() != want.String() {
+		t.Fatalf("pending %d, got %q", aw.Pending(), sw.buf.String())
+	}
+	if sw.batches >= 100 {
+		t.Fatalf("%d batches for 100 lines", sw.batches)
+	}
+	aw.Close()
+	if _, err := aw.Write([]byte("late\n")); err == nil {
+		t.Fatal("write after Close succeeded")
+	}
+}
+
+func TestAsyncWriterKey(t *testing.T) {
+	sw := &slowWriter{}
+	key := func(line []byte) string { return string(line[:bytes.IndexByte(line, ' ')]) }
+	aw := NewAsyncWriter(sw, AsyncConfig{Workers: 4, QueueSize: 8, BatchSize: 3, Key: key})
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(aw, "user%d %d\n", i%5, i)
+	}
+	aw.Close()
+	next := map[string]int{}
+	for _, line := range strings.Split(strings.TrimSpace(sw.buf.String()), "\n") {
+		var user string
+		var n int
+		fmt.Sscanf(line, "%s %d", &user, &n)
+		if want := next[user]*5 + int(user[4]-'0'); n != want {
+			t.Fatalf("%s: got line %d, want %d", user, n, want)
+		}
+		next[user]++
+	}
+	if len(next) != 5 || next["user0"] != 40 {
+		t.Fatalf("got %v lines per key", next)
+	}
+}
+
+func TestEncryptingWriter(t *testing.T) {
+	key := func() ([]byte, error) { return bytes.Repeat([]byte{7}, 32), nil }
+	var file bytes.Buffer
+	file.WriteString("Log file created at: 2020/01/02 03:04:05\n")
+	ew, err := NewEncryptingWriter(&file, key, 16, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ew.Write([]byte("first line\n"))
+	ew.Write([]byte("second line\n"))
+	ew.Write([]byte("third\n"))
+	ew.Close()
+	if bytes.Contains(file.Bytes(), []byte("line")) {
+		t.Fatal("plain text written")
+	}
+	var plain bytes.Buffer
+	if err := DecryptLog(&file, &plain, key); err != nil {
+		t.Fatal(err)
+	}
+	if want := "first line\nsecond line\nthird\n"; plain.String() != want {
+		t.Fatalf("got %q, want %q", plain.String(), want)
+	}
+}
+
+func TestAuditWriter(t *testing.T) {
+	key := []byte("secret")
+	var log bytes.Buffer
+	aw := NewAuditWriter(&log, key, nil)
+	for _, s := range []string{"login alice\n", "delete report\n", "logout alice\n"} {
+		aw.Write([]byte(s))
+	}
+	last, err := VerifyAuditLog(bytes.NewReader(log.Bytes()), key)
+	if err != nil || last.Seq != 3 || last.Entry != "logout alice" {
+		t.Fatalf("got %+v, %v", last, err)
+	}
+
+	NewAuditWriter(&log, key, last).Write([]byte("login bob\n"))
+	if last, err = VerifyAuditLog(bytes.NewReader(log.Bytes()), key); err != nil || last.Seq != 4 {
+		t.Fatalf("resumed chain: %+v, %v", last, err)
+	}
+
+	tampered := bytes.Replace(log.Bytes(), []byte("delete report"), []byte("read report"), 1)
+	if _, err := VerifyAuditLog(bytes.NewReader(tampered), key); err == nil {
+		t.Fatal("modified record not detected")
+	}
+	lines := bytes.SplitAfter(log.Bytes(), []byte("\n"))
+	removed := bytes.Join(append(lines[:1:1], lines[2:]...), nil)
+	if _, err := VerifyAuditLog(bytes.NewReader(removed), key); err == nil {
+		t.Fatal("removed record not detected")
+	}
+}
+
+func TestFilePathWriter(t *testing.T) {
+	dir := t.TempDir()
+	fw := NewFilePathWriter(filepath.Join(dir, "app.log"), 0, 0, 300)
+	line := bytes.Repeat([]byte("x"), 99)
+	line[98] = '\n'
+	for i := 0; i < 3; i++ {
+		fw.Write(line)
+	}
+	fw.Close()
+	names, _ := filepath.Glob(filepath.Join(dir, "app.*.log"))
+	current, err := os.ReadFile(filepath.Join(dir, "app.log"))
+	if err != nil || len(names) == 0 {
+		t.Fatalf("got rotated files %v, %v", names, err)
+	}
+	lines := bytes.Count(current, line)
+	for _, name := range names {
+		data, _ := os.ReadFile(name)
+		lines += bytes.Count(data, line)
+	}
+	if lines != 3 {
+		t.Fatalf("found %d lines", lines)
+	}
+}
+
+func TestFileTemplate(t *testing.T) {
+	dir := t.TempDir()
+	fw := NewFileWriter(dir, 0, 0, 200, WithFileTemplate("{app}-{seq}.log"), WithSymlink("current.log"))
+	line := append(bytes.Repeat([]byte("x"), 149), '\n')
+	fw.Write(line)
+	fw.Write(line)
+	fw.Close()
+	for _, name := range []string{program + "-1.log", program + "-2.log"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if target, err := os.Readlink(filepath.Join(dir, "current.log")); err != nil || target != program+"-2.log" {
+		t.Fatalf("current.log links to %q, %v", target, err)
+	}
+}
+
+func TestFileTemplateWithoutSeq(t *testing.T) {
+	dir := t.TempDir()
+	fw := NewFileWriter(dir, 0, 0, 400, WithFileTemplate("{app}.log"))
+	line := append(bytes.Repeat([]byte("x"), 49), '\n')
+	for i := 0; i < 20; i++ {
+		fw.Write(line)
+	}
+	fw.Close()
+	files := fw.ownFiles()
+	if len(files) < 3 {
+		t.Fatalf("got %d files for 1000 bytes and a 400 bytes limit", len(files))
+	}
+	lines := 0
+	for _, f := range files {
+		if f.Name() != program+".log" && !regexp.MustCompile(`^`+regexp.QuoteMeta(program)+`\.\d\.log$`).MatchString(f.Name()) {
+			t.Errorf("unexpected file %s", f.Name())
+		}
+		data, _ := os.ReadFile(filepath.Join(dir, f.Name()))
+		n := bytes.Count(data, line)
+		if n < 2 && f.Name() != filepath.Base(fw.path) {
+			t.Errorf("%s rotated after %d lines", f.Name(), n)
+		}
+		lines += n
+	}
+	if lines != 20 {
+		t.Fatalf("found %d lines", lines)
+	}
+
+	// a restarted writer skips the full files
+	fw = NewFileWriter(dir, 0, 0, 400, WithFileTemplate("{app}.log"))
+	fw.Write(line)
+	fw.Close()
+	if got := len(fw.ownFiles()); got != len(files) && got != len(files)+1 {
+		t.Fatalf("got %d files after a restart, had %d", got, len(files))
+	}
+	if fileSize(fw.path) >= 400 {
+		t.Fatalf("restarted in the full file %s", fw.path)
+	}
+}
+
+func TestFileWriterReopen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	fw := NewFilePathWriter(path, 0, 0, 0)
+	defer fw.Close()
+	fw.Write([]byte("before\n"))
+	fw.lockAndFlush()
+	os.Rename(path, path+".1")
+	fw.Write([]byte("to the moved file\n"))
+	if err := fw.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	fw.Write([]byte("after\n"))
+	fw.lockAndFlush()
+	moved, _ := os.ReadFile(path + ".1")
+	current, _ := os.ReadFile(path)
+	if !bytes.HasSuffix(moved, []byte("before\nto the moved file\n")) || string(current) != "after\n" {
+		t.Fatalf("got %q and %q", moved, current)
+	}
+}
+
+func TestFileWriterSync(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	fw := NewFilePathWriter(path, 0, 3600, 0)
+	l := NewSpoor(DEBUG, "", 0, WithConsoleWriter(NewAsyncWriter(fw, AsyncConfig{})))
+	if err := l.Sync(); err != nil {
+		t.Fatalf("sync before the first write: %v", err)
+	}
+	l.Info("synced")
+	if err := l.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); !bytes.Contains(data, []byte("INFO synced")) {
+		t.Fatalf("got %q", data)
+	}
+	fw.Close()
+	if err := fw.Sync(); err != nil {
+		t.Fatalf("sync after close: %v", err)
+	}
+	if err := flushWriter(fw); err != nil {
+		t.Fatalf("flush after close: %v", err)
+	}
+}
+
+func TestDiskGuard(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-time.Hour)
+	for i, name := range []string{"app.20200101-000000.log", "app.20200102-000000.log", "other.log"} {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, bytes.Repeat([]byte("x"), 1000), 0666)
+		os.Chtimes(path, old.Add(time.Duration(i)*time.Minute), old.Add(time.Duration(i)*time.Minute))
+	}
+	var reports []string
+	SetInternalErrorHandler(func(err error) { reports = append(reports, err.Error()) })
+	defer SetInternalErrorHandler(nil)
+	fw := NewFilePathWriter(filepath.Join(dir, "app.log"), 0, 0, 0, WithDiskGuard(DiskGuard{MaxDirBytes: 1500, Interval: time.Hour}))
+	defer fw.Close()
+	fw.Write([]byte("INFO started\n"))
+
+	fw.checkDisk()
+	for name, exists := range map[string]bool{"app.20200101-000000.log": false, "app.20200102-000000.log": true, "other.log": true} {
+		if fileExists(filepath.Join(dir, name)) != exists {
+			t.Errorf("%s exists: %v", name, !exists)
+		}
+	}
+	fw.guard.MaxDirBytes = 10
+	fw.checkDisk()
+	fw.Write([]byte("INFO dropped\n"))
+	fw.Write([]byte("ERROR kept\n"))
+	fw.lockAndFlush()
+	data, _ := os.ReadFile(filepath.Join(dir, "app.log"))
+	if bytes.Contains(data, []byte("dropped")) || !bytes.Contains(data, []byte("ERROR kept")) || bytes.Contains(data, []byte("disk guard")) {
+		t.Fatalf("got %s", data)
+	}
+	if len(reports) != 3 || !strings.HasSuffix(reports[0], "deleted app.20200101-000000.log") || !strings.HasSuffix(reports[2], "ERROR and FATAL lines only") {
+		t.Fatalf("got reports %q", reports)
+	}
+}
+
+func TestDiskGuardTemplate(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-time.Hour)
+	own := program + "-2020-01-01.log"
+	for i, name := range []string{"other-2020-01-01.log", "x" + own, own, program + "-latest.log"} {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, bytes.Repeat([]byte("x"), 1000), 0666)
+		os.Chtimes(path, old.Add(time.Duration(i)*time.Minute), old.Add(time.Duration(i)*time.Minute))
+	}
+	SetInternalErrorHandler(func(error) {})
+	defer SetInternalErrorHandler(nil)
+	fw := NewFileWriter(dir, 0, 0, 0, WithFileTemplate("{app}-{date}.log"), WithDiskGuard(DiskGuard{MaxDirBytes: 1, Interval: time.Hour}))
+	defer fw.Close()
+	fw.Write([]byte("INFO started\n"))
+
+	fw.checkDisk()
+	for name, exists := range map[string]bool{"other-2020-01-01.log": true, "x" + own: true, own: false, program + "-latest.log": true} {
+		if fileExists(filepath.Join(dir, name)) != exists {
+			t.Errorf("%s exists: %v", name, !exists)
+		}
+	}
+	if !fileExists(fw.path) {
+		t.Error("current file deleted")
+	}
+}
+
+func TestFileWriterErrors(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "logs")
+	os.WriteFile(dir, nil, 0666) // a file where the directory should be
+	fw := NewFileWriter(dir, 0, 0, 0)
+	defer fw.Close()
+	var errs []error
+	l := NewSpoor(DEBUG, "", 0, WithFileWriter(fw), WithErrorHandler(func(e *Entry, err error) { errs = append(errs, err) }))
+	l.Info("lost")
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "rotate log file") {
+		t.Fatalf("got errors %v", errs)
+	}
+
+	// the next write tries again
+	os.Remove(dir)
+	os.Mkdir(dir, 0777)
+	l.Info("written")
+	fw.lockAndFlush()
+	if data, _ := os.ReadFile(fw.path); len(errs) != 1 || !bytes.Contains(data, []byte("INFO written")) {
+		t.Fatalf("got errors %v and %q", errs, data)
+	}
+}
+
+func TestFileWriterLevel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	fw := NewFilePathWriter(path, 0, 3600, 0, WithSyncPolicy(SyncPolicy{MinLevel: ERROR}))
+	defer fw.Close()
+	// the ECS lines have no level lineLevel knows, the logger gives it
+	l := NewSpoor(DEBUG, "", 0, WithFileWriter(fw), WithFormatter(&ECSFormatter{}))
+	l.Info("buffered")
+	if data, _ := os.ReadFile(path); bytes.Contains(data, []byte("buffered")) {
+		t.Fatal("flushed after INFO")
+	}
+	l.Error("synced")
+	if data, _ := os.ReadFile(path); !bytes.Contains(data, []byte("synced")) {
+		t.Fatalf("not flushed after ERROR: %s", data)
+	}
+	fw.mu.Lock()
+	fw.degraded = true
+	fw.mu.Unlock()
+	l.Info("dropped")
+	l.Error("kept")
+	fw.lockAndFlush()
+	if data, _ := os.ReadFile(path); bytes.Contains(data, []byte("dropped")) || !bytes.Contains(data, []byte("kept")) {
+		t.Fatalf("degraded mode got %s", data)
+	}
+
+	line := []byte("0102 03:04:05.000006 main.go:7 ERROR failed\n")
+	if allocs := testing.AllocsPerRun(100, func() { lineLevel(line) }); allocs != 0 {
+		t.Fatalf("lineLevel allocates %v times", allocs)
+	}
+}
+
+func TestSyncPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	fw := NewFilePathWriter(path, 0, 3600, 0, WithSyncPolicy(SyncPolicy{EveryWrites: 3, MinLevel: ERROR}))
+	defer fw.Close()
+	size := func() int64 {
+		fi, _ := os.Stat(path)
+		return fi.Size()
+	}
+	fw.Write([]byte("INFO one\n"))
+	header := size()
+	fw.Write([]byte("ERROR two\n"))
+	if size() != header+int64(len("INFO one\nERROR two\n")) {
+		t.Fatal("not flushed after ERROR")
+	}
+	fw.Write([]byte("INFO three\n"))
+	if size() != header+int64(len("INFO one\nERROR two\nINFO three\n")) {
+		t.Fatal("not flushed after 3 writes")
+	}
+}
+
+func TestSharedAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shared.log")
+	a := NewFilePathWriter(path, 0, 3600, 10, WithSharedAppend())
+	b := NewFilePathWriter(path, 0, 3600, 10, WithSharedAppend())
+	defer a.Close()
+	defer b.Close()
+	var want bytes.Buffer
+	for i := 0; i < 20; i++ {
+		w := a
+		if i%2 == 1 {
+			w = b
+		}
+		line := fmt.Sprintf("line %d from %p\n", i, w)
+		w.Write([]byte(line))
+		want.WriteString(line)
+	}
+	if data, _ := os.ReadFile(path); string(data) != want.String() {
+		t.Fatalf("got %q, want %q", data, want.String())
+	}
+}
+
+func TestSharedAppendDefaultName(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	var writers []*FileWriter
+	for i := 0; i < 2; i++ {
+		fw := NewFileWriter(dir, 0, 3600, 10, WithSharedAppend())
+		defer fw.Close()
+		fw.mu.Lock()
+		err := fw.rotateFile(now)
+		fw.mu.Unlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(fmt.Sprintf("line %d\n", i)))
+		writers = append(writers, fw)
+	}
+	if writers[0].path != writers[1].path {
+		t.Fatalf("got %s and %s", writers[0].path, writers[1].path)
+	}
+	if data, _ := os.ReadFile(writers[0].path); string(data) != "line 0\nline 1\n" {
+		t.Fatalf("got %q", data)
+	}
+}
+
+func TestStatsDHook(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	h := NewStatsDHook(StatsDConfig{Addr: pc.LocalAddr().String(), DogStatsD: true, Tags: []string{"env:test"}, FlushInterval: time.Hour, MaxTimings: 1})
+	l := NewSpoor(DEBUG, "", 0, WithHook(h), WithConsoleWriter(h.Writer(io.Discard)))
+	l.Info("a")
+	l.Info("b")
+	l.Error("c")
+	h.Close()
+	buf := make([]byte, statsdMaxPacket)
+	pc.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(string(buf[:n]), "\n")
+	if len(lines) != 3 || lines[0] != "spoor.entries:2|c|#env:test,level:info" || lines[1] != "spoor.entries:1|c|#env:test,level:error" ||
+		!strings.HasPrefix(lines[2], "spoor.write:") || !strings.HasSuffix(lines[2], "|ms|@0.3333|#env:test") {
+		t.Fatalf("got %q", lines)
+	}
+}
+
+func TestReplayLog(t *testing.T) {
+	var archive bytes.Buffer
+	zw := gzip.NewWriter(&archive)
+	zw.Write([]byte(`{"time":"2021-02-03T04:00:00Z","level":"INFO","msg":"early"}
+{"time":"2021-02-03T05:00:00Z","level":"DEBUG","msg":"detail"}
+{"time":"2021-02-03T05:00:01Z","level":"ERROR","msg":"failed","fields":{"id":7}}
+not json
+`))
+	zw.Close()
+	var out bytes.Buffer
+	since := time.Date(2021, 2, 3, 5, 0, 0, 0, time.UTC)
+	n, err := ReplayLog(&archive, &out, ReplayOptions{Since: since, Level: INFO, Formatter: &TextFormatter{}})
+	if err != nil || n != 1 || out.String() != "ERROR failed id=7\n" {
+		t.Fatalf("got %d %q, %v", n, out.String(), err)
+	}
+}
+
+func TestQueryLogs(t *testing.T) {
+	dir := t.TempDir()
+	ts := time.Date(2021, 2, 3, 4, 5, 6, 0, time.UTC)
+	for i, name := range []string{"a.log", "b.log"} {
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		l := NewSpoor(DEBUG, "", 0, WithConsoleWriter(f), WithFormatter(&JSONFormatter{}), WithClock(fixedClock(ts.Add(time.Duration(i)*time.Hour))))
+		l.Info("request", String("user", name), Int("status", 200))
+		l.Error("request", String("user", name), Int("status", 500))
+		f.Close()
+	}
+	idx, err := BuildIndex(filepath.Join(dir, "a.log"))
+	if err != nil || idx.Entries != 2 || idx.Levels["ERROR"] != 1 || !idx.To.Equal(ts) {
+		t.Fatalf("got %+v, %v", idx, err)
+	}
+	q := LogQuery{Level: ERROR, Fields: map[string]string{"status": "500"}}
+	if !q.mayMatch(idx) {
+		t.Fatal("index skipped a matching file")
+	}
+	q.Fields["user"] = "b.log"
+	if q.mayMatch(idx) {
+		t.Fatal("index kept a file without the user")
+	}
+	var got []string
+	err = QueryLogs(filepath.Join(dir, "*.log"), q, func(e *Entry) bool {
+		got = append(got, e.Level.String()+" "+e.Fields[0].Str)
+		return true
+	})
+	if err != nil || len(got) != 1 || got[0] != "ERROR b.log" {
+		t.Fatalf("got %q, %v", got, err)
+	}
+}
+
+func TestSaaSWriters(t *testing.T) {
+	var mu sync.Mutex
+	bodies := make(map[string]string)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			body = zr
+		}
+		data, _ := io.ReadAll(body)
+		mu.Lock()
+		bodies[r.Header.Get("DD-API-KEY")+r.Header.Get("Authorization")] = string(data)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	line := []byte(`{"time":"2021-02-03T04:05:06Z","level":"WARNING","msg":"slow"}` + "\n")
+	dw := NewDatadogWriter(DatadogConfig{APIKey: "key", Endpoint: srv.URL, Service: "api", Tags: []string{"env:test"}, Gzip: true})
+	dw.Write(line)
+	dw.Close()
+	sw := NewSplunkWriter(SplunkConfig{URL: srv.URL, Token: "tok", Index: "main"})
+	sw.Write(line)
+	sw.Close()
+	var dead bytes.Buffer
+	big := NewDatadogWriter(DatadogConfig{APIKey: "big", Endpoint: srv.URL, Service: "api", DeadLetter: &dead})
+	big.Write(append(bytes.Repeat([]byte("x"), datadogMaxEntryBytes+1), '\n'))
+	big.Write(line)
+	big.Close()
+	if strings.Count(dead.String(), "\n") != 1 || !strings.Contains(dead.String(), "above the 1048576 bytes limit") {
+		t.Errorf("dead letters %.100q", dead.String())
+	}
+	if got := bodies["big"]; strings.Count(got, `"message"`) != 1 || strings.Contains(got, "xxx") {
+		t.Errorf("datadog sent %.100q", got)
+	}
+
+	want := `[{"message":"{\"time\":\"2021-02-03T04:05:06Z\",\"level\":\"WARNING\",\"msg\":\"slow\"}","status":"warning","service":"api","ddsource":"go","ddtags":"env:test","hostname":"` + host + `"}]`
+	if got := bodies["key"]; got != want {
+		t.Errorf("datadog got %s, want %s", got, want)
+	}
+	want = `{"time":1612325106,"host":"` + host + `","source":"` + program + `","sourcetype":"_json","index":"main","event":{"time":"2021-02-03T04:05:06Z","level":"WARNING","msg":"slow"},"fields":{"severity":"warning"}}` + "\n"
+	if got := bodies["Splunk tok"]; got != want {
+		t.Errorf("splunk got %s, want %s", got, want)
+	}
+}
+
+func TestHTTPTransport(t *testing.T) {
+	var mu sync.Mutex
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		got = r.Header.Clone()
+		mu.Unlock()
+		w.Write([]byte(`{"errors":false}`))
+	}))
+	defer srv.Close()
+
+	ew := NewElasticWriter(ElasticConfig{URL: srv.URL, Transport: HTTPTransport{
+		Headers: map[string]string{"X-Tenant": "a"},
+		SigV4:   &SigV4Config{Credentials: AWSCredentials{AccessKeyID: "AKID", SecretAccessKey: "secret"}, Region: "eu-west-1"},
+	}})
+	ew.Write([]byte("INFO ok\n"))
+	ew.Close()
+	if got.Get("X-Tenant") != "a" || !strings.HasPrefix(got.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") ||
+		!strings.Contains(got.Get("Authorization"), "/eu-west-1/es/aws4_request") {
+		t.Fatalf("got headers %v", got)
+	}
+
+	path := filepath.Join(t.TempDir(), "logbus.json")
+	os.WriteFile(path, []byte(`{"endpoint":"http://x","transport":{"proxy":"http://proxy:3128","headers":{"X-Tenant":"b"},"insecure_skip_verify":true}}`), 0666)
+	cfg, err := LoadLogbusConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Transport.Proxy.Host != "proxy:3128" || cfg.Transport.Headers["X-Tenant"] != "b" || !cfg.Transport.TLS.InsecureSkipVerify {
+		t.Fatalf("got %+v", cfg.Transport)
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	calls := 0
+	p := RetryPolicy{MaxRetries: 2, InitialBackoff: time.Millisecond}
+	err := p.Do(func() error { calls++; return unavailable(errors.New("down")) })
+	if !errors.Is(err, ErrRemoteUnavailable) || calls != 3 {
+		t.Fatalf("got %v after %d calls, want 3", err, calls)
+	}
+	calls = 0
+	p.Do(func() error { calls++; return errors.New("bad request") })
+	if calls != 1 {
+		t.Fatalf("retried a permanent error %d times", calls-1)
+	}
+	calls = 0
+	p = RetryPolicy{MaxRetries: 10, InitialBackoff: 20 * time.Millisecond, Jitter: -1, MaxElapsed: 50 * time.Millisecond}
+	p.Do(func() error { calls++; return unavailable(errors.New("down")) })
+	if calls != 2 {
+		t.Fatalf("got %d calls within MaxElapsed, want 2", calls)
+	}
+}
+
+func TestLevelRouterWriter(t *testing.T) {
+	var out, errs bytes.Buffer
+	router := NewLevelRouterWriter(map[Level]io.Writer{DEBUG: &out, INFO: &out, WARN: &errs, ERROR: &errs})
+	l := NewSpoor(DEBUG, "", 0, WithConsoleWriter(router), WithFormatter(&JSONFormatter{}))
+	l.Info("started")
+	l.Error("failed")
+	if !strings.Contains(out.String(), "started") || strings.Contains(out.String(), "failed") {
+		t.Errorf("stdout = %q", out.String())
+	}
+	if !strings.Contains(errs.String(), "failed") || strings.Contains(errs.String(), "started") {
+		t.Errorf("stderr = %q", errs.String())
+	}
+	out.Reset()
+	errs.Reset()
+	router.Write([]byte("WARNING plain line\n"))
+	router.Write([]byte("no level\n"))
+	if errs.String() != "WARNING plain line\n" || out.String() != "no level\n" {
+		t.Errorf("lines routed to %q and %q", out.String(), errs.String())
+	}
+}
+
+// fakeRedis serves the connections one after the other, sending every
+// command to cmds and answering with reply, it closes a connection when
+// reply returns "".
+func fakeRedis(t *testing.T, reply func(args []string) string) (string, chan []string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	cmds := make(chan []string, 100)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			r := bufio.NewReader(conn)
+			for {
+				var n int
+				if _, err := fmt.Fscanf(r, "*%d\r\n", &n); err != nil {
+					break
+				}
+				args := make([]string, n)
+				for i := range args {
+					var size int
+					fmt.Fscanf(r, "$%d\r\n", &size)
+					arg := make([]byte, size+2)
+					io.ReadFull(r, arg)
+					args[i] = string(arg[:size])
+				}
+				cmds <- args
+				out := reply(args)
+				if out == "" {
+					break
+				}
+				conn.Write([]byte(out))
+			}
+			conn.Close()
+		}
+	}()
+	return ln.Addr().String(), cmds
+}
+
+func TestRedisStreamWriter(t *testing.T) {
+	xadds := 0
+	addr, cmds := fakeRedis(t, func(args []string) string {
+		if args[0] != "XADD" {
+			return "+OK\r\n"
+		}
+		if xadds++; xadds == 2 {
+			return ""
+		}
+		return "$15\r\n1700000000000-0\r\n"
+	})
+	rw := NewRedisStreamWriter(RedisStreamConfig{Addr: addr, Password: "secret", DB: 2, Stream: "logs:{level}", MaxLen: 1000, Timeout: time.Second})
+	defer rw.Close()
+	if _, err := rw.Write([]byte("ERROR failed\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rw.Write([]byte("INFO dropped by the server\n")); err == nil {
+		t.Fatal("closed connection not reported")
+	}
+	if _, err := rw.Write([]byte("INFO reconnected\n")); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for len(cmds) > 0 {
+		got = append(got, strings.Join(<-cmds, " "))
+	}
+	want := []string{
+		"AUTH secret", "SELECT 2",
+		"XADD logs:error MAXLEN ~ 1000 * level ERROR message ERROR failed",
+		"XADD logs:info MAXLEN ~ 1000 * level INFO message INFO dropped by the server",
+		"AUTH secret", "SELECT 2",
+		"XADD logs:info MAXLEN ~ 1000 * level INFO message INFO reconnected",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got commands\n%s", strings.Join(got, "\n"))
+	}
+}
+
+func TestRedisStreamWriterReplies(t *testing.T) {
+	for reply, want := range map[string]string{
+		"-ERR wrong type\r\n": "redis: ERR wrong type",
+		"-\n":                 `redis: malformed reply "-\n"`,
+		"$\r\n":               `strconv.Atoi: parsing "": invalid syntax`,
+	} {
+		addr, _ := fakeRedis(t, func([]string) string { return reply })
+		rw := NewRedisStreamWriter(RedisStreamConfig{Addr: addr, Timeout: time.Second})
+		if _, err := rw.Write([]byte("INFO x\n")); err == nil || err.Error() != want {
+			t.Errorf("reply %q: got %v, want %s", reply, err, want)
+		}
+		rw.Close()
+	}
+}
+
+func TestNATSWriter(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	lines := make(chan string, 100)
+	conns := make(chan net.Conn, 2)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conns <- conn
+			go func() {
+				conn.Write([]byte("INFO {\"server_id\":\"test\"}\r\n"))
+				r := bufio.NewReader(conn)
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					lines <- strings.TrimRight(line, "\r\n")
+				}
+			}()
+		}
+	}()
+	nw := NewNATSWriter(NATSConfig{Addr: ln.Addr().String(), Subject: "logs.{level}", Token: "tok", Timeout: time.Second})
+	defer nw.Close()
+	if _, err := nw.Write([]byte("WARNING disk low\n")); err != nil {
+		t.Fatal(err)
+	}
+	next := func() string {
+		select {
+		case line := <-lines:
+			return line
+		case <-time.After(time.Second):
+			t.Fatal("nothing received")
+		}
+		return ""
+	}
+	if connect := next(); !strings.HasPrefix(connect, "CONNECT {") || !strings.Contains(connect, `"auth_token":"tok"`) {
+		t.Fatalf("connect %q", connect)
+	}
+	// the payload keeps its newline, followed by the \r\n of the protocol
+	if pub, payload, end := next(), next(), next(); pub != "PUB logs.warning 17" || payload != "WARNING disk low" || end != "" {
+		t.Fatalf("got %q %q %q", pub, payload, end)
+	}
+	first := <-conns
+	first.Write([]byte("PING\r\n"))
+	if pong := next(); pong != "PONG" {
+		t.Fatalf("got %q, want PONG", pong)
+	}
+
+	first.Close()
+	for deadline := time.Now().Add(time.Second); len(conns) == 0 && time.Now().Before(deadline); {
+		nw.Write([]byte("INFO reconnect\n"))
+		time.Sleep(time.Millisecond * 10)
+	}
+	if len(conns) == 0 {
+		t.Fatal("not reconnected")
+	}
+	for line := next(); line != "INFO reconnect"; line = next() {
+	}
+}
+
+func TestNetworkWriter(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	conns := make(chan net.Conn, 2)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conns <- conn
+		}
+	}()
+	nw := NewNetworkWriter(NetworkConfig{Addr: ln.Addr().String(), WriteTimeout: time.Millisecond * 100, ReconnectBackoff: time.Nanosecond})
+	defer nw.Close()
+	if _, err := nw.Write([]byte("first\n")); err != nil {
+		t.Fatal(err)
+	}
+	first := <-conns
+	if line, _ := bufio.NewReader(first).ReadString('\n'); line != "first\n" {
+		t.Fatalf("got %q", line)
+	}
+
+	// the peer going away is noticed by a later write, which reconnects
+	first.Close()
+	for i := 0; len(conns) == 0 && i < 100; i++ {
+		nw.Write([]byte(fmt.Sprintf("line %d\n", i)))
+		time.Sleep(time.Millisecond * 5)
+	}
+	var second net.Conn
+	select {
+	case second = <-conns:
+	case <-time.After(time.Second):
+		t.Fatal("not reconnected")
+	}
+	defer second.Close()
+	second.SetReadDeadline(time.Now().Add(time.Second))
+	if line, err := bufio.NewReader(second).ReadString('\n'); err != nil || !strings.HasPrefix(line, "line ") {
+		t.Fatalf("got %q after reconnecting: %v", line, err)
+	}
+
+	// the peer of the second connection does not read: the write times out
+	// after sending part of a large entry, which is not sent again
+	start := time.Now()
+	n, err := nw.Write(bytes.Repeat([]byte("x"), 64<<20))
+	var ne net.Error
+	if !errors.As(err, &ne) || !ne.Timeout() || n == 0 || time.Since(start) > time.Second {
+		t.Fatalf("wrote %d bytes in %v: %v", n, time.Since(start), err)
+	}
+	if len(conns) != 0 {
+		t.Fatal("partly written entry retried on a new connection")
+	}
+}